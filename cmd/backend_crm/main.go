@@ -1,22 +1,66 @@
 package main
 
 import (
+	"backend_crm/internal/blob/local"
 	"backend_crm/internal/config"
 	httpController "backend_crm/internal/controller/http/fasthttp"
+	"backend_crm/internal/controller/http/fasthttp/analytics"
 	"backend_crm/internal/controller/http/fasthttp/app"
+	"backend_crm/internal/controller/http/fasthttp/attachments"
 	"backend_crm/internal/controller/http/fasthttp/authorization"
+	"backend_crm/internal/controller/http/fasthttp/categories"
+	"backend_crm/internal/controller/http/fasthttp/collab"
+	"backend_crm/internal/controller/http/fasthttp/comments"
+	"backend_crm/internal/controller/http/fasthttp/customers"
+	"backend_crm/internal/controller/http/fasthttp/customfields"
+	"backend_crm/internal/controller/http/fasthttp/events"
+	"backend_crm/internal/controller/http/fasthttp/imports"
 	"backend_crm/internal/controller/http/fasthttp/orders"
+	"backend_crm/internal/controller/http/fasthttp/products"
+	"backend_crm/internal/controller/http/fasthttp/reports"
+	"backend_crm/internal/controller/http/fasthttp/search"
+	"backend_crm/internal/controller/http/fasthttp/webhooks"
+	"backend_crm/internal/database"
+	"backend_crm/internal/mail"
+	"backend_crm/internal/mail/smtp"
+	analyticsRepo "backend_crm/internal/repository/analytics/postgre"
+	attachmentsRepo "backend_crm/internal/repository/attachments/postgre"
+	broadcastRepo "backend_crm/internal/repository/broadcast/postgre"
+	categoriesRepo "backend_crm/internal/repository/categories/postgre"
+	commentsRepo "backend_crm/internal/repository/comments/postgre"
+	customersRepo "backend_crm/internal/repository/customers/postgre"
+	customFieldsRepo "backend_crm/internal/repository/customfields/postgre"
+	emailsRepo "backend_crm/internal/repository/emails/postgre"
+	eventsRepo "backend_crm/internal/repository/events/postgre"
+	locksRepo "backend_crm/internal/repository/locks/postgre"
 	ordersRepo "backend_crm/internal/repository/orders/postgre"
+	productsRepo "backend_crm/internal/repository/products/postgre"
+	reportsRepo "backend_crm/internal/repository/reports/postgre"
+	searchRepo "backend_crm/internal/repository/search/postgre"
+	smsRepo "backend_crm/internal/repository/sms/postgre"
 	usersRepo "backend_crm/internal/repository/users/postgre"
+	webhooksRepo "backend_crm/internal/repository/webhooks/postgre"
+	"backend_crm/internal/scheduler"
+	"backend_crm/internal/server"
+	"backend_crm/internal/sms"
+	smsFile "backend_crm/internal/sms/file"
+	"backend_crm/internal/sms/httpapi"
+	collabUsecase "backend_crm/internal/usecase/collab/std"
+	eventsUsecase "backend_crm/internal/usecase/events/std"
+	importsUsecase "backend_crm/internal/usecase/imports/std"
+	"backend_crm/internal/usecase/notifications"
+	notificationsUsecase "backend_crm/internal/usecase/notifications/std"
+	ordersUsecase "backend_crm/internal/usecase/orders/std"
+	reportsUsecase "backend_crm/internal/usecase/reports/std"
 	"backend_crm/internal/usecase/users/std"
+	"backend_crm/internal/webhook"
 	"context"
-	"database/sql"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
-	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
 	"github.com/valyala/fasthttp"
 )
@@ -33,63 +77,391 @@ func main() {
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to load configuration")
 	}
+	zerolog.SetGlobalLevel(cfg.GetLogLevel())
 
-	// Initialize database connection
-	db, err := sql.Open("postgres", cfg.GetDSN())
+	// Initialize database connection, waiting for Postgres to come up
+	db, err := database.Open(context.Background(), cfg, logger.With().Str("component", "database").Logger())
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to connect to database")
 	}
 	defer db.Close()
 
-	// Test database connection
-	if err := db.Ping(); err != nil {
-		logger.Fatal().Err(err).Msg("failed to ping database")
+	// Initialize repositories
+	usersRepo := usersRepo.NewRepository(db, cfg.GetQueryTimeout())
+	ordersRepo := ordersRepo.NewRepository(db, cfg.GetQueryTimeout())
+	customersRepo := customersRepo.NewRepository(db, cfg.GetQueryTimeout())
+	productsRepo := productsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	categoriesRepo := categoriesRepo.NewRepository(db, cfg.GetQueryTimeout())
+	customFieldsRepo := customFieldsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	commentsRepo := commentsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	attachmentsRepo := attachmentsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	searchRepo := searchRepo.NewRepository(db, cfg.GetQueryTimeout())
+	analyticsRepo := analyticsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	emailsRepo := emailsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	smsRepo := smsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	webhooksRepo := webhooksRepo.NewRepository(db, cfg.GetQueryTimeout())
+	eventsRepo := eventsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	locksRepo := locksRepo.NewRepository(db, cfg.GetQueryTimeout())
+	reportsRepo := reportsRepo.NewRepository(db, cfg.GetQueryTimeout())
+	broadcastRepo := broadcastRepo.NewRepository(db, cfg.GetDSN(), "order_collab", cfg.GetQueryTimeout())
+
+	// Initialize blob storage for uploaded files
+	blobs, err := local.NewStore(cfg.Storage.Path)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize blob storage")
 	}
 
-	// Initialize repositories
-	usersRepo := usersRepo.NewRepository(db)
-	ordersRepo := ordersRepo.NewRepository(db)
+	// Initialize outgoing mail, disabled without an SMTP host
+	var mailSender mail.Sender
+	if cfg.SMTP.Host != "" {
+		mailSender, err = smtp.NewSender(smtp.Config{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+			Security: cfg.SMTP.Security,
+			Timeout:  cfg.GetSMTPTimeout(),
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to initialize mail")
+		}
+	}
+
+	// Initialize the SMS provider
+	var smsSender sms.Sender
+	if cfg.Notifications.SMS.Enabled {
+		smsCfg := cfg.Notifications.SMS
+		if smsCfg.Provider == config.SMSProviderHTTP {
+			smsSender, err = httpapi.NewSender(httpapi.Config{
+				URL:      smsCfg.HTTP.URL,
+				Token:    smsCfg.HTTP.Token,
+				Username: smsCfg.HTTP.Username,
+				Password: smsCfg.HTTP.Password,
+				From:     smsCfg.HTTP.From,
+				IdField:  smsCfg.HTTP.IdField,
+				Timeout:  cfg.GetSMSTimeout(),
+			})
+		} else {
+			smsSender, err = smsFile.NewSender(smsCfg.File.Path, logger.With().Str("component", "sms").Logger())
+		}
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to initialize sms")
+		}
+	}
 
 	// Initialize usecases
 	usersUsecase := std.NewUsecase(
 		usersRepo,
-		[]byte(cfg.JWT.AccessSecret),
-		[]byte(cfg.JWT.RefreshSecret),
+		cfg.GetAccessSecrets(),
+		cfg.GetRefreshSecrets(),
 		cfg.GetAccessTTL(),
 		cfg.GetRefreshTTL(),
 	)
+	importsUsecase := importsUsecase.NewUsecase(
+		productsRepo,
+		ordersRepo,
+		cfg.Pricing.DefaultCurrency,
+		cfg.GetTaxRate(),
+		cfg.GetImportBatchSize(),
+	)
+	reportsUsecase := reportsUsecase.NewUsecase(
+		analyticsRepo,
+		reportsRepo,
+		mailSender,
+		cfg.GetReportSchedules(),
+		cfg.GetReportLocation(),
+		cfg.GetOverdueAfter(),
+	)
+
+	// Dispatch the domain events of order changes. Subscribers queue the
+	// customer notifications and the webhook deliveries.
+	dispatcher := eventsUsecase.NewDispatcher(
+		eventsRepo,
+		cfg.GetEventInterval(),
+		cfg.GetEventRetryBackoff(),
+		logger.With().Str("component", "events").Logger(),
+	)
+	dispatcher.Subscribe("notifications", notificationsUsecase.NotificationHandler(emailsRepo, smsRepo))
+	dispatcher.Subscribe("webhooks", webhooksRepo.Enqueue)
+	ordersUsecase := ordersUsecase.NewUsecase(ordersRepo, dispatcher)
+
+	// Follow the event store for the live stream of order events
+	eventFeed := eventsUsecase.NewFeed(
+		eventsRepo,
+		cfg.GetStreamInterval(),
+		cfg.GetStreamGapTimeout(),
+		logger.With().Str("component", "feed").Logger(),
+	)
+
+	// Share viewers, locks and comments of orders between the instances
+	collabHub := collabUsecase.NewHub(
+		broadcastRepo,
+		locksRepo,
+		commentsRepo,
+		eventFeed,
+		cfg.GetLockTTL(),
+		logger.With().Str("component", "collab").Logger(),
+	)
+
+	// Send queued notifications, they are queued whether or not a channel
+	// is enabled
+	var emailNotifications, smsNotifications notifications.Usecase
+	if emailCfg := cfg.Notifications.Email; emailCfg.Enabled {
+		emailNotifications = notificationsUsecase.NewEmailUsecase(
+			emailsRepo,
+			ordersRepo,
+			mailSender,
+			cfg.Notifications.Company,
+			notifications.Policy{
+				Interval:     emailCfg.GetInterval(),
+				MaxAttempts:  emailCfg.MaxAttempts,
+				RetryBackoff: emailCfg.GetRetryBackoff(),
+				MaxAge:       emailCfg.GetMaxAge(),
+				SendTimeout:  cfg.GetSMTPTimeout(),
+				Lease:        emailCfg.GetLease(),
+			},
+			logger.With().Str("component", "notifications").Str("channel", "email").Logger(),
+		)
+	}
+	if smsCfg := cfg.Notifications.SMS; smsCfg.Enabled {
+		smsNotifications = notificationsUsecase.NewSMSUsecase(
+			smsRepo,
+			ordersRepo,
+			smsSender,
+			cfg.Notifications.Company,
+			smsCfg.CountryCode,
+			smsCfg.RateLimit,
+			cfg.GetSMSRateWindow(),
+			notifications.Policy{
+				Interval:     smsCfg.GetInterval(),
+				MaxAttempts:  smsCfg.MaxAttempts,
+				RetryBackoff: smsCfg.GetRetryBackoff(),
+				MaxAge:       smsCfg.GetMaxAge(),
+				SendTimeout:  cfg.GetSMSTimeout(),
+				Lease:        smsCfg.GetLease(),
+			},
+			logger.With().Str("component", "notifications").Str("channel", "sms").Logger(),
+		)
+	}
+
+	// Deliver queued webhook events, they are queued whether or not
+	// delivery is enabled
+	var webhookDeliveries notifications.Usecase
+	if webhooksCfg := cfg.Webhooks; webhooksCfg.Enabled {
+		webhookDeliveries = notificationsUsecase.NewWebhookUsecase(
+			webhooksRepo,
+			ordersRepo,
+			webhook.NewSender(cfg.GetWebhookTimeout()),
+			notifications.Policy{
+				Interval:     webhooksCfg.GetInterval(),
+				MaxAttempts:  webhooksCfg.MaxAttempts,
+				RetryBackoff: webhooksCfg.GetRetryBackoff(),
+				MaxAge:       webhooksCfg.GetMaxAge(),
+				SendTimeout:  cfg.GetWebhookTimeout(),
+				Lease:        webhooksCfg.GetLease(),
+			},
+			logger.With().Str("component", "webhooks").Logger(),
+		)
+	}
+
+	// Schedule reports
+	reportScheduler := scheduler.New(cfg.GetReportLocation(), logger.With().Str("component", "scheduler").Logger())
+	for _, schedule := range cfg.GetReportSchedules() {
+		name := schedule.Name
+		err := reportScheduler.Add("report "+name, schedule.Cron, func(ctx context.Context, at time.Time) error {
+			return reportsUsecase.DeliverScheduled(ctx, name, at)
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Str("report", name).Msg("failed to schedule report")
+		}
+	}
+	// Failed runs, also those of other instances, are tried again
+	err = reportScheduler.Add("report retries", "*/5 * * * *", func(ctx context.Context, _ time.Time) error {
+		return reportsUsecase.RetryRuns(ctx)
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to schedule report retries")
+	}
+
+	// Timeouts change with the configuration
+	timeouts := server.NewTimeouts(cfg.GetReadTimeout(), cfg.GetWriteTimeout())
 
 	// Initialize controllers
 	authController := authorization.NewController(usersUsecase, logger.With().Str("component", "authorization").Logger())
-	ordersController := orders.NewController(ordersRepo, logger.With().Str("component", "orders").Logger())
+	ordersController := orders.NewController(ordersRepo, ordersUsecase, customFieldsRepo, emailsRepo, smsRepo, locksRepo, cfg.GetTaxRate(), logger.With().Str("component", "orders").Logger())
+	commentsController := comments.NewController(commentsRepo, ordersRepo, collabHub, logger.With().Str("component", "comments").Logger())
+	attachmentsController := attachments.NewController(
+		attachmentsRepo,
+		ordersRepo,
+		blobs,
+		cfg.Storage.MaxAttachmentSize,
+		logger.With().Str("component", "attachments").Logger(),
+	)
+	customersController := customers.NewController(customersRepo, ordersRepo, logger.With().Str("component", "customers").Logger())
+	productsController := products.NewController(
+		productsRepo,
+		blobs,
+		cfg.Pricing.DefaultCurrency,
+		cfg.Storage.MaxImageSize,
+		cfg.Storage.ThumbnailSize,
+		logger.With().Str("component", "products").Logger(),
+	)
+	categoriesController := categories.NewController(categoriesRepo, logger.With().Str("component", "categories").Logger())
+	customFieldsController := customfields.NewController(customFieldsRepo, logger.With().Str("component", "customfields").Logger())
+	searchController := search.NewController(searchRepo, logger.With().Str("component", "search").Logger())
+	importsController := imports.NewController(importsUsecase, logger.With().Str("component", "imports").Logger())
+	analyticsController := analytics.NewController(analyticsRepo, logger.With().Str("component", "analytics").Logger())
+	reportsController := reports.NewController(reportsUsecase, logger.With().Str("component", "reports").Logger())
+	webhooksController := webhooks.NewController(webhooksRepo, logger.With().Str("component", "webhooks").Logger())
+	eventsController := events.NewController(eventFeed, timeouts, cfg.GetHeartbeat(), logger.With().Str("component", "events").Logger())
+	collabController := collab.NewController(
+		collabHub,
+		usersUsecase,
+		usersRepo,
+		ordersRepo,
+		timeouts,
+		cfg.GetPingInterval(),
+		logger.With().Str("component", "collab").Logger(),
+	)
 	appController := app.NewController(cfg.HTML.Files.Index, logger.With().Str("component", "app").Logger())
 
 	// Initialize main controller
 	controller := httpController.NewController(
 		*authController,
 		*ordersController,
+		*commentsController,
+		*attachmentsController,
+		*customersController,
+		*productsController,
+		*categoriesController,
+		*customFieldsController,
+		*searchController,
+		*importsController,
+		*analyticsController,
+		*reportsController,
+		*webhooksController,
+		*eventsController,
+		*collabController,
 		*appController,
 	)
 
+	// Load TLS certificate
+	var certificates *server.CertificateStore
+	switch cfg.Server.Mode {
+	case config.ServerModeTLS, config.ServerModeBoth:
+		certificates, err = server.NewCertificateStore(cfg.TLS.CertFilePath, cfg.TLS.CertKeyPath)
+	case config.ServerModeDev:
+		logger.Warn().Msg("dev mode: serving a self-signed certificate")
+		certificates, err = server.NewSelfSignedCertificateStore(cfg.Server.Host, "localhost", "127.0.0.1", "::1")
+	}
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load tls certificate")
+	}
+	proxies := server.NewProxyResolver(cfg.GetTrustedProxies(), logger.With().Str("component", "http").Logger())
+
 	// Create server
-	server := &fasthttp.Server{
-		Handler:            controller.Handlers(context.Background()),
+	handler := controller.Handlers(context.Background())
+	srv := &fasthttp.Server{
+		Handler:            proxies.Middleware(handler),
 		ReadTimeout:        cfg.GetReadTimeout(),
 		WriteTimeout:       cfg.GetWriteTimeout(),
-		MaxRequestBodySize: 10 * 1024 * 1024, // 10MB
+		HeaderReceived:     timeouts.HeaderReceived,
+		MaxRequestBodySize: cfg.Server.MaxRequestBodySize,
+	}
+	if certificates != nil {
+		srv.TLSConfig = certificates.TLSConfig()
+	}
+
+	// Plain HTTP listener redirecting to HTTPS
+	var redirectSrv *fasthttp.Server
+	if cfg.Server.Mode == config.ServerModeBoth {
+		redirectSrv = &fasthttp.Server{
+			Handler:            proxies.Middleware(server.RedirectToHTTPS(cfg.Server.Port, proxies, handler)),
+			ReadTimeout:        cfg.GetReadTimeout(),
+			WriteTimeout:       cfg.GetWriteTimeout(),
+			HeaderReceived:     timeouts.HeaderReceived,
+			MaxRequestBodySize: cfg.Server.MaxRequestBodySize,
+		}
+	}
+
+	// Watch configuration and certificates for changes
+	watcher := config.NewWatcher(cfg, logger.With().Str("component", "config").Logger())
+	watcher.OnReload(func(old, new *config.AppConfig) (func(), error) {
+		// The listener mode is fixed on startup
+		if !cfg.UsesCertificateFiles() || !new.UsesCertificateFiles() {
+			return nil, nil
+		}
+		cert, err := server.LoadCertificate(new.TLS.CertFilePath, new.TLS.CertKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		return func() { certificates.Set(cert) }, nil
+	})
+	watcher.OnReload(func(old, new *config.AppConfig) (func(), error) {
+		accessSecrets, refreshSecrets := new.GetAccessSecrets(), new.GetRefreshSecrets()
+		if err := usersUsecase.CheckSecrets(accessSecrets, refreshSecrets); err != nil {
+			return nil, err
+		}
+		return func() { usersUsecase.SetSecrets(accessSecrets, refreshSecrets) }, nil
+	})
+	watcher.OnReload(func(old, new *config.AppConfig) (func(), error) {
+		return func() {
+			timeouts.Set(new.GetReadTimeout(), new.GetWriteTimeout())
+			zerolog.SetGlobalLevel(new.GetLogLevel())
+		}, nil
+	})
+
+	watchCtx, stopWatcher := context.WithCancel(context.Background())
+	defer stopWatcher()
+	go watcher.Run(watchCtx)
+
+	// Background jobs stop before the server. A message cancelled while it
+	// is sent is retried once its claim expires.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go dispatcher.Run(jobsCtx)
+	// Stopping the feed ends the live streams, which would keep the
+	// server from shutting down
+	go eventFeed.Run(jobsCtx)
+	go collabHub.Run(jobsCtx)
+	go reportScheduler.Run(jobsCtx)
+	if emailNotifications != nil {
+		go emailNotifications.Run(jobsCtx)
+	}
+	if smsNotifications != nil {
+		go smsNotifications.Run(jobsCtx)
+	}
+	if webhookDeliveries != nil {
+		go webhookDeliveries.Run(jobsCtx)
 	}
 
 	// Create error channel
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 
 	// Start server in a goroutine
 	go func() {
-		logger.Info().Str("addr", cfg.GetServerAddr()).Msg("starting server")
-		if err := server.ListenAndServeTLS(cfg.GetServerAddr(), cfg.TLS.CertFilePath, cfg.TLS.CertKeyPath); err != nil {
+		logger.Info().Str("addr", cfg.GetServerAddr()).Str("mode", cfg.Server.Mode).Msg("starting server")
+		var err error
+		if cfg.Server.Mode == config.ServerModeHTTP {
+			err = srv.ListenAndServe(cfg.GetServerAddr())
+		} else {
+			err = srv.ListenAndServeTLS(cfg.GetServerAddr(), "", "")
+		}
+		if err != nil {
 			errChan <- err
 		}
 	}()
 
+	if redirectSrv != nil {
+		go func() {
+			logger.Info().Str("addr", cfg.GetHTTPAddr()).Msg("starting http redirect server")
+			if err := redirectSrv.ListenAndServe(cfg.GetHTTPAddr()); err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
 	// Handle graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -103,7 +475,14 @@ func main() {
 
 	// Graceful shutdown
 	logger.Info().Msg("shutting down server")
-	if err := server.Shutdown(); err != nil {
+	stopWatcher()
+	stopJobs()
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(); err != nil {
+			logger.Error().Err(err).Msg("error during redirect server shutdown")
+		}
+	}
+	if err := srv.Shutdown(); err != nil {
 		logger.Error().Err(err).Msg("error during server shutdown")
 	}
 }
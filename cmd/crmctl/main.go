@@ -0,0 +1,147 @@
+// Command crmctl runs maintenance tasks against the CRM database using the
+// same configuration as the server.
+//
+//	crmctl import [-dry-run] [-batch n] products|orders FILE
+package main
+
+import (
+	"backend_crm/internal/config"
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	ordersRepo "backend_crm/internal/repository/orders/postgre"
+	productsRepo "backend_crm/internal/repository/products/postgre"
+	importsUsecase "backend_crm/internal/usecase/imports/std"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const usage = `usage: crmctl <command> [arguments]
+
+commands:
+  import [-dry-run] [-batch n] products|orders FILE
+      import a CSV file, FILE "-" reads standard input
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var code int
+	switch os.Args[1] {
+	case "import":
+		code = runImport(ctx, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		code = 2
+	}
+
+	stop()
+	os.Exit(code)
+}
+
+// runImport returns 1 when any record failed, so scripts can stop before
+// importing orders for products that did not make it
+func runImport(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "validate and report without storing anything")
+	batchSize := flags.Int("batch", 0, "records stored per transaction (default import.batch_size of the configuration)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: crmctl import [-dry-run] [-batch n] products|orders FILE")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	kind, path := flags.Arg(0), flags.Arg(1)
+	if kind != "products" && kind != "orders" {
+		flags.Usage()
+		return 2
+	}
+
+	var file io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		file = f
+	}
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load configuration")
+		return 1
+	}
+
+	db, err := database.Open(ctx, cfg, logger.With().Str("component", "database").Logger())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to connect to database")
+		return 1
+	}
+	defer db.Close()
+
+	if *batchSize <= 0 {
+		*batchSize = cfg.GetImportBatchSize()
+	}
+
+	usecase := importsUsecase.NewUsecase(
+		productsRepo.NewRepository(db, cfg.GetQueryTimeout()),
+		ordersRepo.NewRepository(db, cfg.GetQueryTimeout()),
+		cfg.Pricing.DefaultCurrency,
+		cfg.GetTaxRate(),
+		*batchSize,
+	)
+
+	run := usecase.Products
+	if kind == "orders" {
+		run = usecase.Orders
+	}
+
+	report, err := run(ctx, file, *dryRun)
+	printReport(os.Stdout, report)
+	if err != nil {
+		logger.Error().Err(err).Msg("import stopped, batches stored before are kept")
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printReport(w io.Writer, report *model.ImportReport) {
+	for _, e := range report.Errors {
+		if e.Column != "" {
+			fmt.Fprintf(w, "line %d: %s: %s\n", e.Line, e.Column, e.Message)
+		} else {
+			fmt.Fprintf(w, "line %d: %s\n", e.Line, e.Message)
+		}
+	}
+
+	mode := ""
+	if report.DryRun {
+		mode = " (dry run, nothing stored)"
+	}
+	fmt.Fprintf(w, "%d rows: %d created, %d updated, %d skipped, %d failed%s\n",
+		report.Rows, report.Created, report.Updated, report.Skipped, report.Failed, mode)
+}
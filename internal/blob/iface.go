@@ -0,0 +1,31 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Object is an opened blob. It is seekable so that byte ranges can be
+// served without reading the whole content.
+type Object interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
+
+// Store keeps binary content such as product images outside of the
+// database. Keys are slash separated relative paths chosen by the caller.
+type Store interface {
+	// Put stores the content under key, replacing an existing blob. The
+	// blob becomes visible only after it has been written completely.
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (Object, error)
+	Delete(ctx context.Context, key string) error
+}
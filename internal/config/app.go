@@ -1,11 +1,25 @@
 package config
 
 import (
+	"backend_crm/internal/mail"
+	"backend_crm/internal/mail/smtp"
+	"backend_crm/internal/model"
+	"backend_crm/internal/scheduler"
+	"backend_crm/internal/usecase/imports"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/rs/zerolog"
 )
 
 type AppConfig struct {
@@ -14,6 +28,17 @@ type AppConfig struct {
 		Port         int    `json:"port"`
 		ReadTimeout  string `json:"read_timeout"`
 		WriteTimeout string `json:"write_timeout"`
+		// Mode is one of ServerModeTLS, ServerModeHTTP, ServerModeBoth
+		// or ServerModeDev.
+		Mode string `json:"mode"`
+		// HTTPPort is the plain HTTP port redirecting to HTTPS in
+		// ServerModeBoth.
+		HTTPPort int `json:"http_port"`
+		// TrustedProxies lists CIDRs whose X-Forwarded-For and
+		// X-Forwarded-Proto headers are honored.
+		TrustedProxies []string `json:"trusted_proxies"`
+		// MaxRequestBodySize in bytes, uploads included
+		MaxRequestBodySize int `json:"max_request_body_size"`
 	} `json:"server"`
 
 	TLS struct {
@@ -26,8 +51,130 @@ type AppConfig struct {
 		RefreshSecret string `json:"refresh_secret"`
 		AccessTTL     string `json:"access_ttl"`
 		RefreshTTL    string `json:"refresh_ttl"`
+		// Secrets that were used before the last rotation. Tokens signed
+		// with them are still accepted, new tokens use the current secret.
+		PreviousAccessSecrets  []string `json:"previous_access_secrets"`
+		PreviousRefreshSecrets []string `json:"previous_refresh_secrets"`
 	} `json:"jwt"`
 
+	Pricing struct {
+		// DefaultCurrency is used for products created without a currency
+		DefaultCurrency string `json:"default_currency"`
+		// TaxRate in percent applied to new orders, e.g. 20
+		TaxRate float64 `json:"tax_rate"`
+	} `json:"pricing"`
+
+	Storage struct {
+		// Path is the root directory of the local blob store
+		Path string `json:"path"`
+		// MaxImageSize in bytes of a single uploaded image. It must leave
+		// room for the multipart framing within max_request_body_size.
+		MaxImageSize int `json:"max_image_size"`
+		// MaxAttachmentSize in bytes of a single file attached to an order,
+		// bounded by max_request_body_size like max_image_size
+		MaxAttachmentSize int `json:"max_attachment_size"`
+		// ThumbnailSize is the bounding box in pixels thumbnails are scaled into
+		ThumbnailSize int `json:"thumbnail_size"`
+	} `json:"storage"`
+
+	Import struct {
+		// BatchSize is the number of CSV records stored per transaction
+		BatchSize int `json:"batch_size"`
+	} `json:"import"`
+
+	Log struct {
+		Level string `json:"level"`
+	} `json:"log"`
+
+	// SMTP is the server outgoing mail is delivered to. Mail is disabled
+	// while Host is empty.
+	SMTP struct {
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		From     string `json:"from"`
+		// Security is "starttls", "tls" or "none"
+		Security string `json:"security"`
+		Timeout  string `json:"timeout"`
+	} `json:"smtp"`
+
+	Notifications struct {
+		// Company signs the messages to customers
+		Company string   `json:"company"`
+		Email   Delivery `json:"email"`
+		SMS     struct {
+			Delivery
+			// Provider is "http" or "file"
+			Provider string `json:"provider"`
+			// CountryCode is prepended to phones given without one, e.g. "7"
+			CountryCode string `json:"country_code"`
+			// RateLimit messages at most go to a number per RateWindow
+			RateLimit  int    `json:"rate_limit"`
+			RateWindow string `json:"rate_window"`
+			HTTP       struct {
+				URL      string `json:"url"`
+				Token    string `json:"token"`
+				Username string `json:"username"`
+				Password string `json:"password"`
+				From     string `json:"from"`
+				// IdField is the field of the response holding the message id
+				IdField string `json:"id_field"`
+				Timeout string `json:"timeout"`
+			} `json:"http"`
+			File struct {
+				// Path of the file messages are appended to, empty to
+				// only log them
+				Path string `json:"path"`
+			} `json:"file"`
+		} `json:"sms"`
+	} `json:"notifications"`
+
+	// Events dispatches the domain events to their subscribers
+	Events struct {
+		// Interval between checks for events stored by other instances or
+		// due for a retry; events of this instance are dispatched at once
+		Interval string `json:"interval"`
+		// RetryBackoff is the delay after a failed dispatch, doubled with
+		// every further one up to an hour
+		RetryBackoff string `json:"retry_backoff"`
+		// StreamInterval between reads of the event store for the live
+		// stream of order events
+		StreamInterval string `json:"stream_interval"`
+		// Heartbeat is the interval of the comments sent on the live
+		// stream to keep proxies from closing it
+		Heartbeat string `json:"heartbeat"`
+	} `json:"events"`
+
+	// Collab is the WebSocket channel of users editing orders together
+	Collab struct {
+		// LockTTL is how long an edit lock lasts unless it is renewed
+		LockTTL string `json:"lock_ttl"`
+		// PingInterval between pings to the clients; a client not heard of
+		// for two intervals is disconnected
+		PingInterval string `json:"ping_interval"`
+	} `json:"collab"`
+
+	// Webhooks delivers order events to the subscriptions Directors manage
+	Webhooks struct {
+		Delivery
+		// Timeout of a request to a receiver
+		Timeout string `json:"timeout"`
+	} `json:"webhooks"`
+
+	Reports struct {
+		// Timezone the schedules and report periods use, e.g. "Europe/Moscow"
+		Timezone string `json:"timezone"`
+		// OverdueAfter is how long an order may stay open before reports
+		// list it as overdue
+		OverdueAfter string           `json:"overdue_after"`
+		Schedules    []ReportSchedule `json:"schedules"`
+	} `json:"reports"`
+
+	Reload struct {
+		Interval string `json:"interval"`
+	} `json:"reload"`
+
 	Database struct {
 		Host     string `json:"host"`
 		Port     int    `json:"port"`
@@ -35,6 +182,15 @@ type AppConfig struct {
 		Password string `json:"password"`
 		DBName   string `json:"db_name"`
 		SSLMode  string `json:"ssl_mode"`
+
+		MaxOpenConns     int    `json:"max_open_conns"`
+		MaxIdleConns     int    `json:"max_idle_conns"`
+		ConnMaxLifetime  string `json:"conn_max_lifetime"`
+		ConnMaxIdleTime  string `json:"conn_max_idle_time"`
+		StatementTimeout string `json:"statement_timeout"`
+		QueryTimeout     string `json:"query_timeout"`
+		ConnectRetries   int    `json:"connect_retries"`
+		ConnectBackoff   string `json:"connect_backoff"`
 	} `json:"database"`
 
 	HTML struct {
@@ -52,14 +208,174 @@ type AppConfig struct {
 	parsedWriteTimeout time.Duration
 	parsedAccessTTL    time.Duration
 	parsedRefreshTTL   time.Duration
+	parsedLogLevel     zerolog.Level
+	parsedReloadPeriod time.Duration
+	parsedProxies      []netip.Prefix
+
+	parsedConnMaxLifetime  time.Duration
+	parsedConnMaxIdleTime  time.Duration
+	parsedStatementTimeout time.Duration
+	parsedQueryTimeout     time.Duration
+	parsedConnectBackoff   time.Duration
+
+	parsedSMTPTimeout    time.Duration
+	parsedSMSWindow      time.Duration
+	parsedSMSTimeout     time.Duration
+	parsedWebhookTimeout time.Duration
+	parsedEventInterval  time.Duration
+	parsedEventBackoff   time.Duration
+	parsedStreamInterval time.Duration
+	parsedHeartbeat      time.Duration
+	parsedLockTTL        time.Duration
+	parsedPingInterval   time.Duration
+	parsedReportLoc      *time.Location
+	parsedOverdueAfter   time.Duration
+	parsedSchedules      []model.ReportSchedule
+
+	path string
+}
+
+// Delivery configures how an outbox of notifications or webhooks is sent
+type Delivery struct {
+	// Enabled sends queued messages. They are queued either way.
+	Enabled bool `json:"enabled"`
+	// Interval between checks of the outbox
+	Interval    string `json:"interval"`
+	MaxAttempts int    `json:"max_attempts"`
+	// RetryBackoff is the delay after the first failed attempt, doubled
+	// with every further one
+	RetryBackoff string `json:"retry_backoff"`
+	// MaxAge drops messages that could not be sent in time, so enabling
+	// a channel later does not send stale notifications
+	MaxAge string `json:"max_age"`
+	// Lease is how long a claimed batch is hidden from other instances.
+	// It must be longer than the time limit of a single message.
+	Lease string `json:"lease"`
+
+	parsedInterval     time.Duration
+	parsedRetryBackoff time.Duration
+	parsedMaxAge       time.Duration
+	parsedLease        time.Duration
+}
+
+// load applies the defaults and parses the durations
+func (d *Delivery) load() error {
+	if d.Interval == "" {
+		d.Interval = "10s"
+	}
+	if d.MaxAttempts == 0 {
+		d.MaxAttempts = 8
+	}
+	if d.RetryBackoff == "" {
+		d.RetryBackoff = "1m"
+	}
+	if d.MaxAge == "" {
+		d.MaxAge = "48h"
+	}
+	if d.Lease == "" {
+		d.Lease = "5m"
+	}
+
+	durations := []struct {
+		value  string
+		target *time.Duration
+	}{
+		{d.Interval, &d.parsedInterval},
+		{d.RetryBackoff, &d.parsedRetryBackoff},
+		{d.MaxAge, &d.parsedMaxAge},
+		{d.Lease, &d.parsedLease},
+	}
+	for _, duration := range durations {
+		var err error
+		if *duration.target, err = time.ParseDuration(duration.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the settings of a channel whose messages take up to
+// sendTimeout each
+func (d *Delivery) validate(section string, sendTimeout time.Duration) error {
+	if d.parsedInterval <= 0 || d.parsedRetryBackoff <= 0 || d.parsedMaxAge <= 0 {
+		return fmt.Errorf("%s durations must be positive", section)
+	}
+	if d.MaxAttempts < 1 {
+		return fmt.Errorf("%s max_attempts must be positive", section)
+	}
+	// Otherwise a message could be claimed again while it is being sent
+	// and go out twice
+	if d.parsedLease <= sendTimeout {
+		return fmt.Errorf("%s lease must be longer than the send timeout %s", section, sendTimeout)
+	}
+	return nil
+}
+
+// GetInterval returns how often the outbox is checked
+func (d *Delivery) GetInterval() time.Duration {
+	return d.parsedInterval
+}
+
+// GetRetryBackoff returns the delay after the first failed delivery
+func (d *Delivery) GetRetryBackoff() time.Duration {
+	return d.parsedRetryBackoff
+}
+
+// GetMaxAge returns how long queued messages may wait to be sent
+func (d *Delivery) GetMaxAge() time.Duration {
+	return d.parsedMaxAge
+}
+
+// GetLease returns how long a claimed batch is hidden from other instances
+func (d *Delivery) GetLease() time.Duration {
+	return d.parsedLease
+}
+
+// ReportSchedule is a report produced automatically, see model.ReportSchedule
+type ReportSchedule struct {
+	Name string `json:"name"`
+	// Cron is a five field cron expression or a shortcut like "@weekly"
+	Cron string `json:"cron"`
+	// Period is "day", "week" or "month"
+	Period string `json:"period"`
+	// Formats are "html" and "pdf", default both
+	Formats   []string `json:"formats"`
+	To        []string `json:"to"`
+	Directory string   `json:"directory"`
 }
 
+const (
+	// SMSProviderHTTP posts messages to a JSON over HTTP provider API
+	SMSProviderHTTP = "http"
+	// SMSProviderFile logs messages and appends them to a file, for development
+	SMSProviderFile = "file"
+)
+
+// reportName keeps names usable in URLs and file names
+var reportName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+const (
+	// ServerModeTLS serves HTTPS using the configured key pair
+	ServerModeTLS = "tls"
+	// ServerModeHTTP serves plain HTTP, e.g. behind a TLS-terminating proxy
+	ServerModeHTTP = "http"
+	// ServerModeBoth serves HTTPS and redirects plain HTTP to it
+	ServerModeBoth = "both"
+	// ServerModeDev serves HTTPS with a self-signed certificate generated on startup
+	ServerModeDev = "dev"
+)
+
 func NewConfig() (*AppConfig, error) {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
 		configPath = "config.json"
 	}
 
+	return Load(configPath)
+}
+
+// Load reads, parses and validates the configuration file at configPath.
+func Load(configPath string) (*AppConfig, error) {
 	file, err := os.Open(configPath)
 	if err != nil {
 		return nil, err
@@ -84,6 +400,16 @@ func NewConfig() (*AppConfig, error) {
 	if config.JWT.RefreshTTL == "" {
 		config.JWT.RefreshTTL = "720h"
 	}
+	if config.Pricing.DefaultCurrency == "" {
+		config.Pricing.DefaultCurrency = "RUB"
+	}
+	config.Pricing.DefaultCurrency = strings.ToUpper(config.Pricing.DefaultCurrency)
+	if config.Log.Level == "" {
+		config.Log.Level = "info"
+	}
+	if config.Reload.Interval == "" {
+		config.Reload.Interval = "5s"
+	}
 
 	var parseErr error
 	config.parsedReadTimeout, parseErr = time.ParseDuration(config.Server.ReadTimeout)
@@ -106,6 +432,16 @@ func NewConfig() (*AppConfig, error) {
 		return nil, parseErr
 	}
 
+	config.parsedLogLevel, parseErr = zerolog.ParseLevel(config.Log.Level)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	config.parsedReloadPeriod, parseErr = time.ParseDuration(config.Reload.Interval)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
 	// Set default values if not provided
 	if config.Server.Host == "" {
 		config.Server.Host = "0.0.0.0"
@@ -113,6 +449,39 @@ func NewConfig() (*AppConfig, error) {
 	if config.Server.Port == 0 {
 		config.Server.Port = 8080
 	}
+	if config.Server.Mode == "" {
+		config.Server.Mode = ServerModeTLS
+	}
+	if config.Server.HTTPPort == 0 {
+		config.Server.HTTPPort = 80
+	}
+	if config.Server.MaxRequestBodySize == 0 {
+		config.Server.MaxRequestBodySize = 10 * 1024 * 1024 // 10MB
+	}
+
+	if config.Storage.Path == "" {
+		config.Storage.Path = "storage"
+	}
+	if config.Storage.MaxImageSize == 0 {
+		config.Storage.MaxImageSize = 5 * 1024 * 1024 // 5MB
+	}
+	if config.Storage.MaxAttachmentSize == 0 {
+		config.Storage.MaxAttachmentSize = 8 * 1024 * 1024 // 8MB
+	}
+	if config.Storage.ThumbnailSize == 0 {
+		config.Storage.ThumbnailSize = 320
+	}
+	if config.Import.BatchSize == 0 {
+		config.Import.BatchSize = imports.DefaultBatchSize
+	}
+
+	for _, cidr := range config.Server.TrustedProxies {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy: %w", err)
+		}
+		config.parsedProxies = append(config.parsedProxies, prefix.Masked())
+	}
 
 	if config.Database.Port == 0 {
 		config.Database.Port = 5432
@@ -120,6 +489,177 @@ func NewConfig() (*AppConfig, error) {
 	if config.Database.SSLMode == "" {
 		config.Database.SSLMode = "disable"
 	}
+	if config.Database.MaxOpenConns == 0 {
+		config.Database.MaxOpenConns = 25
+	}
+	if config.Database.MaxIdleConns == 0 {
+		config.Database.MaxIdleConns = 5
+	}
+	if config.Database.ConnMaxLifetime == "" {
+		config.Database.ConnMaxLifetime = "30m"
+	}
+	if config.Database.ConnMaxIdleTime == "" {
+		config.Database.ConnMaxIdleTime = "5m"
+	}
+	if config.Database.StatementTimeout == "" {
+		config.Database.StatementTimeout = "30s"
+	}
+	if config.Database.QueryTimeout == "" {
+		config.Database.QueryTimeout = "10s"
+	}
+	if config.Database.ConnectRetries == 0 {
+		config.Database.ConnectRetries = 10
+	}
+	if config.Database.ConnectBackoff == "" {
+		config.Database.ConnectBackoff = "500ms"
+	}
+
+	durations := []struct {
+		value  string
+		target *time.Duration
+	}{
+		{config.Database.ConnMaxLifetime, &config.parsedConnMaxLifetime},
+		{config.Database.ConnMaxIdleTime, &config.parsedConnMaxIdleTime},
+		{config.Database.StatementTimeout, &config.parsedStatementTimeout},
+		{config.Database.QueryTimeout, &config.parsedQueryTimeout},
+		{config.Database.ConnectBackoff, &config.parsedConnectBackoff},
+	}
+	for _, d := range durations {
+		if *d.target, parseErr = time.ParseDuration(d.value); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if config.SMTP.Security == "" {
+		config.SMTP.Security = smtp.SecurityStartTLS
+	}
+	if config.SMTP.Port == 0 {
+		switch config.SMTP.Security {
+		case smtp.SecurityTLS:
+			config.SMTP.Port = 465
+		case smtp.SecurityNone:
+			config.SMTP.Port = 25
+		default:
+			config.SMTP.Port = 587
+		}
+	}
+	if config.SMTP.Timeout == "" {
+		config.SMTP.Timeout = "30s"
+	}
+	if config.SMTP.From == "" {
+		config.SMTP.From = config.SMTP.Username
+	}
+	if config.parsedSMTPTimeout, parseErr = time.ParseDuration(config.SMTP.Timeout); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if err := config.Notifications.Email.load(); err != nil {
+		return nil, err
+	}
+	if err := config.Notifications.SMS.load(); err != nil {
+		return nil, err
+	}
+	if config.Notifications.SMS.Provider == "" {
+		config.Notifications.SMS.Provider = SMSProviderFile
+	}
+	if config.Notifications.SMS.CountryCode == "" {
+		config.Notifications.SMS.CountryCode = "7"
+	}
+	if config.Notifications.SMS.RateLimit == 0 {
+		config.Notifications.SMS.RateLimit = 3
+	}
+	if config.Notifications.SMS.RateWindow == "" {
+		config.Notifications.SMS.RateWindow = "1h"
+	}
+	if config.Notifications.SMS.HTTP.Timeout == "" {
+		config.Notifications.SMS.HTTP.Timeout = "10s"
+	}
+	if config.parsedSMSWindow, parseErr = time.ParseDuration(config.Notifications.SMS.RateWindow); parseErr != nil {
+		return nil, parseErr
+	}
+	if config.parsedSMSTimeout, parseErr = time.ParseDuration(config.Notifications.SMS.HTTP.Timeout); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if config.Events.Interval == "" {
+		config.Events.Interval = "5s"
+	}
+	if config.Events.RetryBackoff == "" {
+		config.Events.RetryBackoff = "10s"
+	}
+	if config.parsedEventInterval, parseErr = time.ParseDuration(config.Events.Interval); parseErr != nil {
+		return nil, parseErr
+	}
+	if config.parsedEventBackoff, parseErr = time.ParseDuration(config.Events.RetryBackoff); parseErr != nil {
+		return nil, parseErr
+	}
+	if config.Events.StreamInterval == "" {
+		config.Events.StreamInterval = "1s"
+	}
+	if config.Events.Heartbeat == "" {
+		config.Events.Heartbeat = "15s"
+	}
+	if config.parsedStreamInterval, parseErr = time.ParseDuration(config.Events.StreamInterval); parseErr != nil {
+		return nil, parseErr
+	}
+	if config.parsedHeartbeat, parseErr = time.ParseDuration(config.Events.Heartbeat); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if config.Collab.LockTTL == "" {
+		config.Collab.LockTTL = "2m"
+	}
+	if config.Collab.PingInterval == "" {
+		config.Collab.PingInterval = "30s"
+	}
+	if config.parsedLockTTL, parseErr = time.ParseDuration(config.Collab.LockTTL); parseErr != nil {
+		return nil, parseErr
+	}
+	if config.parsedPingInterval, parseErr = time.ParseDuration(config.Collab.PingInterval); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if err := config.Webhooks.load(); err != nil {
+		return nil, err
+	}
+	if config.Webhooks.Timeout == "" {
+		config.Webhooks.Timeout = "10s"
+	}
+	if config.parsedWebhookTimeout, parseErr = time.ParseDuration(config.Webhooks.Timeout); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if config.Reports.Timezone == "" {
+		config.Reports.Timezone = "UTC"
+	}
+	if config.Reports.OverdueAfter == "" {
+		config.Reports.OverdueAfter = "72h"
+	}
+	if config.parsedReportLoc, parseErr = time.LoadLocation(config.Reports.Timezone); parseErr != nil {
+		return nil, fmt.Errorf("reports timezone: %w", parseErr)
+	}
+	if config.parsedOverdueAfter, parseErr = time.ParseDuration(config.Reports.OverdueAfter); parseErr != nil {
+		return nil, parseErr
+	}
+	for _, s := range config.Reports.Schedules {
+		schedule := model.ReportSchedule{
+			Name:      s.Name,
+			Cron:      s.Cron,
+			Period:    model.Grouping(s.Period),
+			To:        s.To,
+			Directory: s.Directory,
+		}
+		if schedule.Period == "" {
+			schedule.Period = model.GroupByWeek
+		}
+		for _, f := range s.Formats {
+			schedule.Formats = append(schedule.Formats, model.ReportFormat(strings.ToLower(f)))
+		}
+		if len(schedule.Formats) == 0 {
+			schedule.Formats = []model.ReportFormat{model.ReportHTML, model.ReportPDF}
+		}
+		config.parsedSchedules = append(config.parsedSchedules, schedule)
+	}
 
 	// Ensure HTML file paths are absolute
 	if config.HTML.BasePath != "" {
@@ -129,17 +669,204 @@ func NewConfig() (*AppConfig, error) {
 		config.HTML.Files.Orders = filepath.Join(config.HTML.BasePath, config.HTML.Files.Orders)
 	}
 
+	config.path = configPath
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// Validate checks that the configuration can actually be used to run the
+// server. It is called on startup and before applying a reload.
+func (c *AppConfig) Validate() error {
+	if c.parsedReadTimeout <= 0 || c.parsedWriteTimeout <= 0 {
+		return errors.New("server timeouts must be positive")
+	}
+	if c.parsedAccessTTL <= 0 || c.parsedRefreshTTL <= 0 {
+		return errors.New("jwt ttl must be positive")
+	}
+	if c.parsedReloadPeriod <= 0 {
+		return errors.New("reload interval must be positive")
+	}
+	if c.JWT.AccessSecret == "" || c.JWT.RefreshSecret == "" {
+		return errors.New("jwt secrets must not be empty")
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnectRetries < 0 {
+		return errors.New("database pool settings must not be negative")
+	}
+	if c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		return errors.New("database max_idle_conns must not exceed max_open_conns")
+	}
+
+	if c.Pricing.TaxRate < 0 || c.Pricing.TaxRate > 100 {
+		return errors.New("pricing tax_rate must be between 0 and 100")
+	}
+	if len(c.Pricing.DefaultCurrency) != 3 {
+		return errors.New("pricing default_currency must be an ISO 4217 code")
+	}
+
+	if c.Server.MaxRequestBodySize < 0 {
+		return errors.New("server max_request_body_size must not be negative")
+	}
+	if c.Storage.MaxImageSize < 0 || c.Storage.MaxImageSize >= c.Server.MaxRequestBodySize {
+		return errors.New("storage max_image_size must be below server max_request_body_size")
+	}
+	if c.Storage.MaxAttachmentSize < 0 || c.Storage.MaxAttachmentSize >= c.Server.MaxRequestBodySize {
+		return errors.New("storage max_attachment_size must be below server max_request_body_size")
+	}
+	if c.Storage.ThumbnailSize < 0 {
+		return errors.New("storage thumbnail_size must not be negative")
+	}
+	if c.Import.BatchSize < 0 {
+		return errors.New("import batch_size must not be negative")
+	}
+
+	switch c.Server.Mode {
+	case ServerModeTLS, ServerModeBoth:
+		if _, err := tls.LoadX509KeyPair(c.TLS.CertFilePath, c.TLS.CertKeyPath); err != nil {
+			return fmt.Errorf("load tls key pair: %w", err)
+		}
+	case ServerModeHTTP, ServerModeDev:
+	default:
+		return fmt.Errorf("unknown server mode %q", c.Server.Mode)
+	}
+
+	if c.Server.Mode == ServerModeBoth && c.Server.HTTPPort == c.Server.Port {
+		return errors.New("http_port must differ from port")
+	}
+
+	if c.SMTP.Host != "" {
+		switch c.SMTP.Security {
+		case smtp.SecurityStartTLS, smtp.SecurityTLS, smtp.SecurityNone:
+		default:
+			return fmt.Errorf("unknown smtp security %q", c.SMTP.Security)
+		}
+		if _, err := mail.ParseAddress(c.SMTP.From); err != nil {
+			return fmt.Errorf("smtp from: %w", err)
+		}
+		if c.parsedSMTPTimeout <= 0 {
+			return errors.New("smtp timeout must be positive")
+		}
+	}
+
+	if err := c.Notifications.Email.validate("notifications email", c.parsedSMTPTimeout); err != nil {
+		return err
+	}
+	if c.Notifications.Email.Enabled && c.SMTP.Host == "" {
+		return errors.New("notifications email requires smtp")
+	}
+	if err := c.Notifications.SMS.validate("notifications sms", c.parsedSMSTimeout); err != nil {
+		return err
+	}
+	switch c.Notifications.SMS.Provider {
+	case SMSProviderHTTP:
+		if c.Notifications.SMS.Enabled && c.Notifications.SMS.HTTP.URL == "" {
+			return errors.New("notifications sms http url must not be empty")
+		}
+	case SMSProviderFile:
+	default:
+		return fmt.Errorf("unknown sms provider %q", c.Notifications.SMS.Provider)
+	}
+	if c.Notifications.SMS.RateLimit < 1 || c.parsedSMSWindow <= 0 {
+		return errors.New("notifications sms rate_limit and rate_window must be positive")
+	}
+	if c.parsedSMSTimeout <= 0 {
+		return errors.New("notifications sms http timeout must be positive")
+	}
+	if c.parsedEventInterval <= 0 || c.parsedEventBackoff <= 0 {
+		return errors.New("events interval and retry_backoff must be positive")
+	}
+	if c.parsedStreamInterval <= 0 || c.parsedHeartbeat <= 0 {
+		return errors.New("events stream_interval and heartbeat must be positive")
+	}
+	if c.parsedLockTTL <= 0 || c.parsedPingInterval <= 0 {
+		return errors.New("collab lock_ttl and ping_interval must be positive")
+	}
+	if err := c.Webhooks.validate("webhooks", c.parsedWebhookTimeout); err != nil {
+		return err
+	}
+	if c.parsedWebhookTimeout <= 0 {
+		return errors.New("webhooks timeout must be positive")
+	}
+
+	if c.parsedOverdueAfter <= 0 {
+		return errors.New("reports overdue_after must be positive")
+	}
+	names := make(map[string]bool, len(c.parsedSchedules))
+	for _, s := range c.parsedSchedules {
+		if !reportName.MatchString(s.Name) {
+			return fmt.Errorf("report name %q must be lower case letters, digits, - and _", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("report %q is defined twice", s.Name)
+		}
+		names[s.Name] = true
+
+		if _, err := scheduler.Parse(s.Cron); err != nil {
+			return fmt.Errorf("report %s cron: %w", s.Name, err)
+		}
+		switch s.Period {
+		case model.GroupByDay, model.GroupByWeek, model.GroupByMonth:
+		default:
+			return fmt.Errorf("report %s period must be day, week or month", s.Name)
+		}
+		for _, f := range s.Formats {
+			if f != model.ReportHTML && f != model.ReportPDF {
+				return fmt.Errorf("report %s has unknown format %q", s.Name, f)
+			}
+		}
+		if len(s.To) == 0 && s.Directory == "" {
+			return fmt.Errorf("report %s needs recipients or a directory", s.Name)
+		}
+		if len(s.To) > 0 && c.SMTP.Host == "" {
+			return fmt.Errorf("report %s is mailed but smtp is not configured", s.Name)
+		}
+		for _, to := range s.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("report %s recipient: %w", s.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *AppConfig) Path() string {
+	return c.path
+}
+
 func (c *AppConfig) GetDSN() string {
-	return "postgres://" + c.Database.User + ":" + c.Database.Password + "@" + c.Database.Host + ":" + strconv.Itoa(c.Database.Port) + "/" + c.Database.DBName + "?sslmode=" + c.Database.SSLMode
+	dsn := "postgres://" + c.Database.User + ":" + c.Database.Password + "@" + c.Database.Host + ":" + strconv.Itoa(c.Database.Port) + "/" + c.Database.DBName + "?sslmode=" + c.Database.SSLMode
+	if c.parsedStatementTimeout > 0 {
+		// Unknown parameters are sent by lib/pq as session settings
+		dsn += "&statement_timeout=" + strconv.FormatInt(c.parsedStatementTimeout.Milliseconds(), 10)
+	}
+	return dsn
 }
 
 func (c *AppConfig) GetServerAddr() string {
 	return c.Server.Host + ":" + strconv.Itoa(c.Server.Port)
 }
 
+// GetHTTPAddr returns the address of the plain HTTP redirect listener
+func (c *AppConfig) GetHTTPAddr() string {
+	return c.Server.Host + ":" + strconv.Itoa(c.Server.HTTPPort)
+}
+
+// UsesCertificateFiles reports whether the server mode serves the configured key pair
+func (c *AppConfig) UsesCertificateFiles() bool {
+	return c.Server.Mode == ServerModeTLS || c.Server.Mode == ServerModeBoth
+}
+
+// GetTrustedProxies returns the parsed trusted proxy networks
+func (c *AppConfig) GetTrustedProxies() []netip.Prefix {
+	return c.parsedProxies
+}
+
 // GetReadTimeout returns the parsed read timeout duration
 func (c *AppConfig) GetReadTimeout() time.Duration {
 	return c.parsedReadTimeout
@@ -159,3 +886,140 @@ func (c *AppConfig) GetAccessTTL() time.Duration {
 func (c *AppConfig) GetRefreshTTL() time.Duration {
 	return c.parsedRefreshTTL
 }
+
+// GetConnMaxLifetime returns the parsed maximum lifetime of a database connection
+func (c *AppConfig) GetConnMaxLifetime() time.Duration {
+	return c.parsedConnMaxLifetime
+}
+
+// GetConnMaxIdleTime returns the parsed maximum idle time of a database connection
+func (c *AppConfig) GetConnMaxIdleTime() time.Duration {
+	return c.parsedConnMaxIdleTime
+}
+
+// GetQueryTimeout returns the parsed timeout applied to every repository call
+func (c *AppConfig) GetQueryTimeout() time.Duration {
+	return c.parsedQueryTimeout
+}
+
+// GetConnectBackoff returns the parsed initial delay between startup connection attempts
+func (c *AppConfig) GetConnectBackoff() time.Duration {
+	return c.parsedConnectBackoff
+}
+
+// GetTaxRate returns the tax rate for new orders in basis points
+func (c *AppConfig) GetTaxRate() int {
+	return int(math.Round(c.Pricing.TaxRate * 100))
+}
+
+// GetImportBatchSize returns the number of imported records stored per transaction
+func (c *AppConfig) GetImportBatchSize() int {
+	return c.Import.BatchSize
+}
+
+// GetLogLevel returns the parsed log level
+func (c *AppConfig) GetLogLevel() zerolog.Level {
+	return c.parsedLogLevel
+}
+
+// GetReloadInterval returns how often watched files are checked for changes
+func (c *AppConfig) GetReloadInterval() time.Duration {
+	return c.parsedReloadPeriod
+}
+
+// GetAccessSecrets returns the current access secret followed by the previous ones
+func (c *AppConfig) GetAccessSecrets() [][]byte {
+	return secrets(c.JWT.AccessSecret, c.JWT.PreviousAccessSecrets)
+}
+
+// GetRefreshSecrets returns the current refresh secret followed by the previous ones
+func (c *AppConfig) GetRefreshSecrets() [][]byte {
+	return secrets(c.JWT.RefreshSecret, c.JWT.PreviousRefreshSecrets)
+}
+
+// GetSMTPTimeout returns the parsed time limit of a single mail delivery
+func (c *AppConfig) GetSMTPTimeout() time.Duration {
+	return c.parsedSMTPTimeout
+}
+
+// GetSMSRateWindow returns the period the SMS rate limit applies to
+func (c *AppConfig) GetSMSRateWindow() time.Duration {
+	return c.parsedSMSWindow
+}
+
+// GetSMSTimeout returns the parsed time limit of a request to the SMS provider
+func (c *AppConfig) GetSMSTimeout() time.Duration {
+	return c.parsedSMSTimeout
+}
+
+// GetEventInterval returns how often stored events are checked for
+func (c *AppConfig) GetEventInterval() time.Duration {
+	return c.parsedEventInterval
+}
+
+// GetEventRetryBackoff returns the delay after the first failed dispatch
+func (c *AppConfig) GetEventRetryBackoff() time.Duration {
+	return c.parsedEventBackoff
+}
+
+// GetStreamInterval returns how often the live stream reads the event store
+func (c *AppConfig) GetStreamInterval() time.Duration {
+	return c.parsedStreamInterval
+}
+
+// GetHeartbeat returns the interval of the live stream heartbeats
+func (c *AppConfig) GetHeartbeat() time.Duration {
+	return c.parsedHeartbeat
+}
+
+// GetStreamGapTimeout returns how long the live stream waits for a missing
+// event. Events are stored by repository calls, which cannot take longer
+// than the query timeout.
+func (c *AppConfig) GetStreamGapTimeout() time.Duration {
+	if c.parsedQueryTimeout <= 0 {
+		return time.Minute
+	}
+	return c.parsedQueryTimeout
+}
+
+// GetLockTTL returns how long an edit lock lasts unless it is renewed
+func (c *AppConfig) GetLockTTL() time.Duration {
+	return c.parsedLockTTL
+}
+
+// GetPingInterval returns the interval between pings to WebSocket clients
+func (c *AppConfig) GetPingInterval() time.Duration {
+	return c.parsedPingInterval
+}
+
+// GetWebhookTimeout returns the parsed time limit of a webhook request
+func (c *AppConfig) GetWebhookTimeout() time.Duration {
+	return c.parsedWebhookTimeout
+}
+
+// GetReportLocation returns the time zone of report schedules and periods
+func (c *AppConfig) GetReportLocation() *time.Location {
+	return c.parsedReportLoc
+}
+
+// GetOverdueAfter returns how long an order may stay open before reports
+// list it as overdue
+func (c *AppConfig) GetOverdueAfter() time.Duration {
+	return c.parsedOverdueAfter
+}
+
+// GetReportSchedules returns the configured reports with defaults applied
+func (c *AppConfig) GetReportSchedules() []model.ReportSchedule {
+	return c.parsedSchedules
+}
+
+func secrets(current string, previous []string) [][]byte {
+	keys := make([][]byte, 0, len(previous)+1)
+	keys = append(keys, []byte(current))
+	for _, p := range previous {
+		if p != "" {
+			keys = append(keys, []byte(p))
+		}
+	}
+	return keys
+}
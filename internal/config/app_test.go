@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryLease(t *testing.T) {
+	tests := []struct {
+		name        string
+		lease       string
+		sendTimeout time.Duration
+		wantErr     bool
+	}{
+		{"default", "", 30 * time.Second, false},
+		{"longer than the timeout", "20s", 10 * time.Second, false},
+		{"equal to the timeout", "10s", 10 * time.Second, true},
+		{"shorter than the timeout", "5s", 10 * time.Second, true},
+		{"default below a long timeout", "", 10 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Delivery{Lease: tt.lease}
+			if err := d.load(); err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if err := d.validate("webhooks", tt.sendTimeout); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ReloadFunc checks a freshly loaded configuration and returns the function
+// applying it, which may be nil. It is called only after the new
+// configuration passed validation. Every handler checks before any applies,
+// so a configuration rejected by one of them changes nothing; apply itself
+// must not fail.
+type ReloadFunc func(old, new *AppConfig) (apply func(), err error)
+
+// Watcher reloads the configuration when the config file or the TLS key pair
+// change on disk, or when the process receives SIGHUP.
+type Watcher struct {
+	logger zerolog.Logger
+
+	mu       sync.Mutex
+	current  *AppConfig
+	handlers []ReloadFunc
+	stamps   map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func NewWatcher(cfg *AppConfig, logger zerolog.Logger) *Watcher {
+	w := &Watcher{
+		logger:  logger,
+		current: cfg,
+	}
+	w.stamps = w.readStamps(cfg)
+
+	return w
+}
+
+// OnReload registers fn to be called on every accepted reload
+func (w *Watcher) OnReload(fn ReloadFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handlers = append(w.handlers, fn)
+}
+
+// Current returns the last successfully applied configuration
+func (w *Watcher) Current() *AppConfig {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current
+}
+
+// Run polls watched files and listens for SIGHUP until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(w.Current().GetReloadInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.logger.Info().Msg("received SIGHUP, reloading configuration")
+			if err := w.Reload(); err != nil {
+				w.logger.Error().Err(err).Msg("configuration reload rejected")
+			}
+		case <-ticker.C:
+			w.mu.Lock()
+			changed := w.changedFiles()
+			w.mu.Unlock()
+			if len(changed) == 0 {
+				continue
+			}
+
+			w.logger.Info().Strs("files", changed).Msg("watched files changed, reloading configuration")
+			if err := w.Reload(); err != nil {
+				w.logger.Error().Err(err).Msg("configuration reload rejected")
+			}
+			ticker.Reset(w.Current().GetReloadInterval())
+		}
+	}
+}
+
+// Reload loads the configuration from disk and applies it. An invalid
+// configuration is rejected and the running one stays in effect.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	old := w.current
+	// Remember the files as they are now, even if the reload fails, so a
+	// broken file is not reported again on every tick.
+	w.stamps = w.readStamps(old)
+
+	next, err := Load(old.Path())
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	applies := make([]func(), 0, len(w.handlers))
+	for _, fn := range w.handlers {
+		apply, err := fn(old, next)
+		if err != nil {
+			return fmt.Errorf("apply config: %w", err)
+		}
+		if apply != nil {
+			applies = append(applies, apply)
+		}
+	}
+	for _, apply := range applies {
+		apply()
+	}
+
+	w.current = next
+	w.stamps = w.readStamps(next)
+
+	changes, restart := Diff(old, next)
+	if len(restart) > 0 {
+		w.logger.Warn().Strs("fields", restart).Msg("changed settings require a restart to take effect")
+	}
+	w.logger.Info().Strs("changed", changes).Msg("configuration reloaded")
+
+	return nil
+}
+
+// Diff lists the settings that differ between old and new. Settings that are
+// applied only on startup are returned separately.
+func Diff(old, new *AppConfig) (changes []string, restart []string) {
+	if old.parsedReadTimeout != new.parsedReadTimeout {
+		changes = append(changes, "server.read_timeout")
+	}
+	if old.parsedWriteTimeout != new.parsedWriteTimeout {
+		changes = append(changes, "server.write_timeout")
+	}
+	if old.parsedLogLevel != new.parsedLogLevel {
+		changes = append(changes, "log.level")
+	}
+	if old.TLS != new.TLS {
+		changes = append(changes, "tls")
+	}
+	if old.JWT.AccessSecret != new.JWT.AccessSecret ||
+		!slices.Equal(old.JWT.PreviousAccessSecrets, new.JWT.PreviousAccessSecrets) {
+		changes = append(changes, "jwt.access_secrets")
+	}
+	if old.JWT.RefreshSecret != new.JWT.RefreshSecret ||
+		!slices.Equal(old.JWT.PreviousRefreshSecrets, new.JWT.PreviousRefreshSecrets) {
+		changes = append(changes, "jwt.refresh_secrets")
+	}
+	if old.Pricing != new.Pricing {
+		restart = append(restart, "pricing")
+	}
+	if old.parsedReloadPeriod != new.parsedReloadPeriod {
+		changes = append(changes, "reload.interval")
+	}
+
+	if old.Server.Host != new.Server.Host || old.Server.Port != new.Server.Port ||
+		old.Server.HTTPPort != new.Server.HTTPPort {
+		restart = append(restart, "server.address")
+	}
+	if old.Server.Mode != new.Server.Mode {
+		restart = append(restart, "server.mode")
+	}
+	if !slices.Equal(old.Server.TrustedProxies, new.Server.TrustedProxies) {
+		restart = append(restart, "server.trusted_proxies")
+	}
+	if old.parsedAccessTTL != new.parsedAccessTTL || old.parsedRefreshTTL != new.parsedRefreshTTL {
+		restart = append(restart, "jwt.ttl")
+	}
+	if old.Server.MaxRequestBodySize != new.Server.MaxRequestBodySize {
+		restart = append(restart, "server.max_request_body_size")
+	}
+	if old.Storage != new.Storage {
+		restart = append(restart, "storage")
+	}
+	if old.Database != new.Database {
+		restart = append(restart, "database")
+	}
+	if old.HTML.Files != new.HTML.Files {
+		restart = append(restart, "html")
+	}
+
+	return changes, restart
+}
+
+func (w *Watcher) watchedFiles(cfg *AppConfig) []string {
+	files := []string{cfg.Path()}
+	if !cfg.UsesCertificateFiles() {
+		return files
+	}
+	if cfg.TLS.CertFilePath != "" {
+		files = append(files, cfg.TLS.CertFilePath)
+	}
+	if cfg.TLS.CertKeyPath != "" {
+		files = append(files, cfg.TLS.CertKeyPath)
+	}
+	return files
+}
+
+func (w *Watcher) readStamps(cfg *AppConfig) map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, f := range w.watchedFiles(cfg) {
+		info, err := os.Stat(f)
+		if err != nil {
+			stamps[f] = fileStamp{}
+			continue
+		}
+		stamps[f] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps
+}
+
+func (w *Watcher) changedFiles() []string {
+	var changed []string
+	for f, stamp := range w.readStamps(w.current) {
+		if w.stamps[f] != stamp {
+			changed = append(changed, f)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
@@ -0,0 +1,45 @@
+package contact
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizePhone keeps only the digits of a phone number so that
+// "+7 (900) 123-45-67" and "89001234567" compare equal. The domestic
+// trunk prefix 8 of 11-digit numbers is replaced with the country code 7.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	digits := b.String()
+	if len(digits) == 11 && digits[0] == '8' {
+		digits = "7" + digits[1:]
+	}
+
+	return digits
+}
+
+// NormalizeEmail trims and lower-cases an email address
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimFunc(email, unicode.IsSpace))
+}
+
+// InternationalPhone formats a phone number for SMS delivery as "+" and
+// 8 to 15 digits. Numbers given without their country code, i.e. not
+// starting with "+" and shorter than 11 digits, get countryCode prepended.
+// ok is false when the result cannot be a valid number.
+func InternationalPhone(phone string, countryCode string) (string, bool) {
+	digits := NormalizePhone(phone)
+	if !strings.HasPrefix(strings.TrimSpace(phone), "+") && len(digits) < 11 {
+		digits = countryCode + digits
+	}
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", false
+	}
+	return "+" + digits, true
+}
@@ -0,0 +1,86 @@
+package contact
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		phone string
+		want  string
+	}{
+		{"formatted", "+7 (900) 123-45-67", "79001234567"},
+		{"trunk prefix", "89001234567", "79001234567"},
+		{"trunk prefix with spaces", "8 900 123 45 67", "79001234567"},
+		{"without country code", "900 123-45-67", "9001234567"},
+		{"other country", "+1 415 555 0100", "14155550100"},
+		{"long number", "+44 20 7946 0958", "442079460958"},
+		{"short number starting with 8", "8123", "8123"},
+		{"twelve digits starting with 8", "890012345678", "890012345678"},
+		{"non ascii digits are dropped", "+7 ９００ 1234567", "71234567"},
+		{"no digits", "n/a", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizePhone(tt.phone); got != tt.want {
+				t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{"lower case", "ivan@example.com", "ivan@example.com"},
+		{"mixed case", "Ivan.Petrov@Example.COM", "ivan.petrov@example.com"},
+		{"surrounding space", " \tivan@example.com\r\n", "ivan@example.com"},
+		{"no-break space", "\u00a0ivan@example.com\u00a0", "ivan@example.com"},
+		{"inner space is kept", "ivan @example.com", "ivan @example.com"},
+		{"cyrillic", "Иван@Пример.РФ", "иван@пример.рф"},
+		{"empty", "  ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.email); got != tt.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInternationalPhone(t *testing.T) {
+	tests := []struct {
+		name        string
+		phone       string
+		countryCode string
+		want        string
+		wantOk      bool
+	}{
+		{"international", "+7 900 123-45-67", "7", "+79001234567", true},
+		{"trunk prefix", "8 (900) 123-45-67", "7", "+79001234567", true},
+		{"without country code", "900 123 45 67", "7", "+79001234567", true},
+		{"country code of the setting", "555 0100", "1", "+15550100", true},
+		{"other country given", "+44 20 7946 0958", "7", "+442079460958", true},
+		{"eleven digits keep their code", "14155550100", "7", "+14155550100", true},
+		{"too short", "12345", "7", "", false},
+		{"too short with plus", "+1234567", "7", "", false},
+		{"too long", "+1234567890123456", "7", "", false},
+		{"leading zero", "+0123456789", "7", "", false},
+		{"empty", "", "7", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := InternationalPhone(tt.phone, tt.countryCode)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("InternationalPhone(%q, %q) = %q, %v, want %q, %v", tt.phone, tt.countryCode, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package access holds the checks controllers share before they work on an
+// order of the request.
+package access
+
+import (
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"errors"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// VisibleOrder loads the order of the orderId path value and checks that
+// the caller may see it. Orders of other employees are reported as missing.
+// It writes the error response itself and reports whether to continue.
+func VisibleOrder(ctx *fasthttp.RequestCtx, repo orders.Repository, logger zerolog.Logger) (*model.Order, bool) {
+	orderId, ok := ctx.UserValue("orderId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	userRole, ok := ctx.UserValue("user_role").(model.Role)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	order, err := repo.GetById(ctx, orderId)
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFoundOrder) {
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+			return nil, false
+		}
+		logger.Error().Err(err).Msg("Error getting order")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return nil, false
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	if !order.VisibleTo(userId, userRole) {
+		ctx.Error("order not found", fasthttp.StatusNotFound)
+		return nil, false
+	}
+
+	return order, true
+}
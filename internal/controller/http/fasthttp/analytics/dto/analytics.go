@@ -0,0 +1,167 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+// periodLayout formats the start of a period, periods begin at UTC midnight
+const periodLayout = "2006-01-02"
+
+type StatusCount struct {
+	Status int `json:"status"`
+	Orders int `json:"orders"`
+}
+
+type NewOrders struct {
+	GroupBy string        `json:"groupBy"`
+	Orders  int           `json:"orders"`
+	Periods []PeriodCount `json:"periods"`
+}
+
+type PeriodCount struct {
+	Period string `json:"period"`
+	Orders int    `json:"orders"`
+}
+
+type CompletionTime struct {
+	Orders         int   `json:"orders"`
+	AverageSeconds int64 `json:"averageSeconds"`
+	MedianSeconds  int64 `json:"medianSeconds"`
+}
+
+type CompletionTimes struct {
+	GroupBy string `json:"groupBy"`
+	CompletionTime
+	Periods []PeriodCompletionTime `json:"periods"`
+}
+
+type PeriodCompletionTime struct {
+	Period string `json:"period"`
+	CompletionTime
+}
+
+type RejectionRate struct {
+	Orders    int     `json:"orders"`
+	Rejected  int     `json:"rejected"`
+	Completed int     `json:"completed"`
+	Rate      float64 `json:"rate"`
+}
+
+type RejectionRates struct {
+	GroupBy string `json:"groupBy"`
+	RejectionRate
+	Periods []PeriodRejectionRate `json:"periods"`
+}
+
+type PeriodRejectionRate struct {
+	Period string `json:"period"`
+	RejectionRate
+}
+
+type ProductStats struct {
+	ProductId string  `json:"productId"`
+	Name      string  `json:"name"`
+	Orders    int     `json:"orders"`
+	Quantity  int64   `json:"quantity"`
+	Weight    float64 `json:"weight"`
+}
+
+type EmployeeStats struct {
+	UserId                   string `json:"userId"`
+	Username                 string `json:"username"`
+	Open                     int    `json:"open"`
+	Completed                int    `json:"completed"`
+	Rejected                 int    `json:"rejected"`
+	AverageCompletionSeconds int64  `json:"averageCompletionSeconds"`
+}
+
+func StatusCountsFromModel(counts []model.StatusCount) []StatusCount {
+	resp := make([]StatusCount, 0, len(counts))
+	for _, count := range counts {
+		resp = append(resp, StatusCount{Status: int(count.Status), Orders: count.Orders})
+	}
+	return resp
+}
+
+func NewOrdersFromModel(groupBy model.Grouping, counts []model.PeriodCount) *NewOrders {
+	resp := &NewOrders{
+		GroupBy: string(groupBy),
+		Periods: make([]PeriodCount, 0, len(counts)),
+	}
+	for _, count := range counts {
+		resp.Orders += count.Orders
+		resp.Periods = append(resp.Periods, PeriodCount{
+			Period: count.Period.Format(periodLayout),
+			Orders: count.Orders,
+		})
+	}
+	return resp
+}
+
+func CompletionTimesFromModel(groupBy model.Grouping, total *model.CompletionTime, periods []model.PeriodCompletionTime) *CompletionTimes {
+	resp := &CompletionTimes{
+		GroupBy:        string(groupBy),
+		CompletionTime: completionTime(*total),
+		Periods:        make([]PeriodCompletionTime, 0, len(periods)),
+	}
+	for _, period := range periods {
+		resp.Periods = append(resp.Periods, PeriodCompletionTime{
+			Period:         period.Period.Format(periodLayout),
+			CompletionTime: completionTime(period.CompletionTime),
+		})
+	}
+	return resp
+}
+
+func completionTime(c model.CompletionTime) CompletionTime {
+	return CompletionTime{
+		Orders:         c.Orders,
+		AverageSeconds: int64(c.Average / time.Second),
+		MedianSeconds:  int64(c.Median / time.Second),
+	}
+}
+
+func RejectionRatesFromModel(groupBy model.Grouping, total *model.RejectionRate, periods []model.PeriodRejectionRate) *RejectionRates {
+	resp := &RejectionRates{
+		GroupBy:       string(groupBy),
+		RejectionRate: RejectionRate(*total),
+		Periods:       make([]PeriodRejectionRate, 0, len(periods)),
+	}
+	for _, period := range periods {
+		resp.Periods = append(resp.Periods, PeriodRejectionRate{
+			Period:        period.Period.Format(periodLayout),
+			RejectionRate: RejectionRate(period.RejectionRate),
+		})
+	}
+	return resp
+}
+
+func ProductStatsFromModel(products []*model.ProductStats) []*ProductStats {
+	resp := make([]*ProductStats, 0, len(products))
+	for _, product := range products {
+		resp = append(resp, &ProductStats{
+			ProductId: product.ProductId,
+			Name:      product.Name,
+			Orders:    product.Orders,
+			Quantity:  product.Quantity,
+			Weight:    product.Weight,
+		})
+	}
+	return resp
+}
+
+func EmployeeStatsFromModel(employees []*model.EmployeeStats) []*EmployeeStats {
+	resp := make([]*EmployeeStats, 0, len(employees))
+	for _, employee := range employees {
+		resp = append(resp, &EmployeeStats{
+			UserId:                   employee.UserId,
+			Username:                 employee.Username,
+			Open:                     employee.Open,
+			Completed:                employee.Completed,
+			Rejected:                 employee.Rejected,
+			AverageCompletionSeconds: int64(employee.AverageCompletion / time.Second),
+		})
+	}
+	return resp
+}
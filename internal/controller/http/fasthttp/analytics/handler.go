@@ -0,0 +1,274 @@
+package analytics
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/analytics/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/analytics"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// defaultDays is the range covered when from is not given
+	defaultDays     = 30
+	maxPeriods      = 366
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+	dateLayout      = "2006-01-02"
+)
+
+type Controller struct {
+	analytics analytics.Repository
+	logger    zerolog.Logger
+}
+
+func NewController(analytics analytics.Repository, logger zerolog.Logger) *Controller {
+	return &Controller{
+		analytics: analytics,
+		logger:    logger,
+	}
+}
+
+// OrdersByStatus counts the orders created in the range by current status
+func (c *Controller) OrdersByStatus(ctx *fasthttp.RequestCtx) {
+	if !allowed(ctx) {
+		return
+	}
+
+	rng, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	counts, err := c.analytics.OrdersByStatus(ctx, rng)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error counting orders by status")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, dto.StatusCountsFromModel(counts))
+}
+
+// NewOrders counts the orders created per day, week or month
+func (c *Controller) NewOrders(ctx *fasthttp.RequestCtx) {
+	if !allowed(ctx) {
+		return
+	}
+
+	rng, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	counts, err := c.analytics.NewOrders(ctx, rng)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error counting new orders")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, dto.NewOrdersFromModel(rng.GroupBy, counts))
+}
+
+// CompletionTime reports how long orders took from creation to completion
+func (c *Controller) CompletionTime(ctx *fasthttp.RequestCtx) {
+	if !allowed(ctx) {
+		return
+	}
+
+	rng, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	total, periods, err := c.analytics.CompletionTime(ctx, rng)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error measuring completion time")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, dto.CompletionTimesFromModel(rng.GroupBy, total, periods))
+}
+
+// RejectionRate reports the share of rejected orders among decided ones
+func (c *Controller) RejectionRate(ctx *fasthttp.RequestCtx) {
+	if !allowed(ctx) {
+		return
+	}
+
+	rng, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	total, periods, err := c.analytics.RejectionRate(ctx, rng)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error computing rejection rate")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, dto.RejectionRatesFromModel(rng.GroupBy, total, periods))
+}
+
+// TopProducts ranks products by the number of orders or the weight ordered
+func (c *Controller) TopProducts(ctx *fasthttp.RequestCtx) {
+	if !allowed(ctx) {
+		return
+	}
+
+	rng, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	queryArgs := ctx.QueryArgs()
+	by := model.ProductRanking(queryArgs.Peek("by"))
+	if by == "" {
+		by = model.RankByOrders
+	}
+	if by != model.RankByOrders && by != model.RankByWeight {
+		ctx.Error("by must be orders or weight", fasthttp.StatusBadRequest)
+		return
+	}
+
+	limit := defaultTopLimit
+	if queryArgs.Has("limit") {
+		var err error
+		limit, err = queryArgs.GetUint("limit")
+		if err != nil || limit == 0 || limit > maxTopLimit {
+			ctx.Error("limit must be between 1 and 100", fasthttp.StatusBadRequest)
+			return
+		}
+	}
+
+	products, err := c.analytics.TopProducts(ctx, rng, by, limit)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error ranking products")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, dto.ProductStatsFromModel(products))
+}
+
+// Employees reports the throughput of every user
+func (c *Controller) Employees(ctx *fasthttp.RequestCtx) {
+	if !allowed(ctx) {
+		return
+	}
+
+	rng, ok := parseRange(ctx)
+	if !ok {
+		return
+	}
+
+	employees, err := c.analytics.Employees(ctx, rng)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error computing employee throughput")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(ctx, dto.EmployeeStatsFromModel(employees))
+}
+
+// allowed checks the method and that the caller is a Director, analytics
+// cover the orders of every user
+func allowed(ctx *fasthttp.RequestCtx) bool {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return false
+	}
+
+	// Director is the zero Role, a missing role must not pass as one
+	if userRole, ok := ctx.UserValue("user_role").(model.Role); !ok || userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// parseRange reads the from, to and groupBy query arguments. Dates are
+// YYYY-MM-DD in UTC with to included, RFC 3339 timestamps are taken as they
+// are with to excluded. The range defaults to the last 30 days including
+// today, grouped by day.
+func parseRange(ctx *fasthttp.RequestCtx) (model.AnalyticsRange, bool) {
+	var rng model.AnalyticsRange
+
+	queryArgs := ctx.QueryArgs()
+
+	rng.To = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if raw := string(queryArgs.Peek("to")); raw != "" {
+		to, ok := parseTime(raw, true)
+		if !ok {
+			ctx.Error("to must be a date as YYYY-MM-DD or an RFC 3339 timestamp", fasthttp.StatusBadRequest)
+			return rng, false
+		}
+		rng.To = to
+	}
+
+	rng.From = rng.To.AddDate(0, 0, -defaultDays)
+	if raw := string(queryArgs.Peek("from")); raw != "" {
+		from, ok := parseTime(raw, false)
+		if !ok {
+			ctx.Error("from must be a date as YYYY-MM-DD or an RFC 3339 timestamp", fasthttp.StatusBadRequest)
+			return rng, false
+		}
+		rng.From = from
+	}
+
+	if !rng.From.Before(rng.To) {
+		ctx.Error("from must be before to", fasthttp.StatusBadRequest)
+		return rng, false
+	}
+
+	rng.GroupBy = model.Grouping(queryArgs.Peek("groupBy"))
+	switch rng.GroupBy {
+	case "":
+		rng.GroupBy = model.GroupByDay
+	case model.GroupByDay, model.GroupByWeek, model.GroupByMonth:
+	default:
+		ctx.Error("groupBy must be day, week or month", fasthttp.StatusBadRequest)
+		return rng, false
+	}
+
+	if rng.Periods() > maxPeriods {
+		ctx.Error("Range is too long, group by a longer period or use at most "+strconv.Itoa(maxPeriods)+" periods", fasthttp.StatusBadRequest)
+		return rng, false
+	}
+
+	return rng, true
+}
+
+// parseTime reads a date or a timestamp. A date given as end of the range
+// includes the whole day.
+func parseTime(raw string, end bool) (time.Time, bool) {
+	if t, err := time.Parse(dateLayout, raw); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, true
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+func writeJSON(ctx *fasthttp.RequestCtx, resp any) {
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
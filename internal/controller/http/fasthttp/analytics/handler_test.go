@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/analytics"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// countingRepository counts the queries that reach it
+type countingRepository struct {
+	analytics.Repository
+	calls int
+}
+
+func (r *countingRepository) OrdersByStatus(context.Context, model.AnalyticsRange) ([]model.StatusCount, error) {
+	r.calls++
+	return nil, nil
+}
+
+func (r *countingRepository) NewOrders(context.Context, model.AnalyticsRange) ([]model.PeriodCount, error) {
+	r.calls++
+	return nil, nil
+}
+
+func (r *countingRepository) CompletionTime(context.Context, model.AnalyticsRange) (*model.CompletionTime, []model.PeriodCompletionTime, error) {
+	r.calls++
+	return &model.CompletionTime{}, nil, nil
+}
+
+func (r *countingRepository) RejectionRate(context.Context, model.AnalyticsRange) (*model.RejectionRate, []model.PeriodRejectionRate, error) {
+	r.calls++
+	return &model.RejectionRate{}, nil, nil
+}
+
+func (r *countingRepository) TopProducts(context.Context, model.AnalyticsRange, model.ProductRanking, int) ([]*model.ProductStats, error) {
+	r.calls++
+	return nil, nil
+}
+
+func (r *countingRepository) Employees(context.Context, model.AnalyticsRange) ([]*model.EmployeeStats, error) {
+	r.calls++
+	return nil, nil
+}
+
+func TestDirectorOnly(t *testing.T) {
+	repo := &countingRepository{}
+	c := NewController(repo, zerolog.Nop())
+
+	handlers := []struct {
+		name    string
+		handler fasthttp.RequestHandler
+	}{
+		{"orders by status", c.OrdersByStatus},
+		{"new orders", c.NewOrders},
+		{"completion time", c.CompletionTime},
+		{"rejection rate", c.RejectionRate},
+		{"top products", c.TopProducts},
+		{"employees", c.Employees},
+	}
+	roles := []struct {
+		name string
+		role any
+		want int
+	}{
+		{"director", model.Director, fasthttp.StatusOK},
+		{"employee", model.Employee, fasthttp.StatusForbidden},
+		{"no role", nil, fasthttp.StatusForbidden},
+	}
+
+	for _, h := range handlers {
+		for _, r := range roles {
+			t.Run(h.name+"/"+r.name, func(t *testing.T) {
+				var ctx fasthttp.RequestCtx
+				ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+				ctx.Request.SetRequestURI("/")
+				if r.role != nil {
+					ctx.SetUserValue("user_role", r.role)
+				}
+
+				before := repo.calls
+				h.handler(&ctx)
+
+				if got := ctx.Response.StatusCode(); got != r.want {
+					t.Errorf("status %d, want %d", got, r.want)
+				}
+				if queried := repo.calls > before; queried != (r.want == fasthttp.StatusOK) {
+					t.Errorf("repository queried: %v", queried)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+type Attachment struct {
+	AttachmentId string    `json:"attachmentId"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	UploadedBy   string    `json:"uploadedBy"`
+	Username     string    `json:"username"`
+	URL          string    `json:"url"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func AttachmentFromModel(attachment *model.OrderAttachment) *Attachment {
+	return &Attachment{
+		AttachmentId: attachment.AttachmentId,
+		Filename:     attachment.Filename,
+		ContentType:  attachment.File.ContentType,
+		Size:         attachment.File.Size,
+		SHA256:       attachment.File.SHA256,
+		UploadedBy:   attachment.UploadedBy,
+		Username:     attachment.Username,
+		URL:          "/api/v1/orders/order/" + attachment.OrderId + "/attachments/" + attachment.AttachmentId,
+		CreatedAt:    attachment.CreatedAt,
+	}
+}
@@ -0,0 +1,306 @@
+package attachments
+
+import (
+	"backend_crm/internal/blob"
+	"backend_crm/internal/controller/http/fasthttp/access"
+	"backend_crm/internal/controller/http/fasthttp/attachments/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/attachments"
+	"backend_crm/internal/repository/orders"
+	"backend_crm/internal/server"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// maxFilenameLength matches the filename column
+const maxFilenameLength = 255
+
+type Controller struct {
+	attachments       attachments.Repository
+	orders            orders.Repository
+	blobs             blob.Store
+	maxAttachmentSize int
+	logger            zerolog.Logger
+}
+
+func NewController(
+	attachments attachments.Repository,
+	orders orders.Repository,
+	blobs blob.Store,
+	maxAttachmentSize int,
+	logger zerolog.Logger,
+) *Controller {
+	return &Controller{
+		attachments:       attachments,
+		orders:            orders,
+		blobs:             blobs,
+		maxAttachmentSize: maxAttachmentSize,
+		logger:            logger,
+	}
+}
+
+// Attachments lists the files kept with an order
+func (c *Controller) Attachments(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+	orderId := order.OrderId
+
+	found, err := c.attachments.GetByOrderId(ctx, orderId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting attachments")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Attachment, 0, len(found))
+	for _, attachment := range found {
+		resp = append(resp, dto.AttachmentFromModel(attachment))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Upload attaches the "file" field of a multipart form to the order
+func (c *Controller) Upload(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+	orderId := order.OrderId
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.Error("file is required", fasthttp.StatusBadRequest)
+		return
+	}
+	if header.Size > int64(c.maxAttachmentSize) {
+		ctx.Error("file is too large", fasthttp.StatusRequestEntityTooLarge)
+		return
+	}
+
+	filename := cleanFilename(header.Filename)
+	if filename == "" {
+		ctx.Error("file name is required", fasthttp.StatusBadRequest)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error opening uploaded file")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// The declared content type is ignored, the data decides
+	content := bufio.NewReaderSize(file, 512)
+	head, err := content.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Error().Err(err).Msg("Error reading uploaded file")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	attachment := &model.OrderAttachment{
+		OrderId:  orderId,
+		Filename: filename,
+		File: model.BlobFile{
+			Key:         "orders/" + orderId + "/" + rand.Text(),
+			ContentType: http.DetectContentType(head),
+		},
+		UploadedBy: ctx.UserValue("user_id").(string),
+	}
+
+	if err := c.blobs.Put(ctx, attachment.File.Key, io.TeeReader(content, io.MultiWriter(hash, counter))); err != nil {
+		c.logger.Error().Err(err).Msg("Error storing attachment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	attachment.File.Size = counter.n
+	attachment.File.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if err := c.attachments.Save(ctx, attachment); err != nil {
+		c.deleteBlob(attachment.File.Key)
+		if errors.Is(err, attachments.ErrNotFoundOrder) {
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error saving attachment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(dto.AttachmentFromModel(attachment)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Download serves the attached file. It is always sent as a download so
+// that uploaded HTML or scripts are never rendered by the browser.
+func (c *Controller) Download(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	attachment, ok := c.accessibleAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	obj, err := c.blobs.Open(ctx, attachment.File.Key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			c.logger.Warn().Str("key", attachment.File.Key).Msg("Attachment blob is missing")
+			ctx.Error("attachment not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error opening attachment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	server.ServeBlob(ctx, obj, attachment.File.ContentType, attachment.File.SHA256)
+	ctx.Response.Header.Set(fasthttp.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	ctx.Response.Header.Set(fasthttp.HeaderXContentTypeOptions, "nosniff")
+}
+
+// Delete removes an attachment. Directors can delete any attachment, other
+// roles only the files they uploaded.
+func (c *Controller) Delete(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsDelete() {
+		ctx.Error("Only DELETE method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	attachment, ok := c.accessibleAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	userRole, _ := ctx.UserValue("user_role").(model.Role)
+	if userRole != model.Director && attachment.UploadedBy != ctx.UserValue("user_id").(string) {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	deleted, err := c.attachments.Delete(ctx, attachment.OrderId, attachment.AttachmentId)
+	if err != nil {
+		if errors.Is(err, attachments.ErrNotFoundAttachment) {
+			ctx.Error("attachment not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error deleting attachment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	c.deleteBlob(deleted.File.Key)
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func (c *Controller) accessibleAttachment(ctx *fasthttp.RequestCtx) (*model.OrderAttachment, bool) {
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return nil, false
+	}
+	orderId := order.OrderId
+
+	attachmentId, ok := ctx.UserValue("attachmentId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	attachment, err := c.attachments.GetById(ctx, orderId, attachmentId)
+	if err != nil {
+		if errors.Is(err, attachments.ErrNotFoundAttachment) {
+			ctx.Error("attachment not found", fasthttp.StatusNotFound)
+			return nil, false
+		}
+		c.logger.Error().Err(err).Msg("Error getting attachment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return nil, false
+	}
+
+	return attachment, true
+}
+
+// deleteBlob removes a blob that is no longer referenced. A failure only
+// leaves an orphaned file behind, so it is logged and not reported.
+func (c *Controller) deleteBlob(key string) {
+	if err := c.blobs.Delete(context.Background(), key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+		c.logger.Error().Err(err).Str("key", key).Msg("Error deleting blob")
+	}
+}
+
+// cleanFilename drops any directory part and control characters a client
+// might send and shortens the name to fit the column
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		ext := []rune(filepath.Ext(name))
+		if len(ext) >= maxFilenameLength {
+			ext = nil
+		}
+		name = string(runes[:maxFilenameLength-len(ext)]) + string(ext)
+	}
+
+	return name
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
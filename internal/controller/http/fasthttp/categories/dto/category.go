@@ -0,0 +1,12 @@
+package dto
+
+type Category struct {
+	CategoryId string `json:"categoryId"`
+	ParentId   string `json:"parentId,omitempty"`
+	Name       string `json:"name"`
+}
+
+type SaveCategory struct {
+	ParentId string `json:"parentId"`
+	Name     string `json:"name"`
+}
@@ -0,0 +1,149 @@
+package categories
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/categories/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/categories"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+type Controller struct {
+	categories categories.Repository
+	logger     zerolog.Logger
+}
+
+func NewController(categories categories.Repository, logger zerolog.Logger) *Controller {
+	return &Controller{
+		categories: categories,
+		logger:     logger,
+	}
+}
+
+// Categories returns all categories as a flat list, the tree is built by
+// the client from parentId
+func (c *Controller) Categories(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	found, err := c.categories.GetAll(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting categories")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Category, 0, len(found))
+	for _, category := range found {
+		resp = append(resp, &dto.Category{
+			CategoryId: category.CategoryId,
+			ParentId:   category.ParentId,
+			Name:       category.Name,
+		})
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// NewCategory creates a category (Director only)
+func (c *Controller) NewCategory(ctx *fasthttp.RequestCtx) {
+	category, ok := c.parseCategory(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.categories.Save(ctx, category); err != nil {
+		if errors.Is(err, categories.ErrNotFoundCategory) {
+			ctx.Error("parent category not found", fasthttp.StatusBadRequest)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error saving category")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(&dto.Category{
+		CategoryId: category.CategoryId,
+		ParentId:   category.ParentId,
+		Name:       category.Name,
+	}); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateCategory renames or moves a category (Director only)
+func (c *Controller) UpdateCategory(ctx *fasthttp.RequestCtx) {
+	categoryId, ok := ctx.UserValue("categoryId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	category, ok := c.parseCategory(ctx)
+	if !ok {
+		return
+	}
+	category.CategoryId = categoryId
+
+	if err := c.categories.Update(ctx, category); err != nil {
+		switch {
+		case errors.Is(err, categories.ErrNotFoundCategory):
+			ctx.Error("category not found", fasthttp.StatusNotFound)
+		case errors.Is(err, categories.ErrCategoryCycle):
+			ctx.Error("category cannot be moved below itself", fasthttp.StatusBadRequest)
+		default:
+			c.logger.Error().Err(err).Msg("Error updating category")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		}
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+func (c *Controller) parseCategory(ctx *fasthttp.RequestCtx) (*model.Category, bool) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return nil, false
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	var req *dto.SaveCategory
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		ctx.Error("Category name must not be empty", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	return &model.Category{
+		ParentId: req.ParentId,
+		Name:     name,
+	}, true
+}
@@ -0,0 +1,119 @@
+package dto
+
+import (
+	commentsDto "backend_crm/internal/controller/http/fasthttp/comments/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/usecase/collab"
+	"encoding/json"
+	"time"
+)
+
+// Message types of the client
+const (
+	TypeAuth   = "auth"
+	TypeLock   = "lock"
+	TypeUnlock = "unlock"
+)
+
+// Message types of the server besides the ones of collab
+const (
+	TypeSession = "session"
+	TypeError   = "error"
+)
+
+// ClientMessage is sent by the client. The token is only read from the
+// first message, of type auth.
+type ClientMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// Session is the first message of the server, once the client
+// authenticated
+type Session struct {
+	Type      string `json:"type"`
+	SessionId string `json:"sessionId"`
+	UserId    string `json:"userId"`
+}
+
+type Error struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+type Presence struct {
+	Type    string   `json:"type"`
+	Viewers []Viewer `json:"viewers"`
+}
+
+type Viewer struct {
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+}
+
+// Lock is sent with a nil lock when the order is free
+type Lock struct {
+	Type string     `json:"type"`
+	Lock *OrderLock `json:"lock"`
+}
+
+type OrderLock struct {
+	UserId     string    `json:"userId"`
+	Username   string    `json:"username"`
+	SessionId  string    `json:"sessionId"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+type Comment struct {
+	Type    string               `json:"type"`
+	Comment *commentsDto.Comment `json:"comment"`
+}
+
+type Status struct {
+	Type       string          `json:"type"`
+	EventId    string          `json:"eventId"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurredAt"`
+}
+
+func NewError(message string) *Error {
+	return &Error{Type: TypeError, Error: message}
+}
+
+// MessageFromModel returns the JSON form of a message of the hub
+func MessageFromModel(message *collab.Message) any {
+	switch message.Type {
+	case collab.MessagePresence:
+		viewers := make([]Viewer, 0, len(message.Viewers))
+		for _, v := range message.Viewers {
+			viewers = append(viewers, Viewer{UserId: v.UserId, Username: v.Username})
+		}
+		return &Presence{Type: string(message.Type), Viewers: viewers}
+	case collab.MessageLock:
+		return &Lock{Type: string(message.Type), Lock: LockFromModel(message.Lock)}
+	case collab.MessageComment:
+		return &Comment{Type: string(message.Type), Comment: commentsDto.CommentFromModel(message.Comment)}
+	case collab.MessageStatus:
+		return &Status{
+			Type:       string(message.Type),
+			EventId:    message.Event.EventId,
+			Data:       message.Event.Data,
+			OccurredAt: message.Event.CreatedAt,
+		}
+	}
+	return nil
+}
+
+func LockFromModel(lock *model.OrderLock) *OrderLock {
+	if lock == nil {
+		return nil
+	}
+	return &OrderLock{
+		UserId:     lock.UserId,
+		Username:   lock.Username,
+		SessionId:  lock.SessionId,
+		AcquiredAt: lock.AcquiredAt,
+		ExpiresAt:  lock.ExpiresAt,
+	}
+}
@@ -0,0 +1,241 @@
+package collab
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/collab/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	usersRepo "backend_crm/internal/repository/users"
+	"backend_crm/internal/server"
+	"backend_crm/internal/usecase/collab"
+	"backend_crm/internal/usecase/users"
+	"backend_crm/internal/websocket"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// maxMessageSize limits the messages of clients, which are small
+	maxMessageSize = 8 << 10
+	// authTimeout is the wait for the auth message
+	authTimeout = 10 * time.Second
+)
+
+// Close codes of the application
+const (
+	closeUnauthorized = 4401
+	closeNotFound     = 4404
+)
+
+type Controller struct {
+	hub          collab.Hub
+	users        users.Usecase
+	usersRepo    usersRepo.Repository
+	orders       orders.Repository
+	timeouts     *server.Timeouts
+	pingInterval time.Duration
+	logger       zerolog.Logger
+}
+
+func NewController(
+	hub collab.Hub,
+	users users.Usecase,
+	usersRepo usersRepo.Repository,
+	orders orders.Repository,
+	timeouts *server.Timeouts,
+	pingInterval time.Duration,
+	logger zerolog.Logger,
+) *Controller {
+	return &Controller{
+		hub:          hub,
+		users:        users,
+		usersRepo:    usersRepo,
+		orders:       orders,
+		timeouts:     timeouts,
+		pingInterval: pingInterval,
+		logger:       logger,
+	}
+}
+
+// Live upgrades to a WebSocket on which the client is told who views the
+// order, who holds its edit lock and about new comments and status
+// changes. Browsers cannot send the Authorization header with a WebSocket,
+// the access token is the first message instead.
+func (c *Controller) Live(ctx *fasthttp.RequestCtx) {
+	orderId, ok := ctx.UserValue("orderId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	options := websocket.Options{
+		ReadLimit:    maxMessageSize,
+		ReadTimeout:  authTimeout,
+		WriteTimeout: c.timeouts.Write(),
+	}
+	websocket.Upgrade(ctx, options, func(conn *websocket.Conn) {
+		c.serve(conn, orderId)
+	})
+}
+
+func (c *Controller) serve(conn *websocket.Conn, orderId string) {
+	// The connection outlives the request
+	ctx := context.Background()
+
+	viewer, ok := c.authenticate(ctx, conn, orderId)
+	if !ok {
+		return
+	}
+
+	session, err := c.hub.Join(ctx, orderId, viewer)
+	if err != nil {
+		if errors.Is(err, collab.ErrStopped) {
+			conn.Close(websocket.CloseGoingAway, "")
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error joining order session")
+		conn.Close(websocket.CloseInternalError, "")
+		return
+	}
+	defer c.hub.Leave(ctx, session)
+
+	c.send(conn, &dto.Session{Type: dto.TypeSession, SessionId: session.SessionId, UserId: viewer.UserId})
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.write(conn, session, done)
+
+	// Pings are answered within the interval
+	conn.SetReadTimeout(2 * c.pingInterval)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message dto.ClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			c.send(conn, dto.NewError("Invalid JSON format"))
+			continue
+		}
+
+		switch message.Type {
+		case dto.TypeLock:
+			// The new lock reaches every viewer, the requester included
+			current, err := c.hub.Lock(ctx, session)
+			if errors.Is(err, collab.ErrLocked) {
+				c.send(conn, dto.NewError("order is locked"))
+				if current != nil {
+					c.send(conn, &dto.Lock{Type: string(collab.MessageLock), Lock: dto.LockFromModel(current)})
+				}
+			} else if err != nil {
+				c.logger.Error().Err(err).Msg("Error locking order")
+				c.send(conn, dto.NewError("Error on the server"))
+			}
+		case dto.TypeUnlock:
+			if err := c.hub.Unlock(ctx, session); err != nil {
+				c.logger.Error().Err(err).Msg("Error unlocking order")
+				c.send(conn, dto.NewError("Error on the server"))
+			}
+		default:
+			c.send(conn, dto.NewError("unknown message type"))
+		}
+	}
+}
+
+// authenticate reads the auth message and checks the user may see the
+// order. The connection is closed when not.
+func (c *Controller) authenticate(ctx context.Context, conn *websocket.Conn, orderId string) (model.Viewer, bool) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return model.Viewer{}, false
+	}
+
+	var message dto.ClientMessage
+	if err := json.Unmarshal(data, &message); err != nil || message.Type != dto.TypeAuth {
+		conn.Close(closeUnauthorized, "auth message expected")
+		return model.Viewer{}, false
+	}
+
+	userId, userRole, err := c.users.CheckAccess(ctx, message.Token)
+	if err != nil {
+		if errors.Is(err, users.ErrExpiredAccessToken) {
+			conn.Close(closeUnauthorized, "Expired access token")
+			return model.Viewer{}, false
+		}
+		conn.Close(closeUnauthorized, "Invalid access token")
+		return model.Viewer{}, false
+	}
+
+	order, err := c.orders.GetById(ctx, orderId)
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFoundOrder) {
+			conn.Close(closeNotFound, "order not found")
+			return model.Viewer{}, false
+		}
+		c.logger.Error().Err(err).Msg("Error getting order")
+		conn.Close(websocket.CloseInternalError, "")
+		return model.Viewer{}, false
+	}
+
+	// Orders of other employees are reported as missing
+	if !order.VisibleTo(userId, userRole) {
+		conn.Close(closeNotFound, "order not found")
+		return model.Viewer{}, false
+	}
+
+	user, err := c.usersRepo.GetById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, usersRepo.ErrNotFoundUser) {
+			conn.Close(closeUnauthorized, "Invalid access token")
+			return model.Viewer{}, false
+		}
+		c.logger.Error().Err(err).Msg("Error getting user")
+		conn.Close(websocket.CloseInternalError, "")
+		return model.Viewer{}, false
+	}
+
+	return model.Viewer{UserId: user.UserId, Username: user.Username}, true
+}
+
+// write sends the messages of the session and the pings until done. The
+// connection is closed when the session ends first, so the reader stops.
+func (c *Controller) write(conn *websocket.Conn, session *collab.Session, done <-chan struct{}) {
+	ping := time.NewTicker(c.pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case message, ok := <-session.Messages:
+			if !ok {
+				// The server shuts down or the client fell behind, it
+				// reconnects either way
+				conn.Close(websocket.CloseGoingAway, "")
+				return
+			}
+			if err := c.send(conn, dto.MessageFromModel(message)); err != nil {
+				conn.Close(websocket.CloseGoingAway, "")
+				return
+			}
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Controller) send(conn *websocket.Conn, message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error encoding message")
+		return err
+	}
+	return conn.WriteMessage(websocket.OpText, data)
+}
@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+type Comment struct {
+	CommentId string     `json:"commentId"`
+	ParentId  string     `json:"parentId,omitempty"`
+	UserId    string     `json:"userId"`
+	Username  string     `json:"username"`
+	Body      string     `json:"body"`
+	Mentions  []Mention  `json:"mentions"`
+	Edited    bool       `json:"edited"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Replies   []*Comment `json:"replies"`
+}
+
+type Mention struct {
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+}
+
+type NewComment struct {
+	ParentId string `json:"parentId"`
+	Body     string `json:"body"`
+}
+
+type UpdateComment struct {
+	Body string `json:"body"`
+}
+
+type CommentEdit struct {
+	EditId   string    `json:"editId"`
+	UserId   string    `json:"userId"`
+	Body     string    `json:"body"`
+	EditedAt time.Time `json:"editedAt"`
+}
+
+func CommentFromModel(comment *model.OrderComment) *Comment {
+	mentions := make([]Mention, 0, len(comment.Mentions))
+	for _, m := range comment.Mentions {
+		mentions = append(mentions, Mention{UserId: m.UserId, Username: m.Username})
+	}
+
+	return &Comment{
+		CommentId: comment.CommentId,
+		ParentId:  comment.ParentId,
+		UserId:    comment.UserId,
+		Username:  comment.Username,
+		Body:      comment.Body,
+		Mentions:  mentions,
+		Edited:    comment.Edited,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   []*Comment{},
+	}
+}
+
+// ThreadFromModel nests replies below their parents. comments must be
+// ordered oldest first, which keeps every thread in chronological order.
+func ThreadFromModel(comments []*model.OrderComment) []*Comment {
+	byId := make(map[string]*Comment, len(comments))
+	roots := make([]*Comment, 0, len(comments))
+	for _, comment := range comments {
+		c := CommentFromModel(comment)
+		byId[c.CommentId] = c
+		if parent, ok := byId[c.ParentId]; ok {
+			parent.Replies = append(parent.Replies, c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	return roots
+}
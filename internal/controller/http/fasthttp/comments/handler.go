@@ -0,0 +1,256 @@
+package comments
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/access"
+	"backend_crm/internal/controller/http/fasthttp/comments/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/comments"
+	"backend_crm/internal/repository/orders"
+	"backend_crm/internal/usecase/collab"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// maxBodyLength limits a comment in characters
+const maxBodyLength = 10000
+
+type Controller struct {
+	comments comments.Repository
+	orders   orders.Repository
+	hub      collab.Hub
+	logger   zerolog.Logger
+}
+
+func NewController(comments comments.Repository, orders orders.Repository, hub collab.Hub, logger zerolog.Logger) *Controller {
+	return &Controller{
+		comments: comments,
+		orders:   orders,
+		hub:      hub,
+		logger:   logger,
+	}
+}
+
+// Comments returns the internal discussion of an order as threads
+func (c *Controller) Comments(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+	orderId := order.OrderId
+
+	found, err := c.comments.GetByOrderId(ctx, orderId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting comments")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.ThreadFromModel(found)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// NewComment adds a comment or a reply to an order. Users mentioned with
+// @username are recorded.
+func (c *Controller) NewComment(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+	orderId := order.OrderId
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var newComment *dto.NewComment
+	if err := json.Unmarshal(body, &newComment); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	text, ok := validBody(ctx, newComment.Body)
+	if !ok {
+		return
+	}
+
+	comment := &model.OrderComment{
+		OrderId:  orderId,
+		ParentId: newComment.ParentId,
+		UserId:   ctx.UserValue("user_id").(string),
+		Body:     text,
+	}
+	if err := c.comments.Save(ctx, comment); err != nil {
+		switch {
+		case errors.Is(err, comments.ErrNotFoundOrder):
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+		case errors.Is(err, comments.ErrNotFoundComment), errors.Is(err, comments.ErrInvalidParent):
+			ctx.Error("parent comment not found on this order", fasthttp.StatusBadRequest)
+		default:
+			c.logger.Error().Err(err).Msg("Error saving comment")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Viewers of the order see the comment without reloading
+	c.hub.CommentChanged(ctx, orderId, comment.CommentId)
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(dto.CommentFromModel(comment)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateComment changes the text of a comment. Only the author can edit,
+// the previous text is kept in the history.
+func (c *Controller) UpdateComment(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	comment, ok := c.accessibleComment(ctx)
+	if !ok {
+		return
+	}
+
+	userId := ctx.UserValue("user_id").(string)
+	if comment.UserId != userId {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var update *dto.UpdateComment
+	if err := json.Unmarshal(body, &update); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	text, ok := validBody(ctx, update.Body)
+	if !ok {
+		return
+	}
+
+	if err := c.comments.Update(ctx, comment.CommentId, userId, text); err != nil {
+		if errors.Is(err, comments.ErrNotFoundComment) {
+			ctx.Error("comment not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating comment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	c.hub.CommentChanged(ctx, comment.OrderId, comment.CommentId)
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// CommentHistory returns the previous versions of a comment, newest first
+func (c *Controller) CommentHistory(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	comment, ok := c.accessibleComment(ctx)
+	if !ok {
+		return
+	}
+
+	edits, err := c.comments.GetEdits(ctx, comment.CommentId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting comment history")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.CommentEdit, 0, len(edits))
+	for _, edit := range edits {
+		resp = append(resp, &dto.CommentEdit{
+			EditId:   edit.EditId,
+			UserId:   edit.UserId,
+			Body:     edit.Body,
+			EditedAt: edit.EditedAt,
+		})
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// accessibleComment loads the comment of the request and checks that it
+// belongs to an order the caller may see
+func (c *Controller) accessibleComment(ctx *fasthttp.RequestCtx) (*model.OrderComment, bool) {
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return nil, false
+	}
+	orderId := order.OrderId
+
+	commentId, ok := ctx.UserValue("commentId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	comment, err := c.comments.GetById(ctx, commentId)
+	if err != nil {
+		if errors.Is(err, comments.ErrNotFoundComment) {
+			ctx.Error("comment not found", fasthttp.StatusNotFound)
+			return nil, false
+		}
+		c.logger.Error().Err(err).Msg("Error getting comment")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return nil, false
+	}
+	if comment.OrderId != orderId {
+		ctx.Error("comment not found", fasthttp.StatusNotFound)
+		return nil, false
+	}
+
+	return comment, true
+}
+
+func validBody(ctx *fasthttp.RequestCtx, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		ctx.Error("Comment must not be empty", fasthttp.StatusBadRequest)
+		return "", false
+	}
+	if utf8.RuneCountInString(body) > maxBodyLength {
+		ctx.Error("Comment is too long", fasthttp.StatusBadRequest)
+		return "", false
+	}
+	return body, true
+}
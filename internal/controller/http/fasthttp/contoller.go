@@ -1,9 +1,22 @@
 package fasthttp
 
 import (
+	"backend_crm/internal/controller/http/fasthttp/analytics"
 	"backend_crm/internal/controller/http/fasthttp/app"
+	"backend_crm/internal/controller/http/fasthttp/attachments"
 	"backend_crm/internal/controller/http/fasthttp/authorization"
+	"backend_crm/internal/controller/http/fasthttp/categories"
+	"backend_crm/internal/controller/http/fasthttp/collab"
+	"backend_crm/internal/controller/http/fasthttp/comments"
+	"backend_crm/internal/controller/http/fasthttp/customers"
+	"backend_crm/internal/controller/http/fasthttp/customfields"
+	"backend_crm/internal/controller/http/fasthttp/events"
+	"backend_crm/internal/controller/http/fasthttp/imports"
 	"backend_crm/internal/controller/http/fasthttp/orders"
+	"backend_crm/internal/controller/http/fasthttp/products"
+	"backend_crm/internal/controller/http/fasthttp/reports"
+	"backend_crm/internal/controller/http/fasthttp/search"
+	"backend_crm/internal/controller/http/fasthttp/webhooks"
 	"context"
 
 	"github.com/fasthttp/router"
@@ -13,17 +26,56 @@ import (
 type controller struct {
 	authorization authorization.Controller
 	orders        orders.Contoller
+	comments      comments.Controller
+	attachments   attachments.Controller
+	customers     customers.Controller
+	products      products.Controller
+	categories    categories.Controller
+	customFields  customfields.Controller
+	search        search.Controller
+	imports       imports.Controller
+	analytics     analytics.Controller
+	reports       reports.Controller
+	webhooks      webhooks.Controller
+	events        events.Controller
+	collab        collab.Controller
 	app           app.Controller
 }
 
 func NewController(
 	auth authorization.Controller,
 	orders orders.Contoller,
+	comments comments.Controller,
+	attachments attachments.Controller,
+	customers customers.Controller,
+	products products.Controller,
+	categories categories.Controller,
+	customFields customfields.Controller,
+	search search.Controller,
+	imports imports.Controller,
+	analytics analytics.Controller,
+	reports reports.Controller,
+	webhooks webhooks.Controller,
+	events events.Controller,
+	collab collab.Controller,
 	app app.Controller,
 ) *controller {
 	return &controller{
 		authorization: auth,
 		orders:        orders,
+		comments:      comments,
+		attachments:   attachments,
+		customers:     customers,
+		products:      products,
+		categories:    categories,
+		customFields:  customFields,
+		search:        search,
+		imports:       imports,
+		analytics:     analytics,
+		reports:       reports,
+		webhooks:      webhooks,
+		events:        events,
+		collab:        collab,
 		app:           app,
 	}
 }
@@ -40,8 +92,89 @@ func (c *controller) Handlers(ctx context.Context) fasthttp.RequestHandler {
 
 	orders := apiV1.Group("/orders")
 	orders.GET("/{status}", c.addAuthMiddleware(c.orders.Orders))
+	orders.GET("/{status}/export", c.addAuthMiddleware(c.orders.Export))
 	orders.POST("/order/{orderId}", c.addAuthMiddleware(c.orders.UpdateOrder))
+	orders.POST("/order/{orderId}/discount", c.addAuthMiddleware(c.orders.UpdateDiscount))
+	orders.POST("/order/{orderId}/tags", c.addAuthMiddleware(c.orders.UpdateTags))
+	orders.POST("/order/{orderId}/fields", c.addAuthMiddleware(c.orders.UpdateFields))
+	orders.GET("/order/{orderId}/notifications", c.addAuthMiddleware(c.orders.Notifications))
+	orders.POST("/order/{orderId}/notifications", c.addAuthMiddleware(c.orders.UpdateNotifications))
 	orders.POST("/new-order", c.addAuthMiddleware(c.orders.NewOrder))
+	orders.POST("/bulk", c.addAuthMiddleware(c.orders.BulkUpdate))
+	orders.GET("/order/{orderId}/history", c.addAuthMiddleware(c.orders.History))
+	// The WebSocket authenticates with its first message
+	orders.GET("/order/{orderId}/live", c.collab.Live)
+	orders.GET("/order/{orderId}/comments", c.addAuthMiddleware(c.comments.Comments))
+	orders.POST("/order/{orderId}/comments", c.addAuthMiddleware(c.comments.NewComment))
+	orders.POST("/order/{orderId}/comments/{commentId}", c.addAuthMiddleware(c.comments.UpdateComment))
+	orders.GET("/order/{orderId}/comments/{commentId}/history", c.addAuthMiddleware(c.comments.CommentHistory))
+	orders.GET("/order/{orderId}/attachments", c.addAuthMiddleware(c.attachments.Attachments))
+	orders.POST("/order/{orderId}/attachments", c.addAuthMiddleware(c.attachments.Upload))
+	orders.GET("/order/{orderId}/attachments/{attachmentId}", c.addAuthMiddleware(c.attachments.Download))
+	orders.DELETE("/order/{orderId}/attachments/{attachmentId}", c.addAuthMiddleware(c.attachments.Delete))
+
+	apiV1.GET("/products", c.addAuthMiddleware(c.products.Products))
+	products := apiV1.Group("/products")
+	products.GET("/product/{productId}", c.addAuthMiddleware(c.products.Product))
+	products.POST("/product/{productId}", c.addAuthMiddleware(c.products.UpdateProduct))
+	products.DELETE("/product/{productId}", c.addAuthMiddleware(c.products.DeleteProduct))
+	products.GET("/product/{productId}/stock-movements", c.addAuthMiddleware(c.products.StockMovements))
+	products.POST("/product/{productId}/stock", c.addAuthMiddleware(c.products.AdjustStock))
+	products.GET("/product/{productId}/images", c.addAuthMiddleware(c.products.Images))
+	products.POST("/product/{productId}/images", c.addAuthMiddleware(c.products.UploadImage))
+	products.GET("/product/{productId}/images/{imageId}", c.addAuthMiddleware(c.products.Image))
+	products.DELETE("/product/{productId}/images/{imageId}", c.addAuthMiddleware(c.products.DeleteImage))
+	products.GET("/product/{productId}/images/{imageId}/thumbnail", c.addAuthMiddleware(c.products.Thumbnail))
+	products.POST("/new-product", c.addAuthMiddleware(c.products.NewProduct))
+
+	apiV1.GET("/categories", c.addAuthMiddleware(c.categories.Categories))
+	categories := apiV1.Group("/categories")
+	categories.POST("/category/{categoryId}", c.addAuthMiddleware(c.categories.UpdateCategory))
+	categories.POST("/new-category", c.addAuthMiddleware(c.categories.NewCategory))
+
+	apiV1.GET("/custom-fields", c.addAuthMiddleware(c.customFields.Fields))
+	customFields := apiV1.Group("/custom-fields")
+	customFields.POST("/field/{key}", c.addAuthMiddleware(c.customFields.UpdateField))
+	customFields.DELETE("/field/{key}", c.addAuthMiddleware(c.customFields.DeleteField))
+	customFields.POST("/new-field", c.addAuthMiddleware(c.customFields.NewField))
+
+	apiV1.GET("/search", c.addAuthMiddleware(c.search.Search))
+
+	imports := apiV1.Group("/import")
+	imports.POST("/products", c.addAuthMiddleware(c.imports.Products))
+	imports.POST("/orders", c.addAuthMiddleware(c.imports.Orders))
+
+	analytics := apiV1.Group("/analytics")
+	analytics.GET("/orders-by-status", c.addAuthMiddleware(c.analytics.OrdersByStatus))
+	analytics.GET("/new-orders", c.addAuthMiddleware(c.analytics.NewOrders))
+	analytics.GET("/completion-time", c.addAuthMiddleware(c.analytics.CompletionTime))
+	analytics.GET("/rejection-rate", c.addAuthMiddleware(c.analytics.RejectionRate))
+	analytics.GET("/top-products", c.addAuthMiddleware(c.analytics.TopProducts))
+	analytics.GET("/employees", c.addAuthMiddleware(c.analytics.Employees))
+
+	apiV1.GET("/reports", c.addAuthMiddleware(c.reports.Reports))
+	reports := apiV1.Group("/reports")
+	reports.GET("/{name}", c.addAuthMiddleware(c.reports.Preview))
+	reports.POST("/{name}/send", c.addAuthMiddleware(c.reports.Send))
+
+	apiV1.GET("/webhooks", c.addAuthMiddleware(c.webhooks.Webhooks))
+	webhooks := apiV1.Group("/webhooks")
+	webhooks.GET("/webhook/{webhookId}", c.addAuthMiddleware(c.webhooks.Webhook))
+	webhooks.POST("/webhook/{webhookId}", c.addAuthMiddleware(c.webhooks.UpdateWebhook))
+	webhooks.DELETE("/webhook/{webhookId}", c.addAuthMiddleware(c.webhooks.DeleteWebhook))
+	webhooks.GET("/webhook/{webhookId}/deliveries", c.addAuthMiddleware(c.webhooks.Deliveries))
+	webhooks.POST("/webhook/{webhookId}/deliveries/{deliveryId}/redeliver", c.addAuthMiddleware(c.webhooks.Redeliver))
+	webhooks.POST("/new-webhook", c.addAuthMiddleware(c.webhooks.NewWebhook))
+
+	apiV1.GET("/events", c.addAuthMiddleware(c.events.Stream))
+
+	apiV1.GET("/customers", c.addAuthMiddleware(c.customers.Customers))
+	customers := apiV1.Group("/customers")
+	customers.POST("/merge", c.addAuthMiddleware(c.customers.Merge))
+	customers.GET("/{customerId}", c.addAuthMiddleware(c.customers.Customer))
+	customers.POST("/{customerId}", c.addAuthMiddleware(c.customers.UpdateCustomer))
+	customers.POST("/{customerId}/split", c.addAuthMiddleware(c.customers.Split))
+	customers.GET("/{customerId}/audit", c.addAuthMiddleware(c.customers.Audit))
 
 	auth := apiV1.Group("/auth")
 	auth.GET("/access", c.authorization.Access)
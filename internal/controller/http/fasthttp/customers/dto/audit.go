@@ -0,0 +1,14 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+	AuditId   string          `json:"auditId"`
+	Action    string          `json:"action"`
+	UserId    string          `json:"userId"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
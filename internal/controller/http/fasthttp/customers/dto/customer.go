@@ -0,0 +1,38 @@
+package dto
+
+import (
+	ordersDto "backend_crm/internal/controller/http/fasthttp/orders/dto"
+	"backend_crm/internal/model"
+)
+
+type Customer struct {
+	CustomerId string   `json:"customerId"`
+	Name       string   `json:"name"`
+	Phones     []string `json:"phones"`
+	Emails     []string `json:"emails"`
+	Notes      string   `json:"notes"`
+	Tags       []string `json:"tags"`
+}
+
+type CustomerDetails struct {
+	Customer
+	Orders []*ordersDto.Order `json:"orders"`
+}
+
+func CustomerFromModel(customer *model.Customer) *Customer {
+	return &Customer{
+		CustomerId: customer.CustomerId,
+		Name:       customer.Name,
+		Phones:     nonNil(customer.Phones),
+		Emails:     nonNil(customer.Emails),
+		Notes:      customer.Notes,
+		Tags:       nonNil(customer.Tags),
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
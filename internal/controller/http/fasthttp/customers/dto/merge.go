@@ -0,0 +1,15 @@
+package dto
+
+type MergeCustomers struct {
+	TargetId  string   `json:"targetId"`
+	SourceIds []string `json:"sourceIds"`
+}
+
+type SplitCustomer struct {
+	OrderIds []string `json:"orderIds"`
+	Name     string   `json:"name"`
+}
+
+type SplitResult struct {
+	CustomerId string `json:"customerId"`
+}
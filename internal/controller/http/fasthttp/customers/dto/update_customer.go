@@ -0,0 +1,7 @@
+package dto
+
+type UpdateCustomer struct {
+	Name  string   `json:"name"`
+	Notes string   `json:"notes"`
+	Tags  []string `json:"tags"`
+}
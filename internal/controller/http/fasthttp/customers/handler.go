@@ -0,0 +1,185 @@
+package customers
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/customers/dto"
+	ordersDto "backend_crm/internal/controller/http/fasthttp/orders/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customers"
+	"backend_crm/internal/repository/orders"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+type Controller struct {
+	customers customers.Repository
+	orders    orders.Repository
+	logger    zerolog.Logger
+}
+
+func NewController(customers customers.Repository, orders orders.Repository, logger zerolog.Logger) *Controller {
+	return &Controller{
+		customers: customers,
+		orders:    orders,
+		logger:    logger,
+	}
+}
+
+// Customers lists customers. Directors see everybody, other roles only the
+// customers of orders assigned to them.
+func (c *Controller) Customers(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	userRole, ok := ctx.UserValue("user_role").(model.Role)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var found []*model.Customer
+	var err error
+	if userRole == model.Director {
+		found, err = c.customers.GetAll(ctx)
+	} else {
+		found, err = c.customers.GetByUserId(ctx, ctx.UserValue("user_id").(string))
+	}
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting customers")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Customer, 0, len(found))
+	for _, customer := range found {
+		resp = append(resp, dto.CustomerFromModel(customer))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Customer returns a customer together with the order history visible to
+// the caller.
+func (c *Controller) Customer(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	customerId, ok := ctx.UserValue("customerId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	userRole, ok := ctx.UserValue("user_role").(model.Role)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	customer, err := c.customers.GetById(ctx, customerId)
+	if err != nil {
+		if errors.Is(err, customers.ErrNotFoundCustomer) {
+			ctx.Error("customer not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error getting customer")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	var history []*model.Order
+	if userRole == model.Director {
+		history, err = c.orders.GetByCustomerId(ctx, customerId)
+	} else {
+		history, err = c.orders.GetByCustomerIdAndUserId(ctx, customerId, ctx.UserValue("user_id").(string))
+		if err == nil && len(history) == 0 {
+			ctx.Error("customer not found", fasthttp.StatusNotFound)
+			return
+		}
+	}
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting customer orders")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(&dto.CustomerDetails{
+		Customer: *dto.CustomerFromModel(customer),
+		Orders:   ordersDto.OrdersFromModel(history),
+	}); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateCustomer changes the name, notes and tags of a customer. Contacts are
+// maintained from incoming orders.
+func (c *Controller) UpdateCustomer(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	customerId, ok := ctx.UserValue("customerId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var update *dto.UpdateCustomer
+	if err := json.Unmarshal(body, &update); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if err := c.customers.Update(ctx, customerId, &model.CustomerUpdate{
+		Name:  strings.TrimSpace(update.Name),
+		Notes: update.Notes,
+		Tags:  cleanTags(update.Tags),
+	}); err != nil {
+		if errors.Is(err, customers.ErrNotFoundCustomer) {
+			ctx.Error("customer not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating customer")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+func cleanTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !slices.Contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,157 @@
+package customers
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/customers/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customers"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Merge joins duplicate customers into the target customer (Director only)
+func (c *Controller) Merge(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var merge *dto.MergeCustomers
+	if err := json.Unmarshal(body, &merge); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	if err := c.customers.Merge(ctx, &model.CustomerMerge{
+		TargetId:  merge.TargetId,
+		SourceIds: merge.SourceIds,
+		UserId:    userId,
+	}); err != nil {
+		switch {
+		case errors.Is(err, customers.ErrInvalidMerge):
+			ctx.Error("source customers must be given and differ from the target", fasthttp.StatusBadRequest)
+		case errors.Is(err, customers.ErrNotFoundCustomer):
+			ctx.Error("customer not found", fasthttp.StatusNotFound)
+		default:
+			c.logger.Error().Err(err).Msg("Error merging customers")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		}
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// Split moves the selected orders of a customer to a new customer (Director only)
+func (c *Controller) Split(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	customerId, ok := ctx.UserValue("customerId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var split *dto.SplitCustomer
+	if err := json.Unmarshal(body, &split); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	newId, err := c.customers.Split(ctx, &model.CustomerSplit{
+		CustomerId: customerId,
+		OrderIds:   split.OrderIds,
+		Name:       strings.TrimSpace(split.Name),
+		UserId:     userId,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, customers.ErrInvalidSplit):
+			ctx.Error("orders must belong to the customer and at least one must stay", fasthttp.StatusBadRequest)
+		case errors.Is(err, customers.ErrNotFoundCustomer):
+			ctx.Error("customer not found", fasthttp.StatusNotFound)
+		default:
+			c.logger.Error().Err(err).Msg("Error splitting customer")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		}
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(&dto.SplitResult{CustomerId: newId}); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Audit returns the merge and split history of a customer (Director only)
+func (c *Controller) Audit(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	customerId, ok := ctx.UserValue("customerId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	entries, err := c.customers.GetAudit(ctx, customerId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting customer audit")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.AuditEntry, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, &dto.AuditEntry{
+			AuditId:   e.AuditId,
+			Action:    string(e.Action),
+			UserId:    e.UserId,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
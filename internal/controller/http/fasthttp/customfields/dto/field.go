@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+type Field struct {
+	Key       string    `json:"key"`
+	Label     string    `json:"label"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type SaveField struct {
+	Key     string   `json:"key"`
+	Label   string   `json:"label"`
+	Type    string   `json:"type"`
+	Options []string `json:"options"`
+}
+
+func FieldFromModel(field *model.CustomField) *Field {
+	return &Field{
+		Key:       field.Key,
+		Label:     field.Label,
+		Type:      string(field.Type),
+		Options:   field.Options,
+		CreatedAt: field.CreatedAt,
+	}
+}
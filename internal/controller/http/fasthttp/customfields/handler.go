@@ -0,0 +1,229 @@
+package customfields
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/customfields/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customfields"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+type Controller struct {
+	fields customfields.Repository
+	logger zerolog.Logger
+}
+
+func NewController(fields customfields.Repository, logger zerolog.Logger) *Controller {
+	return &Controller{
+		fields: fields,
+		logger: logger,
+	}
+}
+
+// Fields lists the custom fields orders may have
+func (c *Controller) Fields(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	found, err := c.fields.GetAll(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting custom fields")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Field, 0, len(found))
+	for _, field := range found {
+		resp = append(resp, dto.FieldFromModel(field))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// NewField defines a custom field (Director only)
+func (c *Controller) NewField(ctx *fasthttp.RequestCtx) {
+	field, ok := c.parseField(ctx)
+	if !ok {
+		return
+	}
+
+	if !field.ValidKey() {
+		ctx.Error("Key must start with a lower case letter and contain only a-z, 0-9 and _, at most 40 characters", fasthttp.StatusBadRequest)
+		return
+	}
+	if !field.ValidType() {
+		ctx.Error("type must be text, number, date or enum", fasthttp.StatusBadRequest)
+		return
+	}
+	if !validOptions(ctx, field) {
+		return
+	}
+
+	if err := c.fields.Save(ctx, field); err != nil {
+		if errors.Is(err, customfields.ErrDuplicateField) {
+			ctx.Error("custom field already exists", fasthttp.StatusConflict)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error saving custom field")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(dto.FieldFromModel(field)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateField changes the label and the enum options of a custom field
+// (Director only). Key and type are fixed, values already stored on orders
+// are kept even if an option is removed.
+func (c *Controller) UpdateField(ctx *fasthttp.RequestCtx) {
+	key, ok := ctx.UserValue("key").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	field, ok := c.parseField(ctx)
+	if !ok {
+		return
+	}
+
+	all, err := c.fields.GetAll(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting custom fields")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	i := slices.IndexFunc(all, func(f *model.CustomField) bool { return f.Key == key })
+	if i < 0 {
+		ctx.Error("custom field not found", fasthttp.StatusNotFound)
+		return
+	}
+	current := all[i]
+	if (field.Key != "" && field.Key != key) || (field.Type != "" && field.Type != current.Type) {
+		ctx.Error("Key and type of a custom field cannot be changed", fasthttp.StatusBadRequest)
+		return
+	}
+	field.Key = key
+	field.Type = current.Type
+	if !validOptions(ctx, field) {
+		return
+	}
+
+	if err := c.fields.Update(ctx, field); err != nil {
+		if errors.Is(err, customfields.ErrNotFoundField) {
+			ctx.Error("custom field not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating custom field")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// DeleteField removes a custom field and its values from all orders
+// (Director only)
+func (c *Controller) DeleteField(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsDelete() {
+		ctx.Error("Only DELETE method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	key, ok := ctx.UserValue("key").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	if err := c.fields.Delete(ctx, key); err != nil {
+		if errors.Is(err, customfields.ErrNotFoundField) {
+			ctx.Error("custom field not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error deleting custom field")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+func (c *Controller) parseField(ctx *fasthttp.RequestCtx) (*model.CustomField, bool) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return nil, false
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	var req *dto.SaveField
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	label := strings.TrimSpace(req.Label)
+	if label == "" || utf8.RuneCountInString(label) > 100 {
+		ctx.Error("Label must be 1 to 100 characters", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	options := make([]string, 0, len(req.Options))
+	for _, option := range req.Options {
+		option = strings.TrimSpace(option)
+		if option != "" && !slices.Contains(options, option) {
+			options = append(options, option)
+		}
+	}
+
+	return &model.CustomField{
+		Key:     strings.TrimSpace(req.Key),
+		Label:   label,
+		Type:    model.CustomFieldType(req.Type),
+		Options: options,
+	}, true
+}
+
+// validOptions requires options for enum fields and none for the others
+func validOptions(ctx *fasthttp.RequestCtx, field *model.CustomField) bool {
+	if field.Type == model.CustomFieldEnum && len(field.Options) == 0 {
+		ctx.Error("An enum field needs at least one option", fasthttp.StatusBadRequest)
+		return false
+	}
+	if field.Type != model.CustomFieldEnum && len(field.Options) > 0 {
+		ctx.Error("Only enum fields have options", fasthttp.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"encoding/json"
+	"time"
+)
+
+// Event is the data of an order event on the live stream. The event name
+// and id are sent as the type and id of the Server-Sent Event.
+type Event struct {
+	EventId    string          `json:"eventId"`
+	OrderId    string          `json:"orderId"`
+	AssigneeId string          `json:"assigneeId,omitempty"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurredAt"`
+}
+
+func EventFromModel(event *model.DomainEvent) *Event {
+	return &Event{
+		EventId:    event.EventId,
+		OrderId:    event.OrderId,
+		AssigneeId: event.AssigneeId,
+		Data:       event.Data,
+		OccurredAt: event.CreatedAt,
+	}
+}
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/events/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/server"
+	"backend_crm/internal/usecase/events"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// retryDelay is how long clients wait before reconnecting to the stream
+const retryDelay = 3 * time.Second
+
+type Controller struct {
+	feed      events.Feed
+	timeouts  *server.Timeouts
+	heartbeat time.Duration
+	logger    zerolog.Logger
+}
+
+func NewController(feed events.Feed, timeouts *server.Timeouts, heartbeat time.Duration, logger zerolog.Logger) *Controller {
+	return &Controller{
+		feed:      feed,
+		timeouts:  timeouts,
+		heartbeat: heartbeat,
+		logger:    logger,
+	}
+}
+
+// Stream sends the order events as Server-Sent Events: Directors get every
+// event, other roles the events of the orders assigned to them. A client
+// reconnecting with Last-Event-ID first gets the events it missed.
+func (c *Controller) Stream(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	userRole, _ := ctx.UserValue("user_role").(model.Role)
+
+	after := int64(-1)
+	if lastEventId := ctx.Request.Header.Peek("Last-Event-ID"); len(lastEventId) > 0 {
+		sequence, err := strconv.ParseInt(string(lastEventId), 10, 64)
+		if err != nil || sequence < 0 {
+			ctx.Error("Invalid Last-Event-ID", fasthttp.StatusBadRequest)
+			return
+		}
+		after = sequence
+	}
+
+	subscription, err := c.feed.Subscribe(ctx, after)
+	if err != nil {
+		if errors.Is(err, events.ErrFeedNotReady) {
+			ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, strconv.Itoa(int(retryDelay.Seconds())))
+			ctx.Error("Service unavailable", fasthttp.StatusServiceUnavailable)
+			return
+		}
+
+		c.logger.Error().Err(err).Msg("Error subscribing to events")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	// Keeps nginx from buffering the stream
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+
+	// The request context must not be used once the handler returned
+	conn := ctx.Conn()
+	shutdown := ctx.Done()
+	writeTimeout := c.timeouts.Write()
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer subscription.Cancel()
+
+		stream := server.NewEventStream(w, conn, writeTimeout)
+		defer stream.Close()
+
+		c.stream(stream, subscription, shutdown, after, userId, userRole)
+	})
+}
+
+// stream writes the events of the subscription until the client is gone,
+// the subscription ends or the server shuts down
+func (c *Controller) stream(
+	stream *server.EventStream,
+	subscription *events.Subscription,
+	shutdown <-chan struct{},
+	after int64,
+	userId string,
+	userRole model.Role,
+) {
+	// sent is the last id the client got, seen the last event it may skip
+	sent, seen := after, after
+
+	stream.Retry(retryDelay)
+	if subscription.Reset {
+		sent, seen = subscription.Sequence, subscription.Sequence
+		stream.Event(strconv.FormatInt(sent, 10), "reset", []byte("{}"))
+	}
+	for _, event := range subscription.Backlog {
+		seen = event.Sequence
+		if event.VisibleTo(userId, userRole) && c.write(stream, event) {
+			sent = seen
+		}
+	}
+	if err := stream.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(c.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			// The instance the client was connected to may have been
+			// ahead of this one
+			if event.Sequence <= seen {
+				continue
+			}
+			seen = event.Sequence
+			if !event.VisibleTo(userId, userRole) || !c.write(stream, event) {
+				continue
+			}
+			sent = seen
+		case <-heartbeat.C:
+			if seen > sent {
+				// Moves the id past the events of other users, a client
+				// resuming has fewer events to skip
+				stream.Event(strconv.FormatInt(seen, 10), "", nil)
+				sent = seen
+			} else {
+				stream.Comment("heartbeat")
+			}
+		}
+
+		if err := stream.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Controller) write(stream *server.EventStream, event *model.DomainEvent) bool {
+	data, err := json.Marshal(dto.EventFromModel(event))
+	if err != nil {
+		c.logger.Error().Err(err).Str("event_id", event.EventId).Msg("Error encoding event")
+		return false
+	}
+
+	stream.Event(strconv.FormatInt(event.Sequence, 10), string(event.Type), data)
+	return true
+}
@@ -0,0 +1,40 @@
+package dto
+
+import "backend_crm/internal/model"
+
+type Report struct {
+	DryRun  bool        `json:"dryRun"`
+	Rows    int         `json:"rows"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Errors  []*RowError `json:"errors"`
+}
+
+type RowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func ReportFromModel(report *model.ImportReport) *Report {
+	errors := make([]*RowError, 0, len(report.Errors))
+	for _, e := range report.Errors {
+		errors = append(errors, &RowError{
+			Line:    e.Line,
+			Column:  e.Column,
+			Message: e.Message,
+		})
+	}
+
+	return &Report{
+		DryRun:  report.DryRun,
+		Rows:    report.Rows,
+		Created: report.Created,
+		Updated: report.Updated,
+		Skipped: report.Skipped,
+		Failed:  report.Failed,
+		Errors:  errors,
+	}
+}
@@ -0,0 +1,100 @@
+package imports
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/imports/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/usecase/imports"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+type Controller struct {
+	imports imports.Usecase
+	logger  zerolog.Logger
+}
+
+func NewController(imports imports.Usecase, logger zerolog.Logger) *Controller {
+	return &Controller{
+		imports: imports,
+		logger:  logger,
+	}
+}
+
+// Products upserts products from an uploaded CSV file (Director only)
+func (c *Controller) Products(ctx *fasthttp.RequestCtx) {
+	c.runImport(ctx, "products", c.imports.Products)
+}
+
+// Orders imports historical orders from an uploaded CSV file (Director only)
+func (c *Controller) Orders(ctx *fasthttp.RequestCtx) {
+	c.runImport(ctx, "orders", c.imports.Orders)
+}
+
+type importFunc func(ctx context.Context, r io.Reader, dryRun bool) (*model.ImportReport, error)
+
+// runImport reads the file from the multipart field "file" or, for other
+// content types, from the raw request body
+func (c *Controller) runImport(ctx *fasthttp.RequestCtx, kind string, run importFunc) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	var dryRun bool
+	switch string(ctx.QueryArgs().Peek("dryRun")) {
+	case "", "false":
+	case "true":
+		dryRun = true
+	default:
+		ctx.Error("dryRun must be true or false", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var file io.Reader
+	if bytes.HasPrefix(ctx.Request.Header.ContentType(), []byte("multipart/form-data")) {
+		header, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.Error("file is required", fasthttp.StatusBadRequest)
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			c.logger.Error().Err(err).Msg("Error opening uploaded file")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		file = f
+	} else {
+		body := ctx.PostBody()
+		if len(body) == 0 {
+			ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+			return
+		}
+		file = bytes.NewReader(body)
+	}
+
+	report, err := run(ctx, file, dryRun)
+	if err != nil {
+		// Batches stored before the failure are kept
+		c.logger.Error().Err(err).Str("kind", kind).Int("rows", report.Rows).Msg("Error importing")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.ReportFromModel(report)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
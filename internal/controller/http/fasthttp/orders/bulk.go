@@ -0,0 +1,224 @@
+package orders
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/access"
+	"backend_crm/internal/controller/http/fasthttp/orders/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// BulkUpdate applies a status change, assignment or tag to many orders at
+// once. Directors may change every order, other roles only the orders
+// assigned to them, and only Directors assign orders.
+func (c *Contoller) BulkUpdate(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	userRole, ok := ctx.UserValue("user_role").(model.Role)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+	userId := ctx.UserValue("user_id").(string)
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var req *dto.BulkUpdate
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	update := &model.BulkOrderUpdate{
+		Action: model.BulkAction(req.Action),
+		Atomic: req.Atomic == nil || *req.Atomic,
+		UserId: userId,
+	}
+
+	switch {
+	case len(req.OrderIds) > 0 && req.Filter != nil:
+		ctx.Error("Give either orderIds or filter, not both", fasthttp.StatusBadRequest)
+		return
+	case len(req.OrderIds) > 0:
+		update.OrderIds = uniqueIds(req.OrderIds)
+		if len(update.OrderIds) > orders.MaxBulkOrders {
+			ctx.Error("At most "+strconv.Itoa(orders.MaxBulkOrders)+" orders per request", fasthttp.StatusBadRequest)
+			return
+		}
+	case req.Filter != nil:
+		if !validStatus(req.Filter.Status) {
+			ctx.Error("Unknown status in filter", fasthttp.StatusBadRequest)
+			return
+		}
+		update.Filter = model.OrderFilter{
+			Status: model.OrderStatus(req.Filter.Status),
+			Phone:  req.Filter.Phone,
+			Email:  req.Filter.Email,
+		}
+	default:
+		ctx.Error("orderIds or filter is required", fasthttp.StatusBadRequest)
+		return
+	}
+	if userRole != model.Director {
+		update.Filter.UserId = userId
+	}
+
+	switch update.Action {
+	case model.BulkSetStatus:
+		if req.Status == nil || !validStatus(*req.Status) {
+			ctx.Error("Unknown status", fasthttp.StatusBadRequest)
+			return
+		}
+		update.Status = model.OrderStatus(*req.Status)
+	case model.BulkAssign:
+		if userRole != model.Director {
+			ctx.Error("Forbidden", fasthttp.StatusForbidden)
+			return
+		}
+		if req.UserId == nil {
+			ctx.Error("userId is required, empty to unassign", fasthttp.StatusBadRequest)
+			return
+		}
+		update.AssigneeId = *req.UserId
+	case model.BulkAddTag, model.BulkRemoveTag:
+		tag, ok := model.NormalizeTag(req.Tag)
+		if !ok {
+			ctx.Error("Tag must be 1 to 50 characters without commas", fasthttp.StatusBadRequest)
+			return
+		}
+		update.Tag = tag
+	default:
+		ctx.Error("action must be setStatus, assign, addTag or removeTag", fasthttp.StatusBadRequest)
+		return
+	}
+
+	results, err := c.usecase.BulkUpdate(ctx, update)
+	if err != nil {
+		if errors.Is(err, orders.ErrTooManyOrders) {
+			ctx.Error("Filter matches more than "+strconv.Itoa(orders.MaxBulkOrders)+" orders", fasthttp.StatusBadRequest)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating orders")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := bulkResultFromModel(update, results)
+
+	ctx.SetContentType("application/json")
+	if resp.Applied {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	} else {
+		ctx.SetStatusCode(fasthttp.StatusConflict)
+	}
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// History lists the status, assignee, tag and custom field changes of an order
+func (c *Contoller) History(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+
+	changes, err := c.orders.GetHistory(ctx, order.OrderId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting order history")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.HistoryFromModel(changes)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// bulkResultFromModel reports every order. In atomic mode a failure rolls
+// back the whole operation, so the other orders are reported as skipped.
+func bulkResultFromModel(update *model.BulkOrderUpdate, results []model.BulkOrderResult) *dto.BulkResult {
+	resp := &dto.BulkResult{
+		Applied: true,
+		Orders:  make([]*dto.BulkOrder, 0, len(results)),
+	}
+	for _, result := range results {
+		if result.Err != nil && update.Atomic {
+			resp.Applied = false
+		}
+	}
+
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.OrderId] = true
+		order := &dto.BulkOrder{OrderId: result.OrderId}
+		switch {
+		case result.Err != nil:
+			order.Result = "failed"
+			order.Error = bulkErrorMessage(result.Err)
+		case !resp.Applied:
+			order.Result = "skipped"
+		case result.Changed:
+			order.Result = "changed"
+			resp.Changed++
+		default:
+			order.Result = "unchanged"
+		}
+		resp.Orders = append(resp.Orders, order)
+	}
+
+	// Orders after the failure were never reached
+	for _, orderId := range update.OrderIds {
+		if !seen[orderId] {
+			resp.Orders = append(resp.Orders, &dto.BulkOrder{OrderId: orderId, Result: "skipped"})
+		}
+	}
+
+	return resp
+}
+
+func bulkErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, orders.ErrNotFoundOrder):
+		return "order not found"
+	case errors.Is(err, orders.ErrInsufficientStock):
+		return "not enough stock for this order"
+	case errors.Is(err, orders.ErrNotFoundUser):
+		return "user not found"
+	case errors.Is(err, orders.ErrLocked):
+		return "order is being edited by another user"
+	}
+	return "could not be updated"
+}
+
+// uniqueIds drops repeated ids keeping the first occurrence
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+type BulkUpdate struct {
+	OrderIds []string    `json:"orderIds"`
+	Filter   *BulkFilter `json:"filter"`
+	Action   string      `json:"action"`
+	Status   *int        `json:"status"`
+	// UserId is the assignee, empty to unassign
+	UserId *string `json:"userId"`
+	Tag    string  `json:"tag"`
+	// Atomic defaults to true
+	Atomic *bool `json:"atomic"`
+}
+
+type BulkFilter struct {
+	Status int    `json:"status"`
+	Phone  string `json:"phone"`
+	Email  string `json:"email"`
+}
+
+type BulkResult struct {
+	Applied bool         `json:"applied"`
+	Changed int          `json:"changed"`
+	Orders  []*BulkOrder `json:"orders"`
+}
+
+type BulkOrder struct {
+	OrderId string `json:"orderId"`
+	// Result is changed, unchanged, failed or skipped
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+type OrderChange struct {
+	HistoryId string    `json:"historyId"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"oldValue"`
+	NewValue  string    `json:"newValue"`
+	UserId    string    `json:"userId,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	BulkId    string    `json:"bulkId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func HistoryFromModel(changes []*model.OrderChange) []*OrderChange {
+	resp := make([]*OrderChange, 0, len(changes))
+	for _, change := range changes {
+		resp = append(resp, &OrderChange{
+			HistoryId: change.HistoryId,
+			Field:     string(change.Field),
+			OldValue:  change.OldValue,
+			NewValue:  change.NewValue,
+			UserId:    change.UserId,
+			Username:  change.Username,
+			BulkId:    change.BulkId,
+			CreatedAt: change.CreatedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,7 @@
+package dto
+
+type Discount struct {
+	// Amount in minor currency units
+	Amount  int64 `json:"amount"`
+	Percent int   `json:"percent"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+type Tags struct {
+	Tags []string `json:"tags"`
+}
+
+type Fields struct {
+	// Fields are merged into the order, null removes a field
+	Fields map[string]any `json:"fields"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "backend_crm/internal/model"
+
+// Money carries the exact amount in minor units next to a formatted value
+type Money struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Formatted string `json:"formatted"`
+}
+
+func MoneyFromModel(m model.Money) Money {
+	return Money{
+		Amount:    m.Amount,
+		Currency:  m.Currency,
+		Formatted: m.String(),
+	}
+}
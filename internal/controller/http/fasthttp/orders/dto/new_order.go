@@ -1,8 +1,24 @@
 package dto
 
 type NewOrder struct {
+	Name        string `json:"name"`
 	Phone       string `json:"phone"`
 	Email       string `json:"email"`
 	Description string `json:"description"`
-	ProductId   string `json:"productId"`
+	// ProductId creates a single-item order, kept for older clients.
+	// Ignored when Items are given.
+	ProductId string         `json:"productId"`
+	Items     []NewOrderItem `json:"items"`
+	Tags      []string       `json:"tags"`
+	// Fields holds custom field values by key
+	Fields map[string]any `json:"fields"`
+	// EmailOptOut and SMSOptOut stop notifications to the customer about
+	// the order on that channel
+	EmailOptOut bool `json:"emailOptOut"`
+	SMSOptOut   bool `json:"smsOptOut"`
+}
+
+type NewOrderItem struct {
+	ProductId string `json:"productId"`
+	Quantity  int    `json:"quantity"`
 }
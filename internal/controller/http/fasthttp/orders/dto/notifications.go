@@ -0,0 +1,94 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+type Notifications struct {
+	EmailOptOut bool    `json:"emailOptOut"`
+	SMSOptOut   bool    `json:"smsOptOut"`
+	Emails      []Email `json:"emails"`
+	SMS         []SMS   `json:"sms"`
+}
+
+type Email struct {
+	EmailId   string `json:"emailId"`
+	Event     string `json:"event"`
+	Recipient string `json:"recipient"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// NextAttemptAt is only set for pending emails
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type SMS struct {
+	SMSId string `json:"smsId"`
+	Event string `json:"event"`
+	// Recipient is the phone as given on the order until the message is
+	// sent, then the normalized number it went to
+	Recipient         string     `json:"recipient"`
+	Status            string     `json:"status"`
+	Attempts          int        `json:"attempts"`
+	LastError         string     `json:"lastError,omitempty"`
+	ProviderMessageId string     `json:"providerMessageId,omitempty"`
+	NextAttemptAt     *time.Time `json:"nextAttemptAt,omitempty"`
+	SentAt            *time.Time `json:"sentAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// OptOut changes the given channels only, at least one is required
+type OptOut struct {
+	EmailOptOut *bool `json:"emailOptOut"`
+	SMSOptOut   *bool `json:"smsOptOut"`
+}
+
+func NotificationsFromModel(order *model.Order, emails []*model.OutboxEmail, messages []*model.OutboxSMS) *Notifications {
+	result := &Notifications{
+		EmailOptOut: order.EmailOptOut,
+		SMSOptOut:   order.SMSOptOut,
+		Emails:      make([]Email, 0, len(emails)),
+		SMS:         make([]SMS, 0, len(messages)),
+	}
+	for _, e := range emails {
+		email := Email{
+			EmailId:   e.EmailId,
+			Event:     string(e.Event),
+			Recipient: e.Recipient,
+			Status:    string(e.Status),
+			Attempts:  e.Attempts,
+			LastError: e.LastError,
+			CreatedAt: e.CreatedAt,
+		}
+		if e.Status == model.DeliveryPending {
+			email.NextAttemptAt = &e.NextAttemptAt
+		}
+		if !e.SentAt.IsZero() {
+			email.SentAt = &e.SentAt
+		}
+		result.Emails = append(result.Emails, email)
+	}
+	for _, m := range messages {
+		sms := SMS{
+			SMSId:             m.SMSId,
+			Event:             string(m.Event),
+			Recipient:         m.Recipient,
+			Status:            string(m.Status),
+			Attempts:          m.Attempts,
+			LastError:         m.LastError,
+			ProviderMessageId: m.ProviderMessageId,
+			CreatedAt:         m.CreatedAt,
+		}
+		if m.Status == model.DeliveryPending {
+			sms.NextAttemptAt = &m.NextAttemptAt
+		}
+		if !m.SentAt.IsZero() {
+			sms.SentAt = &m.SentAt
+		}
+		result.SMS = append(result.SMS, sms)
+	}
+	return result
+}
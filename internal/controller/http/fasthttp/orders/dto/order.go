@@ -1,15 +1,114 @@
 package dto
 
+import (
+	"backend_crm/internal/model"
+	"fmt"
+)
+
 type Order struct {
-	Phone       string  `json:"phone"`
-	Email       string  `json:"email"`
-	Description string  `json:"description"`
-	Product     Product `json:"product"`
-	Status      int     `json:"status"`
+	OrderId     string         `json:"orderId"`
+	CustomerId  string         `json:"customerId,omitempty"`
+	UserId      string         `json:"userId,omitempty"`
+	Phone       string         `json:"phone"`
+	Email       string         `json:"email"`
+	Description string         `json:"description"`
+	Product     Product        `json:"product"`
+	Items       []Item         `json:"items"`
+	TotalWeight string         `json:"totalWeight"`
+	Subtotal    Money          `json:"subtotal"`
+	Discount    Money          `json:"discount"`
+	TaxRate     string         `json:"taxRate"`
+	Tax         Money          `json:"tax"`
+	Total       Money          `json:"total"`
+	Status      int            `json:"status"`
+	Tags        []string       `json:"tags"`
+	Fields      map[string]any `json:"fields"`
+	EmailOptOut bool           `json:"emailOptOut"`
+	SMSOptOut   bool           `json:"smsOptOut"`
+}
+
+type Item struct {
+	ProductId   string `json:"productId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitWeight  string `json:"unitWeight"`
+	Weight      string `json:"weight"`
+	UnitPrice   Money  `json:"unitPrice"`
+	Total       Money  `json:"total"`
 }
 
 type Product struct {
 	Name        string `json:"name"`
 	Weigth      string `json:"weigth"`
 	Description string `json:"description"`
+	Price       Money  `json:"price"`
+}
+
+func OrderFromModel(order *model.Order) *Order {
+	return &Order{
+		OrderId:     order.OrderId,
+		CustomerId:  order.CustomerId,
+		UserId:      order.UserId,
+		Phone:       order.Phone,
+		Email:       order.Email,
+		Description: order.Description,
+		Product: Product{
+			Name:        order.Product.Name,
+			Weigth:      fmt.Sprintf("%f kg", order.Product.Weigth),
+			Description: order.Product.Description,
+			Price:       MoneyFromModel(order.Product.Price),
+		},
+		Items:       ItemsFromModel(order.Items, order.Currency),
+		TotalWeight: fmt.Sprintf("%f kg", order.TotalWeight()),
+		Subtotal:    MoneyFromModel(order.Subtotal()),
+		Discount:    MoneyFromModel(order.Discount()),
+		TaxRate:     fmt.Sprintf("%.2f%%", float64(order.TaxRate)/100),
+		Tax:         MoneyFromModel(order.Tax()),
+		Total:       MoneyFromModel(order.Total()),
+		Status:      int(order.Status),
+		Tags:        tags(order.Tags),
+		Fields:      fields(order.Fields),
+		EmailOptOut: order.EmailOptOut,
+		SMSOptOut:   order.SMSOptOut,
+	}
+}
+
+func tags(t []string) []string {
+	if t == nil {
+		return []string{}
+	}
+	return t
+}
+
+func fields(f model.CustomFieldValues) map[string]any {
+	if f == nil {
+		return map[string]any{}
+	}
+	return f
+}
+
+func ItemsFromModel(items []model.OrderItem, currency string) []Item {
+	resp := make([]Item, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, Item{
+			ProductId:   item.Product.ProductId,
+			Name:        item.Product.Name,
+			Description: item.Product.Description,
+			Quantity:    item.Quantity,
+			UnitWeight:  fmt.Sprintf("%f kg", item.UnitWeight),
+			Weight:      fmt.Sprintf("%f kg", item.Weight()),
+			UnitPrice:   MoneyFromModel(model.Money{Amount: item.UnitPrice, Currency: currency}),
+			Total:       MoneyFromModel(model.Money{Amount: item.Total(), Currency: currency}),
+		})
+	}
+	return resp
+}
+
+func OrdersFromModel(orders []*model.Order) []*Order {
+	resp := make([]*Order, 0, len(orders))
+	for _, order := range orders {
+		resp = append(resp, OrderFromModel(order))
+	}
+	return resp
 }
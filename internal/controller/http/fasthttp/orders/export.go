@@ -0,0 +1,222 @@
+package orders
+
+import (
+	"backend_crm/internal/export"
+	"backend_crm/internal/model"
+	"bufio"
+	"context"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// exportTimeout bounds the whole export, exportIdleTimeout how long the
+	// client may stall reading before the connection is dropped
+	exportTimeout     = 10 * time.Minute
+	exportIdleTimeout = 30 * time.Second
+	// exportFlushRows is how many rows are buffered before they are sent
+	exportFlushRows = 100
+)
+
+type exportColumn struct {
+	header string
+	value  func(order *model.Order) export.Cell
+}
+
+var statusNames = map[model.OrderStatus]string{
+	model.Consideration: "consideration",
+	model.Refected:      "rejected",
+	model.AtWork:        "at work",
+	model.Complete:      "complete",
+}
+
+var exportColumns = map[string]exportColumn{
+	"orderId": {"Order", func(o *model.Order) export.Cell {
+		return export.Text(o.OrderId)
+	}},
+	"createdAt": {"Created", func(o *model.Order) export.Cell {
+		return export.Text(o.CreatedAt.UTC().Format("2006-01-02 15:04:05"))
+	}},
+	"status": {"Status", func(o *model.Order) export.Cell {
+		return export.Text(statusNames[o.Status])
+	}},
+	"customerId": {"Customer", func(o *model.Order) export.Cell {
+		return export.Text(o.CustomerId)
+	}},
+	"phone": {"Phone", func(o *model.Order) export.Cell {
+		return export.Text(o.Phone)
+	}},
+	"email": {"Email", func(o *model.Order) export.Cell {
+		return export.Text(o.Email)
+	}},
+	"description": {"Description", func(o *model.Order) export.Cell {
+		return export.Text(o.Description)
+	}},
+	"products": {"Products", func(o *model.Order) export.Cell {
+		lines := make([]string, 0, len(o.Items))
+		for _, item := range o.Items {
+			lines = append(lines, item.Product.Name+" x "+strconv.Itoa(item.Quantity))
+		}
+		return export.Text(strings.Join(lines, "; "))
+	}},
+	"quantity": {"Quantity", func(o *model.Order) export.Cell {
+		var total int64
+		for _, item := range o.Items {
+			total += int64(item.Quantity)
+		}
+		return export.Int(total)
+	}},
+	"totalWeight": {"Weight, kg", func(o *model.Order) export.Cell {
+		return export.Float32(o.TotalWeight())
+	}},
+	"currency": {"Currency", func(o *model.Order) export.Cell {
+		return export.Text(o.Currency)
+	}},
+	"subtotal": {"Subtotal", func(o *model.Order) export.Cell {
+		return export.Decimal(o.Subtotal().Decimal())
+	}},
+	"discount": {"Discount", func(o *model.Order) export.Cell {
+		return export.Decimal(o.Discount().Decimal())
+	}},
+	"taxRate": {"Tax rate, %", func(o *model.Order) export.Cell {
+		return export.Decimal(strconv.FormatFloat(float64(o.TaxRate)/100, 'f', -1, 64))
+	}},
+	"tax": {"Tax", func(o *model.Order) export.Cell {
+		return export.Decimal(o.Tax().Decimal())
+	}},
+	"total": {"Total", func(o *model.Order) export.Cell {
+		return export.Decimal(o.Total().Decimal())
+	}},
+	"tags": {"Tags", func(o *model.Order) export.Cell {
+		return export.Text(strings.Join(o.Tags, ", "))
+	}},
+}
+
+var defaultExportColumns = []string{
+	"orderId", "createdAt", "status", "phone", "email", "description", "products",
+	"quantity", "totalWeight", "currency", "subtotal", "discount", "tax", "total",
+}
+
+// Export downloads the order listing as a spreadsheet. It takes the same
+// filters as Orders and additionally:
+//   - format: csv (default) or xlsx
+//   - columns: comma separated column names, see exportColumns
+//   - locale: language for CSV number formatting, defaults to Accept-Language
+//
+// Rows are streamed from the database straight to the client.
+func (c *Contoller) Export(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	status, ok := parseStatus(ctx)
+	if !ok {
+		ctx.Error("Unknown status", fasthttp.StatusBadRequest)
+		return
+	}
+
+	filter, ok := c.filterFromQuery(ctx, status)
+	if !ok {
+		return
+	}
+
+	queryArgs := ctx.QueryArgs()
+
+	format := string(queryArgs.Peek("format"))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		ctx.Error("format must be csv or xlsx", fasthttp.StatusBadRequest)
+		return
+	}
+
+	names := defaultExportColumns
+	if raw := string(queryArgs.Peek("columns")); raw != "" {
+		names = strings.Split(raw, ",")
+	}
+	columns := make([]exportColumn, 0, len(names))
+	header := make([]export.Cell, 0, len(names))
+	for _, name := range names {
+		column, ok := exportColumns[strings.TrimSpace(name)]
+		if !ok {
+			ctx.Error("Unknown column "+strconv.Quote(name), fasthttp.StatusBadRequest)
+			return
+		}
+		columns = append(columns, column)
+		header = append(header, export.Text(column.header))
+	}
+
+	locale := string(queryArgs.Peek("locale"))
+	if locale == "" {
+		locale = string(ctx.Request.Header.Peek(fasthttp.HeaderAcceptLanguage))
+	}
+
+	filename := "orders-" + strings.ReplaceAll(statusNames[status], " ", "-") + "-" + time.Now().UTC().Format("2006-01-02") + "." + format
+	if format == "xlsx" {
+		ctx.SetContentType("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	} else {
+		ctx.SetContentType("text/csv; charset=utf-8")
+	}
+	ctx.Response.Header.Set(fasthttp.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	conn := ctx.Conn()
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	// The writer runs after the handler returned, ctx must not be used in it
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		streamCtx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		var out export.Writer
+		if format == "xlsx" {
+			var err error
+			if out, err = export.NewXLSX(w, "Orders"); err != nil {
+				c.logger.Error().Err(err).Msg("Error starting export")
+				return
+			}
+		} else {
+			out = export.NewCSV(w, export.ParseLocale(locale))
+		}
+
+		if err := out.WriteRow(header); err != nil {
+			c.logger.Error().Err(err).Msg("Error writing export")
+			return
+		}
+
+		rows := 0
+		row := make([]export.Cell, len(columns))
+		err := c.orders.Stream(streamCtx, filter, func(order *model.Order) error {
+			for i, column := range columns {
+				row[i] = column.value(order)
+			}
+			if err := out.WriteRow(row); err != nil {
+				return err
+			}
+
+			rows++
+			if rows%exportFlushRows == 0 {
+				// A client that keeps reading may take as long as it needs
+				conn.SetWriteDeadline(time.Now().Add(exportIdleTimeout))
+				return w.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			// The status line is already sent, the client gets a truncated file
+			c.logger.Error().Err(err).Int("rows", rows).Msg("Error streaming export")
+			return
+		}
+
+		if err := out.Close(); err != nil {
+			c.logger.Error().Err(err).Msg("Error finishing export")
+			return
+		}
+		w.Flush()
+	})
+}
@@ -0,0 +1,254 @@
+package orders
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/access"
+	"backend_crm/internal/controller/http/fasthttp/orders/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxOrderTags limits how many tags one order may carry
+const maxOrderTags = 20
+
+// fieldArgPrefix marks query arguments filtering by custom field, e.g.
+// field.region=north
+const fieldArgPrefix = "field."
+
+// UpdateTags replaces the tags of an order
+func (c *Contoller) UpdateTags(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var req *dto.Tags
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	tags, ok := normalizeTags(ctx, req.Tags)
+	if !ok {
+		return
+	}
+
+	if c.lockedByOther(ctx, order.OrderId) {
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	if err := c.orders.UpdateTags(ctx, order.OrderId, tags, userId); err != nil {
+		if errors.Is(err, orders.ErrNotFoundOrder) {
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating order tags")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// UpdateFields sets custom field values of an order. Fields not mentioned
+// keep their value, null removes a field.
+func (c *Contoller) UpdateFields(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var req *dto.Fields
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+	if len(req.Fields) == 0 {
+		ctx.Error("fields must not be empty", fasthttp.StatusBadRequest)
+		return
+	}
+
+	values, ok := c.validateFields(ctx, req.Fields, true)
+	if !ok {
+		return
+	}
+
+	if c.lockedByOther(ctx, order.OrderId) {
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	if err := c.orders.UpdateFields(ctx, order.OrderId, values, userId); err != nil {
+		if errors.Is(err, orders.ErrNotFoundOrder) {
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating order fields")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// filterFromQuery reads the listing filters: phone, email, any number of
+// tag arguments, which must all be present, and field.<key> arguments.
+// Other roles than Director only see their own orders.
+func (c *Contoller) filterFromQuery(ctx *fasthttp.RequestCtx, status model.OrderStatus) (model.OrderFilter, bool) {
+	queryArgs := ctx.QueryArgs()
+	filter := model.OrderFilter{
+		Status: status,
+		Phone:  string(queryArgs.Peek("phone")),
+		Email:  string(queryArgs.Peek("email")),
+	}
+
+	userRole, ok := ctx.UserValue("user_role").(model.Role)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return filter, false
+	}
+	if userRole != model.Director {
+		filter.UserId = ctx.UserValue("user_id").(string)
+	}
+
+	for _, raw := range queryArgs.PeekMulti("tag") {
+		tag, ok := model.NormalizeTag(string(raw))
+		if !ok {
+			ctx.Error("Tag must be 1 to 50 characters without commas", fasthttp.StatusBadRequest)
+			return filter, false
+		}
+		if !slices.Contains(filter.Tags, tag) {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	raw := make(map[string]string)
+	queryArgs.VisitAll(func(key, value []byte) {
+		if k, ok := strings.CutPrefix(string(key), fieldArgPrefix); ok {
+			raw[k] = string(value)
+		}
+	})
+	if len(raw) == 0 {
+		return filter, true
+	}
+
+	defs, ok := c.customFields(ctx)
+	if !ok {
+		return filter, false
+	}
+	filter.Fields = make(model.CustomFieldValues, len(raw))
+	for key, value := range raw {
+		def, found := defs[key]
+		if !found {
+			ctx.Error("Unknown custom field "+strconv.Quote(key), fasthttp.StatusBadRequest)
+			return filter, false
+		}
+		parsed, err := def.Parse(value)
+		if err != nil {
+			ctx.Error("Field "+key+" "+err.Error(), fasthttp.StatusBadRequest)
+			return filter, false
+		}
+		filter.Fields[key] = parsed
+	}
+
+	return filter, true
+}
+
+// validateFields checks the values against the field definitions. With
+// allowNull a null value is kept to remove the field.
+func (c *Contoller) validateFields(ctx *fasthttp.RequestCtx, values map[string]any, allowNull bool) (model.CustomFieldValues, bool) {
+	defs, ok := c.customFields(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	result := make(model.CustomFieldValues, len(values))
+	for key, value := range values {
+		def, found := defs[key]
+		if !found {
+			ctx.Error("Unknown custom field "+strconv.Quote(key), fasthttp.StatusBadRequest)
+			return nil, false
+		}
+		if value == nil {
+			if !allowNull {
+				continue
+			}
+			result[key] = nil
+			continue
+		}
+		valid, err := def.Validate(value)
+		if err != nil {
+			ctx.Error("Field "+key+" "+err.Error(), fasthttp.StatusBadRequest)
+			return nil, false
+		}
+		result[key] = valid
+	}
+
+	return result, true
+}
+
+// customFields returns the field definitions by key
+func (c *Contoller) customFields(ctx *fasthttp.RequestCtx) (map[string]*model.CustomField, bool) {
+	found, err := c.fields.GetAll(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting custom fields")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return nil, false
+	}
+
+	defs := make(map[string]*model.CustomField, len(found))
+	for _, field := range found {
+		defs[field.Key] = field
+	}
+	return defs, true
+}
+
+// normalizeTags trims the tags and drops repeated ones
+func normalizeTags(ctx *fasthttp.RequestCtx, raw []string) ([]string, bool) {
+	tags := make([]string, 0, len(raw))
+	for _, t := range raw {
+		tag, ok := model.NormalizeTag(t)
+		if !ok {
+			ctx.Error("Tag must be 1 to 50 characters without commas", fasthttp.StatusBadRequest)
+			return nil, false
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxOrderTags {
+		ctx.Error("At most "+strconv.Itoa(maxOrderTags)+" tags per order", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	return tags, true
+}
@@ -1,25 +1,55 @@
 package orders
 
 import (
+	"backend_crm/internal/controller/http/fasthttp/access"
 	"backend_crm/internal/controller/http/fasthttp/orders/dto"
 	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customfields"
+	"backend_crm/internal/repository/emails"
+	"backend_crm/internal/repository/locks"
 	"backend_crm/internal/repository/orders"
+	"backend_crm/internal/repository/sms"
+	ordersUsecase "backend_crm/internal/usecase/orders"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"strconv"
 
 	"github.com/rs/zerolog"
 	"github.com/valyala/fasthttp"
 )
 
 type Contoller struct {
-	orders orders.Repository
-	logger zerolog.Logger
+	orders   orders.Repository
+	usecase  ordersUsecase.Usecase
+	fields   customfields.Repository
+	emails   emails.Repository
+	messages sms.Repository
+	locks    locks.Repository
+	taxRate  int
+	logger   zerolog.Logger
 }
 
-func NewController(orders orders.Repository, logger zerolog.Logger) *Contoller {
+// NewController creates the orders controller. Changes with domain events
+// go through usecase, taxRate in basis points is stored on every new order.
+func NewController(
+	orders orders.Repository,
+	usecase ordersUsecase.Usecase,
+	fields customfields.Repository,
+	emails emails.Repository,
+	messages sms.Repository,
+	locks locks.Repository,
+	taxRate int,
+	logger zerolog.Logger,
+) *Contoller {
 	return &Contoller{
-		orders: orders,
-		logger: logger,
+		orders:   orders,
+		usecase:  usecase,
+		fields:   fields,
+		emails:   emails,
+		messages: messages,
+		locks:    locks,
+		taxRate:  taxRate,
+		logger:   logger,
 	}
 }
 
@@ -29,9 +59,8 @@ func (c *Contoller) UpdateOrder(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	orderId, ok := ctx.UserValue("orderId").(string)
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
 	if !ok {
-		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
 		return
 	}
 
@@ -47,7 +76,26 @@ func (c *Contoller) UpdateOrder(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if err := c.orders.UpdateOrderStatus(ctx, orderId, model.OrderStatus(st.Status)); err != nil {
+	if !validStatus(st.Status) {
+		ctx.Error("Unknown status", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if c.lockedByOther(ctx, order.OrderId) {
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	if err := c.usecase.UpdateStatus(ctx, order.OrderId, model.OrderStatus(st.Status), userId); err != nil {
+		if errors.Is(err, orders.ErrNotFoundOrder) {
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+			return
+		}
+		if errors.Is(err, orders.ErrInsufficientStock) {
+			ctx.Error("not enough stock for this order", fasthttp.StatusConflict)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating order status")
 		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
 		return
 	}
@@ -55,149 +103,143 @@ func (c *Contoller) UpdateOrder(ctx *fasthttp.RequestCtx) {
 	ctx.SetStatusCode(fasthttp.StatusOK)
 }
 
+// Orders lists the orders with the status, filtered by phone, email, tags
+// and custom fields, see filterFromQuery
 func (c *Contoller) Orders(ctx *fasthttp.RequestCtx) {
 	if !ctx.IsGet() {
 		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
 		return
 	}
 
-	status, ok := ctx.UserValue("status").(model.OrderStatus)
+	status, ok := parseStatus(ctx)
 	if !ok {
-		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		ctx.Error("Unknown status", fasthttp.StatusBadRequest)
 		return
 	}
 
-	queryArgs := ctx.QueryArgs()
-	phoneFilter := false
-	var phone string
-	p := queryArgs.Peek("phone")
-	if len(p) != 0 {
-		phone = string(p)
-		phoneFilter = true
+	filter, ok := c.filterFromQuery(ctx, status)
+	if !ok {
+		return
 	}
 
-	emailFilter := false
-	var email string
-	e := queryArgs.Peek("email")
-	if len(p) != 0 {
-		email = string(e)
-		emailFilter = true
+	orders, err := c.orders.GetByFilter(ctx, filter)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting orders")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
 	}
 
-	userRole := ctx.UserValue("user_role").(model.Role)
-	if !ok {
-		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
-		return
+	respOrders := dto.OrdersFromModel(orders)
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(respOrders); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
 	}
+}
 
-	if userRole == model.Director {
-		var orders []*model.Order
-		var err error
-		if phoneFilter && emailFilter {
-			orders, err = c.orders.GetByStatusAndPhoneAndEmail(ctx, status, phone, email)
-			if err != nil {
-				ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
-				return
-			}
-		} else if phoneFilter {
-			orders, err = c.orders.GetByStatusAndPhone(ctx, status, phone)
-			if err != nil {
-				ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
-				return
-			}
-		} else if emailFilter {
-			orders, err = c.orders.GetByStatusAndEmail(ctx, status, email)
-			if err != nil {
-				ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
-				return
-			}
-		} else {
-			orders, err = c.orders.GetByStatus(ctx, status)
-			if err != nil {
-				ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
-				return
-			}
-		}
+func (c *Contoller) NewOrder(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
 
-		respOrders := make([]*dto.Order, 0, len(orders))
-		for _, order := range orders {
-			respOrders = append(respOrders, &dto.Order{
-				Phone:       order.Phone,
-				Email:       order.Email,
-				Description: order.Description,
-				Product: dto.Product{
-					Name:        order.Product.Name,
-					Weigth:      fmt.Sprintf("%f kg", order.Product.Weigth),
-					Description: order.Product.Description,
-				},
-				Status: int(order.Status),
-			})
-		}
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
 
-		ctx.SetContentType("application/json")
-		ctx.SetStatusCode(fasthttp.StatusOK)
-		if err := json.NewEncoder(ctx).Encode(respOrders); err != nil {
-			ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
-		}
+	var newOrder *dto.NewOrder
+	if err := json.Unmarshal(body, &newOrder); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
 		return
 	}
 
-	userId := ctx.UserValue("user_id").(string)
+	items := make([]model.NewOrderItem, 0, len(newOrder.Items))
+	for _, item := range newOrder.Items {
+		items = append(items, model.NewOrderItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+	}
+	if len(items) == 0 && newOrder.ProductId != "" {
+		items = append(items, model.NewOrderItem{
+			ProductId: newOrder.ProductId,
+			Quantity:  1,
+		})
+	}
 
-	var orders []*model.Order
-	var err error
+	if len(items) == 0 {
+		ctx.Error("Order must contain at least one item", fasthttp.StatusBadRequest)
+		return
+	}
+	for _, item := range items {
+		if item.ProductId == "" || item.Quantity <= 0 {
+			ctx.Error("Every item needs a product and a positive quantity", fasthttp.StatusBadRequest)
+			return
+		}
+	}
 
-	if phoneFilter && emailFilter {
-		orders, err = c.orders.GetByUserIdAndStatusAndPhoneAndEmail(ctx, userId, status, phone, email)
-		if err != nil {
-			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+	tags, ok := normalizeTags(ctx, newOrder.Tags)
+	if !ok {
+		return
+	}
+	var fields model.CustomFieldValues
+	if len(newOrder.Fields) > 0 {
+		if fields, ok = c.validateFields(ctx, newOrder.Fields, false); !ok {
 			return
 		}
-	} else if phoneFilter {
-		orders, err = c.orders.GetByUserIdAndStatusAndPhone(ctx, userId, status, phone)
-		if err != nil {
-			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+	}
+
+	if err := c.usecase.Create(ctx, &model.NewOrder{
+		Name:        newOrder.Name,
+		Phone:       newOrder.Phone,
+		Email:       newOrder.Email,
+		Description: newOrder.Description,
+		Items:       items,
+		Status:      model.Consideration,
+		Tags:        tags,
+		Fields:      fields,
+		EmailOptOut: newOrder.EmailOptOut,
+		SMSOptOut:   newOrder.SMSOptOut,
+		TaxRate:     c.taxRate,
+	}); err != nil {
+		if errors.Is(err, orders.ErrNotFoundProduct) {
+			ctx.Error("product not found", fasthttp.StatusBadRequest)
 			return
 		}
-	} else if emailFilter {
-		orders, err = c.orders.GetByUserIdAndStatusAndEmail(ctx, userId, status, email)
-		if err != nil {
-			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		if errors.Is(err, orders.ErrInactiveProduct) {
+			ctx.Error("product is discontinued", fasthttp.StatusBadRequest)
 			return
 		}
-	} else {
-		orders, err = c.orders.GetByUserIdAndStatus(ctx, userId, status)
-		if err != nil {
-			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		if errors.Is(err, orders.ErrCurrencyMismatch) {
+			ctx.Error("all products of an order must have the same currency", fasthttp.StatusBadRequest)
 			return
 		}
+		c.logger.Error().Err(err).Msg("Error saving order")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
 	}
 
-	respOrders := make([]*dto.Order, 0, len(orders))
-	for _, order := range orders {
-		respOrders = append(respOrders, &dto.Order{
-			Phone:       order.Phone,
-			Email:       order.Email,
-			Description: order.Description,
-			Product: dto.Product{
-				Name:        order.Product.Name,
-				Weigth:      fmt.Sprintf("%f kg", order.Product.Weigth),
-				Description: order.Product.Description,
-			},
-			Status: int(order.Status),
-		})
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+}
+
+// UpdateDiscount sets the fixed and percentage discount of an order (Director only)
+func (c *Contoller) UpdateDiscount(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
 	}
 
-	ctx.SetContentType("application/json")
-	ctx.SetStatusCode(fasthttp.StatusOK)
-	if err := json.NewEncoder(ctx).Encode(respOrders); err != nil {
-		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	orderId, ok := ctx.UserValue("orderId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
 	}
-}
 
-func (c *Contoller) NewOrder(ctx *fasthttp.RequestCtx) {
-	if ctx.IsPost() {
-		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
 		return
 	}
 
@@ -207,22 +249,52 @@ func (c *Contoller) NewOrder(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	var newOrder *dto.NewOrder
-	if err := json.Unmarshal(body, &newOrder); err != nil {
+	var discount *dto.Discount
+	if err := json.Unmarshal(body, &discount); err != nil {
 		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
 		return
 	}
 
-	if err := c.orders.Save(ctx, &model.NewOrder{
-		Phone:       newOrder.Phone,
-		Email:       newOrder.Email,
-		Description: newOrder.Description,
-		ProductId:   newOrder.ProductId,
-		Status:      model.Consideration,
+	if discount.Amount < 0 || discount.Percent < 0 || discount.Percent > 100 {
+		ctx.Error("Discount amount must not be negative and percent must be between 0 and 100", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if c.lockedByOther(ctx, orderId) {
+		return
+	}
+
+	if err := c.orders.UpdateDiscount(ctx, orderId, model.OrderDiscount{
+		Amount:  discount.Amount,
+		Percent: discount.Percent,
 	}); err != nil {
+		if errors.Is(err, orders.ErrNotFoundOrder) {
+			ctx.Error("order not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating discount")
 		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
 		return
 	}
 
-	ctx.SetStatusCode(fasthttp.StatusCreated)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// parseStatus reads the status path parameter of the order listings
+func parseStatus(ctx *fasthttp.RequestCtx) (model.OrderStatus, bool) {
+	raw, ok := ctx.UserValue("status").(string)
+	if !ok {
+		return 0, false
+	}
+
+	status, err := strconv.Atoi(raw)
+	if err != nil || !validStatus(status) {
+		return 0, false
+	}
+
+	return model.OrderStatus(status), true
+}
+
+func validStatus(status int) bool {
+	return status >= model.Consideration && status <= model.Complete
 }
@@ -0,0 +1,29 @@
+package orders
+
+import (
+	"backend_crm/internal/repository/locks"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+// lockedByOther answers 423 Locked when another user holds the edit lock of
+// the order. The holder may change the order from any session.
+func (c *Contoller) lockedByOther(ctx *fasthttp.RequestCtx, orderId string) bool {
+	lock, err := c.locks.Get(ctx, orderId)
+	if err != nil {
+		if errors.Is(err, locks.ErrNotFoundLock) {
+			return false
+		}
+		c.logger.Error().Err(err).Msg("Error getting order lock")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return true
+	}
+
+	if userId, _ := ctx.UserValue("user_id").(string); lock.UserId == userId {
+		return false
+	}
+
+	ctx.Error("order is being edited by "+lock.Username, fasthttp.StatusLocked)
+	return true
+}
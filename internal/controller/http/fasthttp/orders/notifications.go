@@ -0,0 +1,101 @@
+package orders
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/access"
+	"backend_crm/internal/controller/http/fasthttp/orders/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"encoding/json"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Notifications shows whether the customer gets emails and SMS about the
+// order and the messages queued so far with their delivery status
+func (c *Contoller) Notifications(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+
+	emails, err := c.emails.GetByOrderId(ctx, order.OrderId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting order emails")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	messages, err := c.messages.GetByOrderId(ctx, order.OrderId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting order sms")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.NotificationsFromModel(order, emails, messages)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateNotifications opts the customer out of emails or SMS about the
+// order or back in
+func (c *Contoller) UpdateNotifications(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	order, ok := access.VisibleOrder(ctx, c.orders, c.logger)
+	if !ok {
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var req *dto.OptOut
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+	if req == nil || (req.EmailOptOut == nil && req.SMSOptOut == nil) {
+		ctx.Error("emailOptOut or smsOptOut is required", fasthttp.StatusBadRequest)
+		return
+	}
+
+	changes := []struct {
+		channel model.NotificationChannel
+		optOut  *bool
+	}{
+		{model.ChannelEmail, req.EmailOptOut},
+		{model.ChannelSMS, req.SMSOptOut},
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	for _, change := range changes {
+		if change.optOut == nil {
+			continue
+		}
+		if err := c.orders.UpdateOptOut(ctx, order.OrderId, change.channel, *change.optOut, userId); err != nil {
+			if errors.Is(err, orders.ErrNotFoundOrder) {
+				ctx.Error("order not found", fasthttp.StatusNotFound)
+				return
+			}
+			c.logger.Error().Err(err).Msg("Error updating order notifications")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+			return
+		}
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"time"
+)
+
+type ProductImage struct {
+	ImageId      string    `json:"imageId"`
+	Position     int       `json:"position"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func ProductImageFromModel(image *model.ProductImage) *ProductImage {
+	url := "/api/v1/products/product/" + image.ProductId + "/images/" + image.ImageId
+	return &ProductImage{
+		ImageId:      image.ImageId,
+		Position:     image.Position,
+		ContentType:  image.Original.ContentType,
+		Size:         image.Original.Size,
+		Width:        image.Width,
+		Height:       image.Height,
+		URL:          url,
+		ThumbnailURL: url + "/thumbnail",
+		CreatedAt:    image.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	ordersDto "backend_crm/internal/controller/http/fasthttp/orders/dto"
+	"backend_crm/internal/model"
+)
+
+type Product struct {
+	ProductId   string          `json:"productId"`
+	SKU         string          `json:"sku,omitempty"`
+	Name        string          `json:"name"`
+	Weight      float32         `json:"weight"`
+	Description string          `json:"description"`
+	Price       ordersDto.Money `json:"price"`
+	Stock       int             `json:"stock"`
+	Reserved    int             `json:"reserved"`
+	Available   int             `json:"available"`
+	CategoryId  string          `json:"categoryId,omitempty"`
+	Attributes  map[string]any  `json:"attributes"`
+	Active      bool            `json:"active"`
+}
+
+func ProductFromModel(product *model.Product) *Product {
+	return &Product{
+		ProductId:   product.ProductId,
+		SKU:         product.SKU,
+		Name:        product.Name,
+		Weight:      product.Weigth,
+		Description: product.Description,
+		Price:       ordersDto.MoneyFromModel(product.Price),
+		Stock:       product.Stock,
+		Reserved:    product.Reserved,
+		Available:   product.Available(),
+		CategoryId:  product.CategoryId,
+		Attributes:  attributes(product.Attributes),
+		Active:      product.Active,
+	}
+}
+
+func attributes(a model.Attributes) map[string]any {
+	if a == nil {
+		return map[string]any{}
+	}
+	return a
+}
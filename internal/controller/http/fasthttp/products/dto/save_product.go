@@ -0,0 +1,16 @@
+package dto
+
+type SaveProduct struct {
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	Weight      float32 `json:"weight"`
+	Description string  `json:"description"`
+	// Price in minor currency units
+	Price      int64          `json:"price"`
+	Currency   string         `json:"currency"`
+	CategoryId string         `json:"categoryId"`
+	Attributes map[string]any `json:"attributes"`
+	// Active defaults to true for new products, an update keeps the
+	// current value
+	Active *bool `json:"active"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+type StockAdjustment struct {
+	Quantity int    `json:"quantity"`
+	Note     string `json:"note"`
+}
+
+type StockMovement struct {
+	MovementId string    `json:"movementId"`
+	OrderId    string    `json:"orderId,omitempty"`
+	UserId     string    `json:"userId,omitempty"`
+	Kind       string    `json:"kind"`
+	Quantity   int       `json:"quantity"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,314 @@
+package products
+
+import (
+	"backend_crm/internal/blob"
+	"backend_crm/internal/controller/http/fasthttp/products/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/products"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+type Controller struct {
+	products        products.Repository
+	blobs           blob.Store
+	defaultCurrency string
+	maxImageSize    int
+	thumbnailSize   int
+	logger          zerolog.Logger
+}
+
+func NewController(
+	products products.Repository,
+	blobs blob.Store,
+	defaultCurrency string,
+	maxImageSize int,
+	thumbnailSize int,
+	logger zerolog.Logger,
+) *Controller {
+	return &Controller{
+		products:        products,
+		blobs:           blobs,
+		defaultCurrency: defaultCurrency,
+		maxImageSize:    maxImageSize,
+		thumbnailSize:   thumbnailSize,
+		logger:          logger,
+	}
+}
+
+func (c *Controller) Products(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, ok := parseFilter(ctx)
+	if !ok {
+		ctx.Error("active must be true, false or all", fasthttp.StatusBadRequest)
+		return
+	}
+
+	found, err := c.products.GetAll(ctx, filter)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting products")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Product, 0, len(found))
+	for _, product := range found {
+		resp = append(resp, dto.ProductFromModel(product))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+func (c *Controller) Product(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	product, err := c.products.GetById(ctx, productId)
+	if err != nil {
+		if errors.Is(err, products.ErrNotFoundProduct) {
+			ctx.Error("product not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error getting product")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.ProductFromModel(product)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// NewProduct adds a product to the catalog (Director only)
+func (c *Controller) NewProduct(ctx *fasthttp.RequestCtx) {
+	product, active, ok := c.parseProduct(ctx)
+	if !ok {
+		return
+	}
+	product.Active = active == nil || *active
+
+	if err := c.products.Save(ctx, product); err != nil {
+		if errors.Is(err, products.ErrNotFoundCategory) {
+			ctx.Error("category not found", fasthttp.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, products.ErrDuplicateSKU) {
+			ctx.Error("sku is already used by another product", fasthttp.StatusConflict)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error saving product")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(dto.ProductFromModel(product)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateProduct changes a product including its price (Director only).
+// Existing orders keep the price they were placed with.
+func (c *Controller) UpdateProduct(ctx *fasthttp.RequestCtx) {
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	product, active, ok := c.parseProduct(ctx)
+	if !ok {
+		return
+	}
+	product.ProductId = productId
+
+	// A discontinued product stays discontinued unless told otherwise
+	if active != nil {
+		product.Active = *active
+	} else {
+		current, err := c.products.GetById(ctx, productId)
+		if err != nil {
+			if errors.Is(err, products.ErrNotFoundProduct) {
+				ctx.Error("product not found", fasthttp.StatusNotFound)
+				return
+			}
+			c.logger.Error().Err(err).Msg("Error getting product")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+			return
+		}
+		product.Active = current.Active
+	}
+
+	if err := c.products.Update(ctx, product); err != nil {
+		if errors.Is(err, products.ErrNotFoundProduct) {
+			ctx.Error("product not found", fasthttp.StatusNotFound)
+			return
+		}
+		if errors.Is(err, products.ErrNotFoundCategory) {
+			ctx.Error("category not found", fasthttp.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, products.ErrDuplicateSKU) {
+			ctx.Error("sku is already used by another product", fasthttp.StatusConflict)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating product")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// DeleteProduct discontinues a product (Director only). The product is only
+// deactivated so that existing orders keep showing it.
+func (c *Controller) DeleteProduct(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsDelete() {
+		ctx.Error("Only DELETE method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	if err := c.products.SetActive(ctx, productId, false); err != nil {
+		if errors.Is(err, products.ErrNotFoundProduct) {
+			ctx.Error("product not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error deactivating product")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// parseFilter reads the categoryId and active query arguments. Only active
+// products are listed unless active=false or active=all is given.
+func parseFilter(ctx *fasthttp.RequestCtx) (model.ProductFilter, bool) {
+	queryArgs := ctx.QueryArgs()
+	filter := model.ProductFilter{
+		CategoryId: string(queryArgs.Peek("categoryId")),
+	}
+
+	active := true
+	switch string(queryArgs.Peek("active")) {
+	case "", "true":
+		filter.Active = &active
+	case "false":
+		active = false
+		filter.Active = &active
+	case "all":
+	default:
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// parseProduct checks method and role and validates the request body. It
+// writes the error response itself and reports whether to continue. The
+// active flag is returned as given, nil when omitted; the caller decides
+// the default.
+func (c *Controller) parseProduct(ctx *fasthttp.RequestCtx) (*model.Product, *bool, bool) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return nil, nil, false
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return nil, nil, false
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	var req *dto.SaveProduct
+	if err := json.Unmarshal(body, &req); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if currency == "" {
+		currency = c.defaultCurrency
+	}
+
+	sku := strings.TrimSpace(req.SKU)
+
+	switch {
+	case len(sku) > 64:
+		ctx.Error("SKU must be at most 64 characters", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	case strings.TrimSpace(req.Name) == "":
+		ctx.Error("Product name must not be empty", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	case req.Weight < 0:
+		ctx.Error("Product weight must not be negative", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	case req.Price < 0:
+		ctx.Error("Product price must not be negative", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	case len(currency) != 3:
+		ctx.Error("Currency must be an ISO 4217 code", fasthttp.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	for name, value := range req.Attributes {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			ctx.Error("Attribute "+name+" must be a string, number or boolean", fasthttp.StatusBadRequest)
+			return nil, nil, false
+		}
+	}
+
+	return &model.Product{
+		SKU:         sku,
+		Name:        strings.TrimSpace(req.Name),
+		Weigth:      req.Weight,
+		Description: req.Description,
+		Price: model.Money{
+			Amount:   req.Price,
+			Currency: currency,
+		},
+		CategoryId: req.CategoryId,
+		Attributes: req.Attributes,
+	}, req.Active, true
+}
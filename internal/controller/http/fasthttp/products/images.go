@@ -0,0 +1,278 @@
+package products
+
+import (
+	"backend_crm/internal/blob"
+	"backend_crm/internal/controller/http/fasthttp/products/dto"
+	"backend_crm/internal/imaging"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/products"
+	"backend_crm/internal/server"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/valyala/fasthttp"
+)
+
+// UploadImage adds a photo to the product gallery (Director only). The
+// image is sent as the "image" field of a multipart form.
+func (c *Controller) UploadImage(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	header, err := ctx.FormFile("image")
+	if err != nil {
+		ctx.Error("image file is required", fasthttp.StatusBadRequest)
+		return
+	}
+	if header.Size > int64(c.maxImageSize) {
+		ctx.Error("image is too large", fasthttp.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error opening uploaded image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, int64(c.maxImageSize)+1))
+	file.Close()
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error reading uploaded image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	if len(data) > c.maxImageSize {
+		ctx.Error("image is too large", fasthttp.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The declared content type is ignored, the data decides
+	img, err := imaging.Decode(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrUnsupportedType):
+			ctx.Error("only JPEG, PNG and GIF images are supported", fasthttp.StatusUnsupportedMediaType)
+		case errors.Is(err, imaging.ErrTooLarge):
+			ctx.Error("image dimensions are too large", fasthttp.StatusRequestEntityTooLarge)
+		default:
+			ctx.Error("Invalid image", fasthttp.StatusBadRequest)
+		}
+		return
+	}
+
+	thumbnail, thumbnailType, err := img.Thumbnail(c.thumbnailSize)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error creating thumbnail")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	prefix := "products/" + productId + "/" + rand.Text()
+	userId, _ := ctx.UserValue("user_id").(string)
+	image := &model.ProductImage{
+		ProductId:  productId,
+		Width:      img.Width,
+		Height:     img.Height,
+		Original:   blobFile(prefix+"/original", img.ContentType, data),
+		Thumbnail:  blobFile(prefix+"/thumbnail", thumbnailType, thumbnail),
+		UploadedBy: userId,
+	}
+
+	if err := c.blobs.Put(ctx, image.Original.Key, bytes.NewReader(data)); err != nil {
+		c.logger.Error().Err(err).Msg("Error storing image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+	if err := c.blobs.Put(ctx, image.Thumbnail.Key, bytes.NewReader(thumbnail)); err != nil {
+		c.deleteBlobs(image.Original.Key)
+		c.logger.Error().Err(err).Msg("Error storing thumbnail")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	if err := c.products.SaveImage(ctx, image); err != nil {
+		c.deleteBlobs(image.Original.Key, image.Thumbnail.Key)
+		if errors.Is(err, products.ErrNotFoundProduct) {
+			ctx.Error("product not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error saving image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(dto.ProductImageFromModel(image)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Images lists the product gallery in display order
+func (c *Controller) Images(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	images, err := c.products.GetImages(ctx, productId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting images")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.ProductImage, 0, len(images))
+	for _, image := range images {
+		resp = append(resp, dto.ProductImageFromModel(image))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Image serves the uploaded image
+func (c *Controller) Image(ctx *fasthttp.RequestCtx) {
+	c.serveImage(ctx, func(image *model.ProductImage) model.BlobFile {
+		return image.Original
+	})
+}
+
+// Thumbnail serves the scaled down image
+func (c *Controller) Thumbnail(ctx *fasthttp.RequestCtx) {
+	c.serveImage(ctx, func(image *model.ProductImage) model.BlobFile {
+		return image.Thumbnail
+	})
+}
+
+// DeleteImage removes a photo from the product gallery (Director only)
+func (c *Controller) DeleteImage(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsDelete() {
+		ctx.Error("Only DELETE method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+	imageId, ok := ctx.UserValue("imageId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	image, err := c.products.DeleteImage(ctx, productId, imageId)
+	if err != nil {
+		if errors.Is(err, products.ErrNotFoundImage) {
+			ctx.Error("image not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error deleting image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	c.deleteBlobs(image.Original.Key, image.Thumbnail.Key)
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func (c *Controller) serveImage(ctx *fasthttp.RequestCtx, file func(*model.ProductImage) model.BlobFile) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+	imageId, ok := ctx.UserValue("imageId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	image, err := c.products.GetImage(ctx, productId, imageId)
+	if err != nil {
+		if errors.Is(err, products.ErrNotFoundImage) {
+			ctx.Error("image not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error getting image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	f := file(image)
+	obj, err := c.blobs.Open(ctx, f.Key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			c.logger.Warn().Str("key", f.Key).Msg("Image blob is missing")
+			ctx.Error("image not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error opening image")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	server.ServeBlob(ctx, obj, f.ContentType, f.SHA256)
+}
+
+// deleteBlobs removes blobs that are no longer referenced. Failures only
+// leave orphaned files behind, so they are logged and not reported.
+func (c *Controller) deleteBlobs(keys ...string) {
+	for _, key := range keys {
+		if err := c.blobs.Delete(context.Background(), key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			c.logger.Error().Err(err).Str("key", key).Msg("Error deleting blob")
+		}
+	}
+}
+
+func blobFile(key string, contentType string, data []byte) model.BlobFile {
+	sum := sha256.Sum256(data)
+	return model.BlobFile{
+		Key:         key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+}
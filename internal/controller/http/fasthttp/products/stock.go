@@ -0,0 +1,108 @@
+package products
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/products/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/products"
+	"encoding/json"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+// AdjustStock books received goods or writes stock off (Director only)
+func (c *Controller) AdjustStock(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return
+	}
+
+	var adjustment *dto.StockAdjustment
+	if err := json.Unmarshal(body, &adjustment); err != nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if adjustment.Quantity == 0 {
+		ctx.Error("Quantity must not be zero", fasthttp.StatusBadRequest)
+		return
+	}
+
+	userId, _ := ctx.UserValue("user_id").(string)
+	if err := c.products.AdjustStock(ctx, &model.StockAdjustment{
+		ProductId: productId,
+		UserId:    userId,
+		Quantity:  adjustment.Quantity,
+		Note:      adjustment.Note,
+	}); err != nil {
+		switch {
+		case errors.Is(err, products.ErrNotFoundProduct):
+			ctx.Error("product not found", fasthttp.StatusNotFound)
+		case errors.Is(err, products.ErrInsufficientStock):
+			ctx.Error("stock would drop below the reserved quantity", fasthttp.StatusConflict)
+		default:
+			c.logger.Error().Err(err).Msg("Error adjusting stock")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		}
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// StockMovements returns the stock history of a product, newest first
+func (c *Controller) StockMovements(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	productId, ok := ctx.UserValue("productId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	movements, err := c.products.GetStockMovements(ctx, productId)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting stock movements")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.StockMovement, 0, len(movements))
+	for _, m := range movements {
+		resp = append(resp, &dto.StockMovement{
+			MovementId: m.MovementId,
+			OrderId:    m.OrderId,
+			UserId:     m.UserId,
+			Kind:       string(m.Kind),
+			Quantity:   m.Quantity,
+			Note:       m.Note,
+			CreatedAt:  m.CreatedAt,
+		})
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,34 @@
+package dto
+
+import "backend_crm/internal/model"
+
+type Schedule struct {
+	Name      string   `json:"name"`
+	Cron      string   `json:"cron"`
+	Period    string   `json:"period"`
+	Formats   []string `json:"formats"`
+	To        []string `json:"to"`
+	Directory string   `json:"directory"`
+}
+
+func SchedulesFromModel(schedules []model.ReportSchedule) []Schedule {
+	result := make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		schedule := Schedule{
+			Name:      s.Name,
+			Cron:      s.Cron,
+			Period:    string(s.Period),
+			Formats:   make([]string, 0, len(s.Formats)),
+			To:        s.To,
+			Directory: s.Directory,
+		}
+		for _, f := range s.Formats {
+			schedule.Formats = append(schedule.Formats, string(f))
+		}
+		if schedule.To == nil {
+			schedule.To = []string{}
+		}
+		result = append(result, schedule)
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package reports
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/reports/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/usecase/reports"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+type Controller struct {
+	reports reports.Usecase
+	logger  zerolog.Logger
+}
+
+func NewController(reports reports.Usecase, logger zerolog.Logger) *Controller {
+	return &Controller{
+		reports: reports,
+		logger:  logger,
+	}
+}
+
+// Reports lists the configured report schedules
+func (c *Controller) Reports(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.SchedulesFromModel(c.reports.Schedules())); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Preview renders a report as it would be delivered now
+func (c *Controller) Preview(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	format := model.ReportFormat(ctx.QueryArgs().Peek("format"))
+	switch format {
+	case "":
+		format = model.ReportHTML
+	case model.ReportHTML, model.ReportPDF:
+	default:
+		ctx.Error("format must be html or pdf", fasthttp.StatusBadRequest)
+		return
+	}
+
+	name, _ := ctx.UserValue("name").(string)
+	report, err := c.reports.Build(ctx, name, time.Now())
+	if err != nil {
+		if errors.Is(err, reports.ErrNotFoundReport) {
+			ctx.Error("report not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Str("report", name).Msg("Error building report")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	data, contentType, err := c.reports.Render(report, format)
+	if err != nil {
+		c.logger.Error().Err(err).Str("report", name).Msg("Error rendering report")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType(contentType)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBody(data)
+}
+
+// Send delivers a report now, in addition to its schedule
+func (c *Controller) Send(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	name, _ := ctx.UserValue("name").(string)
+	if err := c.reports.Deliver(ctx, name, time.Now()); err != nil {
+		if errors.Is(err, reports.ErrNotFoundReport) {
+			ctx.Error("report not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Str("report", name).Msg("Error delivering report")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
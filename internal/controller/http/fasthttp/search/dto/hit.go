@@ -0,0 +1,25 @@
+package dto
+
+import "backend_crm/internal/model"
+
+type Hit struct {
+	Type     string  `json:"type"`
+	Id       string  `json:"id"`
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle"`
+	Rank     float64 `json:"rank"`
+}
+
+func HitsFromModel(hits []*model.SearchHit) []*Hit {
+	result := make([]*Hit, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, &Hit{
+			Type:     string(hit.Kind),
+			Id:       hit.Id,
+			Title:    hit.Title,
+			Subtitle: hit.Subtitle,
+			Rank:     hit.Rank,
+		})
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/search/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/search"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	defaultLimit   = 20
+	maxLimit       = 100
+	maxQueryLength = 200
+)
+
+type Controller struct {
+	search search.Repository
+	logger zerolog.Logger
+}
+
+func NewController(search search.Repository, logger zerolog.Logger) *Controller {
+	return &Controller{
+		search: search,
+		logger: logger,
+	}
+}
+
+// Search looks for orders, customers and products matching the q query
+// argument. Directors search everything, other roles only their own orders,
+// the customers of those orders and products that are still sold.
+func (c *Controller) Search(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	userRole, ok := ctx.UserValue("user_role").(model.Role)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	queryArgs := ctx.QueryArgs()
+	text := strings.TrimSpace(string(queryArgs.Peek("q")))
+	if text == "" {
+		ctx.Error("q is required", fasthttp.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(text) > maxQueryLength {
+		ctx.Error("q is too long", fasthttp.StatusBadRequest)
+		return
+	}
+
+	limit := defaultLimit
+	if queryArgs.Has("limit") {
+		var err error
+		limit, err = queryArgs.GetUint("limit")
+		if err != nil || limit == 0 || limit > maxLimit {
+			ctx.Error("limit must be between 1 and 100", fasthttp.StatusBadRequest)
+			return
+		}
+	}
+
+	query := model.SearchQuery{
+		Text:            text,
+		IncludeInactive: userRole == model.Director,
+		Limit:           limit,
+	}
+	if userRole != model.Director {
+		query.UserId = ctx.UserValue("user_id").(string)
+	}
+
+	hits, err := c.search.Search(ctx, query)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error searching")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.HitsFromModel(hits)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
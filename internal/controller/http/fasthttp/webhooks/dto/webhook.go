@@ -0,0 +1,101 @@
+package dto
+
+import (
+	"backend_crm/internal/model"
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	WebhookId   string   `json:"webhookId"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	// Secret is only returned when it was set or generated
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SaveWebhook creates or replaces a webhook. An empty secret generates one
+// for a new webhook and keeps the current one on update, a missing active
+// enables a new webhook and keeps the current state on update.
+type SaveWebhook struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+	Secret      string   `json:"secret"`
+}
+
+type Delivery struct {
+	DeliveryId   string          `json:"deliveryId"`
+	EventId      string          `json:"eventId"`
+	Event        string          `json:"event"`
+	OrderId      string          `json:"orderId"`
+	Data         json.RawMessage `json:"data"`
+	RedeliveryOf string          `json:"redeliveryOf,omitempty"`
+	Status       string          `json:"status"`
+	Attempts     int             `json:"attempts"`
+	LastError    string          `json:"lastError,omitempty"`
+	// Response is the answer to the last attempt
+	Response      *Response  `json:"response,omitempty"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type Response struct {
+	Status     int    `json:"status"`
+	Body       string `json:"body"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+func WebhookFromModel(webhook *model.Webhook, withSecret bool) *Webhook {
+	result := &Webhook{
+		WebhookId:   webhook.WebhookId,
+		URL:         webhook.URL,
+		Events:      make([]string, 0, len(webhook.Events)),
+		Description: webhook.Description,
+		Active:      webhook.Active,
+		CreatedAt:   webhook.CreatedAt,
+		UpdatedAt:   webhook.UpdatedAt,
+	}
+	for _, event := range webhook.Events {
+		result.Events = append(result.Events, string(event))
+	}
+	if withSecret {
+		result.Secret = webhook.Secret
+	}
+	return result
+}
+
+func DeliveryFromModel(delivery *model.WebhookDelivery) *Delivery {
+	result := &Delivery{
+		DeliveryId:   delivery.DeliveryId,
+		EventId:      delivery.EventId,
+		Event:        string(delivery.Event),
+		OrderId:      delivery.OrderId,
+		Data:         delivery.Data,
+		RedeliveryOf: delivery.RedeliveryOf,
+		Status:       string(delivery.Status),
+		Attempts:     delivery.Attempts,
+		LastError:    delivery.LastError,
+		CreatedAt:    delivery.CreatedAt,
+	}
+	if delivery.Response != nil {
+		result.Response = &Response{
+			Status:     delivery.Response.Status,
+			Body:       delivery.Response.Body,
+			DurationMs: delivery.Response.Duration.Milliseconds(),
+		}
+	}
+	if delivery.Status == model.DeliveryPending {
+		result.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if !delivery.SentAt.IsZero() {
+		result.SentAt = &delivery.SentAt
+	}
+	return result
+}
@@ -0,0 +1,398 @@
+package webhooks
+
+import (
+	"backend_crm/internal/controller/http/fasthttp/webhooks/dto"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/webhooks"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	maxURLLength      = 2048
+	minSecretLength   = 16
+	maxSecretLength   = 128
+	defaultDeliveries = 50
+	maxDeliveries     = 200
+)
+
+type Controller struct {
+	webhooks webhooks.Repository
+	logger   zerolog.Logger
+}
+
+func NewController(webhooks webhooks.Repository, logger zerolog.Logger) *Controller {
+	return &Controller{
+		webhooks: webhooks,
+		logger:   logger,
+	}
+}
+
+// Webhooks lists the webhook subscriptions without their secrets
+// (Director only)
+func (c *Controller) Webhooks(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	found, err := c.webhooks.GetAll(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting webhooks")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Webhook, 0, len(found))
+	for _, webhook := range found {
+		resp = append(resp, dto.WebhookFromModel(webhook, false))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Webhook shows one webhook subscription without its secret (Director only)
+func (c *Controller) Webhook(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	webhook, ok := c.directorWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.WebhookFromModel(webhook, false)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// NewWebhook subscribes a URL to order events (Director only). The
+// response holds the secret, it is not shown again.
+func (c *Controller) NewWebhook(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	req, ok := parseWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	webhook := &model.Webhook{Active: true}
+	if !applyWebhook(ctx, webhook, req) {
+		return
+	}
+	if webhook.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			c.logger.Error().Err(err).Msg("Error generating webhook secret")
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+			return
+		}
+		webhook.Secret = secret
+	}
+
+	if err := c.webhooks.Save(ctx, webhook); err != nil {
+		c.logger.Error().Err(err).Msg("Error saving webhook")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	if err := json.NewEncoder(ctx).Encode(dto.WebhookFromModel(webhook, true)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// UpdateWebhook replaces url, events and description of a webhook and
+// optionally its state and secret (Director only). Pending deliveries are
+// sent with the new settings.
+func (c *Controller) UpdateWebhook(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	webhook, ok := c.directorWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	req, ok := parseWebhook(ctx)
+	if !ok {
+		return
+	}
+	if !applyWebhook(ctx, webhook, req) {
+		return
+	}
+
+	if err := c.webhooks.Update(ctx, webhook); err != nil {
+		if errors.Is(err, webhooks.ErrNotFoundWebhook) {
+			ctx.Error("webhook not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error updating webhook")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(dto.WebhookFromModel(webhook, req.Secret != "")); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// DeleteWebhook removes a webhook with its delivery log (Director only)
+func (c *Controller) DeleteWebhook(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsDelete() {
+		ctx.Error("Only DELETE method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	webhookId, ok := ctx.UserValue("webhookId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return
+	}
+
+	if err := c.webhooks.Delete(ctx, webhookId); err != nil {
+		if errors.Is(err, webhooks.ErrNotFoundWebhook) {
+			ctx.Error("webhook not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error deleting webhook")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// Deliveries is the delivery log of a webhook, newest first (Director only)
+func (c *Controller) Deliveries(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	webhook, ok := c.directorWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	queryArgs := ctx.QueryArgs()
+
+	limit := defaultDeliveries
+	if queryArgs.Has("limit") {
+		var err error
+		limit, err = queryArgs.GetUint("limit")
+		if err != nil || limit == 0 || limit > maxDeliveries {
+			ctx.Error("limit must be between 1 and 200", fasthttp.StatusBadRequest)
+			return
+		}
+	}
+
+	var before time.Time
+	if raw := string(queryArgs.Peek("before")); raw != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			ctx.Error("before must be an RFC 3339 timestamp", fasthttp.StatusBadRequest)
+			return
+		}
+	}
+
+	found, err := c.webhooks.GetDeliveries(ctx, webhook.WebhookId, before, limit)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Error getting webhook deliveries")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*dto.Delivery, 0, len(found))
+	for _, delivery := range found {
+		resp = append(resp, dto.DeliveryFromModel(delivery))
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// Redeliver queues the event of a delivery again, whatever its outcome
+// was (Director only)
+func (c *Controller) Redeliver(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Error("Only POST method allowed", fasthttp.StatusMethodNotAllowed)
+		return
+	}
+
+	webhook, ok := c.directorWebhook(ctx)
+	if !ok {
+		return
+	}
+	if !webhook.Active {
+		ctx.Error("webhook is disabled", fasthttp.StatusConflict)
+		return
+	}
+
+	deliveryId, ok := ctx.UserValue("deliveryId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return
+	}
+
+	delivery, err := c.webhooks.Redeliver(ctx, webhook.WebhookId, deliveryId)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrNotFoundDelivery) {
+			ctx.Error("delivery not found", fasthttp.StatusNotFound)
+			return
+		}
+		c.logger.Error().Err(err).Msg("Error redelivering webhook")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+	if err := json.NewEncoder(ctx).Encode(dto.DeliveryFromModel(delivery)); err != nil {
+		ctx.Error("Error creating response", fasthttp.StatusInternalServerError)
+	}
+}
+
+// directorWebhook loads the webhook of the path for a Director
+func (c *Controller) directorWebhook(ctx *fasthttp.RequestCtx) (*model.Webhook, bool) {
+	webhookId, ok := ctx.UserValue("webhookId").(string)
+	if !ok {
+		ctx.Error("Invalid request", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	if userRole, _ := ctx.UserValue("user_role").(model.Role); userRole != model.Director {
+		ctx.Error("Forbidden", fasthttp.StatusForbidden)
+		return nil, false
+	}
+
+	webhook, err := c.webhooks.GetById(ctx, webhookId)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrNotFoundWebhook) {
+			ctx.Error("webhook not found", fasthttp.StatusNotFound)
+			return nil, false
+		}
+		c.logger.Error().Err(err).Msg("Error getting webhook")
+		ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+func parseWebhook(ctx *fasthttp.RequestCtx) (*dto.SaveWebhook, bool) {
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		ctx.Error("Empty request body", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	var req *dto.SaveWebhook
+	if err := json.Unmarshal(body, &req); err != nil || req == nil {
+		ctx.Error("Invalid JSON format", fasthttp.StatusBadRequest)
+		return nil, false
+	}
+
+	return req, true
+}
+
+// applyWebhook validates the request and copies it to webhook
+func applyWebhook(ctx *fasthttp.RequestCtx, webhook *model.Webhook, req *dto.SaveWebhook) bool {
+	rawURL := strings.TrimSpace(req.URL)
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || len(rawURL) > maxURLLength {
+		ctx.Error("url must be an http or https URL of at most 2048 characters", fasthttp.StatusBadRequest)
+		return false
+	}
+
+	events := make([]model.WebhookEvent, 0, len(req.Events))
+	for _, raw := range req.Events {
+		event := model.WebhookEvent(raw)
+		if !slices.Contains(model.WebhookEvents, event) {
+			ctx.Error("events must be order.created or order.status_changed", fasthttp.StatusBadRequest)
+			return false
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		ctx.Error("At least one event is required", fasthttp.StatusBadRequest)
+		return false
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if utf8.RuneCountInString(description) > 200 {
+		ctx.Error("Description must be at most 200 characters", fasthttp.StatusBadRequest)
+		return false
+	}
+
+	if req.Secret != "" && (len(req.Secret) < minSecretLength || len(req.Secret) > maxSecretLength) {
+		ctx.Error("Secret must be 16 to 128 characters", fasthttp.StatusBadRequest)
+		return false
+	}
+
+	webhook.URL = rawURL
+	webhook.Events = events
+	webhook.Description = description
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	return true
+}
+
+// newSecret returns 32 random bytes in hex
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes the repositories translate into their own errors
+const (
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeInvalidText         = "22P02"
+)
+
+// IsForeignKeyViolation reports a reference to a row that does not exist
+func IsForeignKeyViolation(err error) bool {
+	return hasCode(err, codeForeignKeyViolation)
+}
+
+// IsUniqueViolation reports a value that is already taken
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, codeUniqueViolation)
+}
+
+// IsInvalidText reports a malformed value such as an id that is no uuid
+func IsInvalidText(err error) bool {
+	return hasCode(err, codeInvalidText)
+}
+
+func hasCode(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
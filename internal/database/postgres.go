@@ -0,0 +1,65 @@
+package database
+
+import (
+	"backend_crm/internal/config"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// maxBackoff caps the delay between startup connection attempts
+const maxBackoff = 10 * time.Second
+
+// Open creates a tuned connection pool and waits until Postgres accepts
+// connections, retrying with exponential backoff. This covers the case when
+// the database container is still starting.
+func Open(ctx context.Context, cfg *config.AppConfig, logger zerolog.Logger) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.GetDSN())
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.GetConnMaxLifetime())
+	db.SetConnMaxIdleTime(cfg.GetConnMaxIdleTime())
+
+	backoff := cfg.GetConnectBackoff()
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.GetQueryTimeout())
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return db, nil
+		}
+
+		if attempt > cfg.Database.ConnectRetries {
+			db.Close()
+			return nil, fmt.Errorf("ping after %d attempts: %w", attempt, err)
+		}
+
+		logger.Warn().Err(err).Int("attempt", attempt).Dur("retry_in", backoff).Msg("database is not ready")
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// WithQueryTimeout derives the context for a single repository call from
+// the request context, so a slow query cannot outlive its budget.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
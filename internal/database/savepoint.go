@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Savepoint runs fn inside a savepoint of tx. When fn fails only its own
+// statements are rolled back and the transaction stays usable, so a batch
+// can skip a bad record and commit the rest.
+func Savepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+		return fmt.Errorf("savepoint: %w", err)
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); rbErr != nil {
+			return fmt.Errorf("rollback to savepoint: %w", rbErr)
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_item`); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// bom makes Excel detect UTF-8 instead of the ANSI code page
+const bom = "\uFEFF"
+
+type csvWriter struct {
+	w       *csv.Writer
+	locale  Locale
+	started bool
+	out     io.Writer
+	record  []string
+}
+
+// NewCSV returns a writer producing UTF-8 CSV with a byte order mark
+func NewCSV(w io.Writer, locale Locale) Writer {
+	cw := csv.NewWriter(w)
+	cw.Comma = locale.FieldSeparator
+	// Excel expects CRLF line endings
+	cw.UseCRLF = true
+
+	return &csvWriter{
+		w:      cw,
+		locale: locale,
+		out:    w,
+	}
+}
+
+func (c *csvWriter) WriteRow(cells []Cell) error {
+	if !c.started {
+		c.started = true
+		if _, err := io.WriteString(c.out, bom); err != nil {
+			return err
+		}
+	}
+
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		switch {
+		case cell.Numeric:
+			c.record = append(c.record, strings.Replace(cell.Value, ".", c.locale.DecimalSeparator, 1))
+		case formulaLike(cell.Value):
+			// Keep spreadsheets from evaluating text as a formula
+			c.record = append(c.record, "'"+cell.Value)
+		default:
+			c.record = append(c.record, cell.Value)
+		}
+	}
+
+	if err := c.w.Write(c.record); err != nil {
+		return err
+	}
+	// Flush every row so the output is streamed, not buffered in csv.Writer
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if !c.started {
+		c.started = true
+		if _, err := io.WriteString(c.out, bom); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formulaLike reports text that a spreadsheet would interpret as a formula.
+// Phone numbers such as "+7 (900) 123-45-67" are left alone.
+func formulaLike(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	switch s[0] {
+	case '=', '@', '\t', '\r':
+		return true
+	case '+', '-':
+		return strings.ContainsFunc(s[1:], func(r rune) bool {
+			return !strings.ContainsRune("0123456789 ()-.", r)
+		})
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFormulaLike(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"", false},
+		{"Acme", false},
+		{"=SUM(A1:A2)", true},
+		{"@cmd", true},
+		{"\tx", true},
+		{"\rx", true},
+		{"+7 (900) 123-45-67", false},
+		{"-12.5", false},
+		{"+cmd|' /C calc'!A0", true},
+		{"-2+3", true},
+		{"+", false},
+		{"a=b", false},
+	}
+
+	for _, tt := range tests {
+		if got := formulaLike(tt.s); got != tt.want {
+			t.Errorf("formulaLike(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	rows := [][]Cell{
+		{Text("Client"), Text("Phone"), Text("Total")},
+		{Text("=HYPERLINK(\"x\")"), Text("+7 (900) 123-45-67"), Decimal("-1234.50")},
+		{Text("Acme; Ltd"), Text("@home"), Int(42)},
+	}
+
+	tests := []struct {
+		name   string
+		locale Locale
+		want   string
+	}{
+		{"decimal point", LocaleDecimalPoint, bom +
+			"Client,Phone,Total\r\n" +
+			"\"'=HYPERLINK(\"\"x\"\")\",+7 (900) 123-45-67,-1234.50\r\n" +
+			"Acme; Ltd,'@home,42\r\n"},
+		{"decimal comma", LocaleDecimalComma, bom +
+			"Client;Phone;Total\r\n" +
+			"\"'=HYPERLINK(\"\"x\"\")\";+7 (900) 123-45-67;-1234,50\r\n" +
+			"\"Acme; Ltd\";'@home;42\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewCSV(&buf, tt.locale)
+			for _, row := range rows {
+				if err := w.WriteRow(row); err != nil {
+					t.Fatalf("WriteRow: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewCSV(&buf, LocaleDecimalPoint).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := buf.String(); got != bom {
+		t.Errorf("got %q, want only the byte order mark", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Package export writes tabular data as CSV or XLSX row by row, so large
+// exports never have to be held in memory.
+package export
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Cell is a single spreadsheet value. Numbers keep their exact decimal
+// representation with a dot; writers apply the locale.
+type Cell struct {
+	Value   string
+	Numeric bool
+}
+
+// Text returns a string cell
+func Text(s string) Cell {
+	return Cell{Value: s}
+}
+
+// Decimal returns a numeric cell from a decimal string such as "-1234.50"
+func Decimal(s string) Cell {
+	return Cell{Value: s, Numeric: true}
+}
+
+// Int returns a numeric cell
+func Int(n int64) Cell {
+	return Decimal(strconv.FormatInt(n, 10))
+}
+
+// Float32 returns a numeric cell with the shortest representation of f
+func Float32(f float32) Cell {
+	return Decimal(strconv.FormatFloat(float64(f), 'f', -1, 32))
+}
+
+// Writer receives rows, the first one usually being the header
+type Writer interface {
+	WriteRow(cells []Cell) error
+	// Close completes the document. It does not close the underlying writer.
+	Close() error
+}
+
+// Locale describes how numbers and fields are written in CSV. Spreadsheet
+// applications in locales using a decimal comma expect ';' between fields.
+type Locale struct {
+	DecimalSeparator string
+	FieldSeparator   rune
+}
+
+var (
+	LocaleDecimalPoint = Locale{DecimalSeparator: ".", FieldSeparator: ','}
+	LocaleDecimalComma = Locale{DecimalSeparator: ",", FieldSeparator: ';'}
+)
+
+// decimalComma lists the languages writing 1234,5
+var decimalComma = map[string]bool{
+	"ru": true, "uk": true, "be": true, "kk": true,
+	"de": true, "fr": true, "es": true, "it": true,
+	"pt": true, "nl": true, "pl": true, "cs": true,
+	"tr": true, "sv": true, "fi": true, "da": true,
+}
+
+// ParseLocale picks the locale for a language tag such as "ru", "de-AT" or
+// an Accept-Language header. Unknown languages fall back to LocaleDecimalPoint.
+func ParseLocale(tag string) Locale {
+	tag, _, _ = strings.Cut(tag, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	lang, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+
+	if decimalComma[lang] {
+		return LocaleDecimalComma
+	}
+	return LocaleDecimalPoint
+}
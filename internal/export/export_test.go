@@ -0,0 +1,50 @@
+package export
+
+import "testing"
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Locale
+	}{
+		{"", LocaleDecimalPoint},
+		{"en", LocaleDecimalPoint},
+		{"en-US", LocaleDecimalPoint},
+		{"ru", LocaleDecimalComma},
+		{"RU", LocaleDecimalComma},
+		{"de-AT", LocaleDecimalComma},
+		{"pt_BR", LocaleDecimalComma},
+		{" fr ", LocaleDecimalComma},
+		{"ru-RU,ru;q=0.9,en-US;q=0.8", LocaleDecimalComma},
+		{"en-GB,en;q=0.9,de;q=0.8", LocaleDecimalPoint},
+		{"de;q=0.9", LocaleDecimalComma},
+		{"ja", LocaleDecimalPoint},
+	}
+
+	for _, tt := range tests {
+		if got := ParseLocale(tt.tag); got != tt.want {
+			t.Errorf("ParseLocale(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestNumericCells(t *testing.T) {
+	tests := []struct {
+		name string
+		cell Cell
+		want string
+	}{
+		{"int", Int(-7), "-7"},
+		{"float32", Float32(0.1), "0.1"},
+		{"whole float32", Float32(3), "3"},
+		{"decimal", Decimal("1234.50"), "1234.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.cell.Numeric || tt.cell.Value != tt.want {
+				t.Errorf("got %+v, want numeric %q", tt.cell, tt.want)
+			}
+		})
+	}
+}
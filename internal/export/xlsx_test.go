@@ -0,0 +1,144 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := columnName(tt.i); got != tt.want {
+			t.Errorf("columnName(%d) = %q, want %q", tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("ё", maxCellLength+1)
+	if got := []rune(truncate(long)); len(got) != maxCellLength {
+		t.Errorf("truncate() kept %d characters, want %d", len(got), maxCellLength)
+	}
+	if got := truncate("short"); got != "short" {
+		t.Errorf("truncate(%q) = %q", "short", got)
+	}
+}
+
+// sheet is the part of a worksheet the tests look at
+type sheet struct {
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R    string `xml:"r,attr"`
+			S    string `xml:"s,attr"`
+			T    string `xml:"t,attr"`
+			V    string `xml:"v"`
+			Text string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSX(&buf, "Orders & <co>")
+	if err != nil {
+		t.Fatalf("NewXLSX: %v", err)
+	}
+	rows := [][]Cell{
+		{Text("Client"), Text("Total")},
+		{Text("Acme <\"Ltd\"> & Co"), Decimal("-1234.50")},
+		{Text("=1+1"), Int(42)},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		parts[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		content, ok := parts[name]
+		if !ok {
+			t.Errorf("part %s missing", name)
+			continue
+		}
+		var v struct{}
+		if err := xml.Unmarshal(content, &v); err != nil {
+			t.Errorf("part %s is not well-formed: %v", name, err)
+		}
+	}
+
+	var workbook struct {
+		Sheet struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatalf("workbook: %v", err)
+	}
+	if workbook.Sheet.Name != "Orders & <co>" {
+		t.Errorf("sheet name %q", workbook.Sheet.Name)
+	}
+
+	var s sheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &s); err != nil {
+		t.Fatalf("sheet: %v", err)
+	}
+	if len(s.Rows) != len(rows) {
+		t.Fatalf("%d rows, want %d", len(s.Rows), len(rows))
+	}
+
+	header := s.Rows[0].Cells
+	if header[0].R != "A1" || header[0].S != "1" || header[0].Text != "Client" || header[1].R != "B1" {
+		t.Errorf("header %+v", header)
+	}
+
+	text, number := s.Rows[1].Cells[0], s.Rows[1].Cells[1]
+	if text.R != "A2" || text.S != "" || text.T != "inlineStr" || text.Text != "Acme <\"Ltd\"> & Co" {
+		t.Errorf("text cell %+v", text)
+	}
+	if number.R != "B2" || number.T != "" || number.V != "-1234.50" {
+		t.Errorf("number cell %+v", number)
+	}
+
+	// A formula-like string stays a plain inline string, not a formula
+	if formula := s.Rows[2].Cells[0]; formula.T != "inlineStr" || formula.Text != "=1+1" {
+		t.Errorf("formula-like cell %+v", formula)
+	}
+}
@@ -0,0 +1,160 @@
+// Package imaging decodes uploaded images and renders thumbnails using
+// only the standard library decoders (JPEG, PNG and GIF).
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// maxPixels guards against decompression bombs: a small file can declare
+// huge dimensions and exhaust memory when decoded.
+const maxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+// Image is a decoded upload
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	img         image.Image
+}
+
+// Sniff returns the content type detected from the data itself, ignoring
+// whatever the client claimed. Only formats that can be decoded are
+// accepted.
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return contentType, nil
+	default:
+		return "", ErrUnsupportedType
+	}
+}
+
+// Decode sniffs and decodes the image in data
+func Decode(data []byte) (*Image, error) {
+	contentType, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		img, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	return &Image{
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		img:         img,
+	}, nil
+}
+
+// Thumbnail scales the image down to fit into a size x size square,
+// keeping the aspect ratio, and encodes it. Images with transparency are
+// encoded as PNG, everything else as JPEG. Images that already fit are
+// re-encoded at their original size.
+func (i *Image) Thumbnail(size int) (data []byte, contentType string, err error) {
+	w, h := fit(i.Width, i.Height, size)
+	thumb := resize(i.img, w, h)
+
+	var buf bytes.Buffer
+	if i.ContentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		contentType = "image/jpeg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		contentType = "image/png"
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), contentType, nil
+}
+
+func fit(w, h, size int) (int, int) {
+	if w <= size && h <= size {
+		return w, h
+	}
+	if w >= h {
+		return size, max(1, h*size/w)
+	}
+	return max(1, w*size/h), size
+}
+
+// resize scales src to w x h by averaging the source pixels covered by
+// each destination pixel. This box filter is good enough for
+// downscaling, which is all thumbnails need.
+func resize(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	// Convert once so the loops below can work on raw premultiplied pixels
+	rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	if sw == w && sh == h {
+		return rgba
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max(y0+1, (y+1)*sh/h)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max(x0+1, (x+1)*sw/w)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				off := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(rgba.Pix[off])
+					g += uint64(rgba.Pix[off+1])
+					bl += uint64(rgba.Pix[off+2])
+					a += uint64(rgba.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+
+			off := dst.PixOffset(x, y)
+			dst.Pix[off] = uint8(r / n)
+			dst.Pix[off+1] = uint8(g / n)
+			dst.Pix[off+2] = uint8(bl / n)
+			dst.Pix[off+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,33 @@
+// Package mail composes and sends email messages.
+package mail
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPermanent marks a failure that will not go away by retrying, such as
+// a rejected recipient
+var ErrPermanent = errors.New("permanent mail failure")
+
+// Message is an email with an HTML body, an optional plain text
+// alternative and attachments
+type Message struct {
+	To      []string
+	Subject string
+	HTML    string
+	// Text is shown by clients that do not display HTML
+	Text        string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Sender delivers messages. From is set by the sender.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Compose returns the message in RFC 5322 format, ready for SMTP DATA.
+// The addresses must have been checked with ParseAddress.
+func Compose(from string, msg *Message, now time.Time) ([]byte, error) {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		// Encodes a display name with non-ASCII characters
+		from = addr.String()
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", strings.Join(msg.To, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", now.Format(time.RFC1123Z))
+	header.Set("Message-Id", messageId(from))
+	header.Set("MIME-Version", "1.0")
+
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	if err := writeAlternative(alternative, msg); err != nil {
+		return nil, err
+	}
+	alternativeType := "multipart/alternative; boundary=" + alternative.Boundary()
+
+	var buf bytes.Buffer
+	if len(msg.Attachments) == 0 {
+		header.Set("Content-Type", alternativeType)
+		writeHeader(&buf, header)
+		buf.Write(body.Bytes())
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header.Set("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	writeHeader(&buf, header)
+
+	part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {alternativeType}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(body.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, a := range msg.Attachments {
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(a.ContentType, map[string]string{"name": a.Name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, a.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeAlternative writes the plain text and the HTML body, the preferred
+// one comes last
+func writeAlternative(w *multipart.Writer, msg *Message) error {
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.body == "" {
+			continue
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(p.body)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-Id", "MIME-Version", "Content-Type"} {
+		if v := header.Get(key); v != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, v)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+// writeBase64 wraps the encoded data at 76 characters per line
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := w.Write([]byte(encoded[:n] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+func messageId(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// ParseAddress checks a single recipient address and returns it without a
+// display name
+func ParseAddress(address string) (string, error) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,137 @@
+package smtp
+
+import (
+	"backend_crm/internal/mail"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+const (
+	// SecurityStartTLS upgrades the connection and fails if the server
+	// does not offer STARTTLS
+	SecurityStartTLS = "starttls"
+	// SecurityTLS connects with implicit TLS, usually on port 465
+	SecurityTLS = "tls"
+	// SecurityNone sends in plain text, meant for local relays and test
+	// servers. Credentials are only sent to localhost.
+	SecurityNone = "none"
+)
+
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Security string
+	// Timeout bounds a whole delivery
+	Timeout time.Duration
+}
+
+type sender struct {
+	cfg  Config
+	from string
+}
+
+// NewSender returns a sender delivering every message over a new
+// connection to the configured server
+func NewSender(cfg Config) (mail.Sender, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp from: %w", err)
+	}
+
+	return &sender{
+		cfg:  cfg,
+		from: from,
+	}, nil
+}
+
+func (s *sender) Send(ctx context.Context, msg *mail.Message) error {
+	data, err := mail.Compose(s.cfg.From, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("compose: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", addr, err)
+	}
+	// Unblocks reads and writes when the context ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.cfg.Security == SecurityTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.cfg.Host})
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp greeting: %w", err)
+	}
+	defer client.Close()
+
+	if s.cfg.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not offer STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", classify(err))
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return fmt.Errorf("mail from: %w", classify(err))
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("rcpt to %s: %w", to, classify(err))
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", classify(err))
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("data: %w", classify(err))
+	}
+
+	// The message is accepted, a failing QUIT must not make it be sent again
+	client.Quit()
+	return nil
+}
+
+// classify marks 5xx replies as permanent, retrying them is pointless
+func classify(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: %w", mail.ErrPermanent, err)
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+// Package mention finds references to users in free text
+package mention
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A mention starts with @ at the beginning of the text or after a
+// character that cannot be part of a word, so e-mail addresses do not
+// mention anybody.
+var pattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.\-]+)`)
+
+// Usernames returns the distinct usernames mentioned in text in order of
+// first appearance
+func Usernames(text string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+		// A trailing dot ends the sentence, not the username
+		username := strings.TrimRight(m[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		result = append(result, username)
+	}
+	return result
+}
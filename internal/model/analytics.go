@@ -0,0 +1,97 @@
+package model
+
+import "time"
+
+// Grouping is the length of the periods a time series is split into
+type Grouping string
+
+const (
+	GroupByDay   Grouping = "day"
+	GroupByWeek  Grouping = "week"
+	GroupByMonth Grouping = "month"
+)
+
+// AnalyticsRange selects what an analytics query covers: events from From
+// up to but not including To. Periods start at UTC midnight, weeks on Monday.
+type AnalyticsRange struct {
+	From    time.Time
+	To      time.Time
+	GroupBy Grouping
+}
+
+// Periods returns roughly how many periods the range is split into
+func (r AnalyticsRange) Periods() int {
+	days := int(r.To.Sub(r.From).Hours()/24) + 1
+	switch r.GroupBy {
+	case GroupByWeek:
+		return days/7 + 1
+	case GroupByMonth:
+		return days/28 + 1
+	}
+	return days
+}
+
+type StatusCount struct {
+	Status OrderStatus
+	Orders int
+}
+
+type PeriodCount struct {
+	Period time.Time
+	Orders int
+}
+
+// CompletionTime is how long orders took from creation to their first
+// completion. Average and Median are zero when no order was completed.
+type CompletionTime struct {
+	Orders  int
+	Average time.Duration
+	Median  time.Duration
+}
+
+type PeriodCompletionTime struct {
+	Period time.Time
+	CompletionTime
+}
+
+// RejectionRate counts orders by their current status. Rate is the share
+// of rejected orders among the decided ones, rejected or complete.
+type RejectionRate struct {
+	Orders    int
+	Rejected  int
+	Completed int
+	Rate      float64
+}
+
+type PeriodRejectionRate struct {
+	Period time.Time
+	RejectionRate
+}
+
+type ProductRanking string
+
+const (
+	RankByOrders ProductRanking = "orders"
+	RankByWeight ProductRanking = "weight"
+)
+
+type ProductStats struct {
+	ProductId string
+	Name      string
+	Orders    int
+	Quantity  int64
+	// Weight in kg, from the weight recorded on the order items
+	Weight float64
+}
+
+// EmployeeStats is the work of one user. Open counts the orders assigned
+// now and still in consideration or at work, Completed and Rejected the
+// status changes the user made in the range.
+type EmployeeStats struct {
+	UserId            string
+	Username          string
+	Open              int
+	Completed         int
+	Rejected          int
+	AverageCompletion time.Duration
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// OrderAttachment is a file kept with an order, e.g. a drawing or an
+// invoice sent by the customer
+type OrderAttachment struct {
+	AttachmentId string
+	OrderId      string
+	Filename     string
+	File         BlobFile
+	UploadedBy   string
+	// Username of the uploader
+	Username  string
+	CreatedAt time.Time
+}
@@ -0,0 +1,8 @@
+package model
+
+type Category struct {
+	CategoryId string
+	// ParentId is empty for top level categories
+	ParentId string
+	Name     string
+}
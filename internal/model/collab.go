@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Viewer is a user with an order open
+type Viewer struct {
+	UserId   string
+	Username string
+}
+
+// OrderLock marks an order as being edited. It is held by one editing
+// session, other sessions of the same user included must wait for it.
+type OrderLock struct {
+	OrderId    string
+	UserId     string
+	Username   string
+	SessionId  string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// OrderComment is an internal note employees leave on an order. Comments
+// are never shown to customers.
+type OrderComment struct {
+	CommentId string
+	OrderId   string
+	// ParentId is empty for top level comments
+	ParentId string
+	UserId   string
+	Username string
+	Body     string
+	Mentions []Mention
+	// Edited is set once the body has been changed, see OrderCommentEdit
+	Edited    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Mention is a user referenced in a comment with @username
+type Mention struct {
+	UserId   string
+	Username string
+}
+
+// OrderCommentEdit keeps the body a comment had before an edit
+type OrderCommentEdit struct {
+	EditId    string
+	CommentId string
+	UserId    string
+	Body      string
+	EditedAt  time.Time
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldText   CustomFieldType = "text"
+	CustomFieldNumber CustomFieldType = "number"
+	CustomFieldDate   CustomFieldType = "date"
+	CustomFieldEnum   CustomFieldType = "enum"
+)
+
+// CustomFieldDateLayout is how date values are stored and accepted
+const CustomFieldDateLayout = "2006-01-02"
+
+// maxCustomTextLength limits text values, longer notes belong in comments
+const maxCustomTextLength = 1000
+
+var customFieldKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// CustomField is an extra order property defined by a Director, such as a
+// delivery address or a deadline. Key and Type cannot change once orders
+// may hold values for it.
+type CustomField struct {
+	Key   string
+	Label string
+	Type  CustomFieldType
+	// Options lists the allowed values of an enum field
+	Options   []string
+	CreatedAt time.Time
+}
+
+// CustomFieldValues holds the custom field values of an order by key.
+// Text, date and enum values are strings, numbers are float64.
+type CustomFieldValues map[string]any
+
+// ValidKey reports whether key is usable as a field key: lower case letters,
+// digits and underscores, starting with a letter, at most 40 characters
+func (f *CustomField) ValidKey() bool {
+	return customFieldKey.MatchString(f.Key)
+}
+
+// ValidType reports whether the type is known
+func (f *CustomField) ValidType() bool {
+	switch f.Type {
+	case CustomFieldText, CustomFieldNumber, CustomFieldDate, CustomFieldEnum:
+		return true
+	}
+	return false
+}
+
+// Validate checks a value decoded from JSON and returns it in the form it
+// is stored in. The error message is meant for the client.
+func (f *CustomField) Validate(value any) (any, error) {
+	switch f.Type {
+	case CustomFieldText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if utf8.RuneCountInString(s) > maxCustomTextLength {
+			return nil, errors.New("must be at most " + strconv.Itoa(maxCustomTextLength) + " characters")
+		}
+		return s, nil
+	case CustomFieldNumber:
+		n, ok := value.(float64)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case CustomFieldDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("must be a date string")
+		}
+		if _, err := time.Parse(CustomFieldDateLayout, s); err != nil {
+			return nil, errors.New("must be a date as YYYY-MM-DD")
+		}
+		return s, nil
+	case CustomFieldEnum:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(f.Options, s) {
+			return nil, errors.New("must be one of " + strings.Join(f.Options, ", "))
+		}
+		return s, nil
+	}
+
+	return nil, errors.New("has an unknown type")
+}
+
+// Parse reads a value given as text, e.g. in a query string
+func (f *CustomField) Parse(raw string) (any, error) {
+	if f.Type != CustomFieldNumber {
+		return f.Validate(raw)
+	}
+
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, errors.New("must be a number")
+	}
+	return f.Validate(n)
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+type Customer struct {
+	CustomerId string
+	Name       string
+	Phones     []string
+	Emails     []string
+	Notes      string
+	Tags       []string
+}
+
+type CustomerUpdate struct {
+	Name  string
+	Notes string
+	Tags  []string
+}
+
+type CustomerMerge struct {
+	TargetId  string
+	SourceIds []string
+	UserId    string
+}
+
+type CustomerSplit struct {
+	CustomerId string
+	OrderIds   []string
+	Name       string
+	UserId     string
+}
+
+type CustomerAuditAction string
+
+const (
+	CustomerMerged   CustomerAuditAction = "merge"
+	CustomerSplitOff CustomerAuditAction = "split"
+)
+
+type CustomerAudit struct {
+	AuditId    string
+	CustomerId string
+	Action     CustomerAuditAction
+	UserId     string
+	Details    []byte
+	CreatedAt  time.Time
+}
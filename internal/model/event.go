@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType names a domain event
+type EventType string
+
+const (
+	EventOrderCreated       EventType = "order.created"
+	EventOrderStatusChanged EventType = "order.status_changed"
+)
+
+// DomainEvent is something that happened to an order, stored with the
+// change and dispatched to the subscribers afterwards
+type DomainEvent struct {
+	// Sequence orders the events, it is set when they are stored
+	Sequence int64
+	EventId  string
+	Type     EventType
+	OrderId  string
+	// AssigneeId is the user the order was assigned to, empty if unassigned
+	AssigneeId string
+	// Data holds the details, OrderEventData for order events
+	Data      json.RawMessage
+	CreatedAt time.Time
+
+	// HandledBy lists the subscribers that are done with the event
+	HandledBy []string
+	// Attempts counts the dispatches started, including a running one
+	Attempts int
+}
+
+// VisibleTo reports whether the user may see the event: Directors see
+// every event, other roles only the events of orders assigned to them
+func (e *DomainEvent) VisibleTo(userId string, role Role) bool {
+	return role == Director || (e.AssigneeId != "" && e.AssigneeId == userId)
+}
+
+// OrderEventData are the details of order events
+type OrderEventData struct {
+	Status OrderStatus `json:"status"`
+	// PreviousStatus is only set when the status changed
+	PreviousStatus *OrderStatus `json:"previousStatus,omitempty"`
+	// UserId made the change, empty when it is not known
+	UserId string `json:"userId,omitempty"`
+}
+
+// OrderTransition is a change of an order the orders repository made,
+// described for building its events
+type OrderTransition struct {
+	OrderId    string
+	AssigneeId string
+	// Created is set for new orders, From is meaningless then
+	Created bool
+	From    OrderStatus
+	To      OrderStatus
+	UserId  string
+}
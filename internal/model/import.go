@@ -0,0 +1,30 @@
+package model
+
+// ImportResult is the outcome of importing one record of a batch
+type ImportResult struct {
+	// Created is false when an existing record was updated
+	Created bool
+	// Skipped records already existed and were left unchanged
+	Skipped bool
+	Err     error
+}
+
+// ImportReport summarizes an import. Counts are records: products, or
+// orders which may span several rows.
+type ImportReport struct {
+	DryRun  bool
+	Rows    int
+	Created int
+	Updated int
+	Skipped int
+	Failed  int
+	Errors  []ImportRowError
+}
+
+// ImportRowError points at the line of the file a problem was found in.
+// The header is line 1.
+type ImportRowError struct {
+	Line    int
+	Column  string
+	Message string
+}
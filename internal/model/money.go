@@ -0,0 +1,62 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor currency units (kopecks, cents)
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// minorUnits lists currencies whose minor unit is not 1/100
+var minorUnits = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"BHD": 3,
+}
+
+// Exponent returns the number of decimal places of the currency
+func (m Money) Exponent() int {
+	if e, ok := minorUnits[strings.ToUpper(m.Currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// String formats the amount with its currency, e.g. "1234.50 RUB"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Decimal formats the amount without currency, e.g. "1234.50"
+func (m Money) Decimal() string {
+	exp := m.Exponent()
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if exp == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// percentOf returns amount*basisPoints/10000 rounded half away from zero
+func percentOf(amount int64, basisPoints int64) int64 {
+	v := amount * basisPoints
+	if v >= 0 {
+		return (v + 5000) / 10000
+	}
+	return (v - 5000) / 10000
+}
@@ -0,0 +1,59 @@
+package model
+
+import "testing"
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      int64
+		basisPoints int64
+		want        int64
+	}{
+		{"exact", 10000, 2000, 2000},
+		{"rounded down", 12341, 2000, 2468},
+		{"rounded up", 12344, 2000, 2469},
+		{"half rounds up", 1, 5000, 1},
+		{"below half", 1, 4999, 0},
+		{"negative half rounds away from zero", -1, 5000, -1},
+		{"negative below half", -1, 4999, 0},
+		{"zero amount", 0, 2000, 0},
+		{"whole amount", 99999, 10000, 99999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentOf(tt.amount, tt.basisPoints); got != tt.want {
+				t.Errorf("percentOf(%d, %d) = %d, want %d", tt.amount, tt.basisPoints, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{123450, "RUB"}, "1234.50"},
+		{Money{50, "USD"}, "0.50"},
+		{Money{5, "RUB"}, "0.05"},
+		{Money{0, "EUR"}, "0.00"},
+		{Money{-5, "RUB"}, "-0.05"},
+		{Money{-123450, "RUB"}, "-1234.50"},
+		{Money{1500, "JPY"}, "1500"},
+		{Money{-1500, "jpy"}, "-1500"},
+		{Money{1234, "KWD"}, "1.234"},
+		{Money{7, "BHD"}, "0.007"},
+		{Money{100, ""}, "1.00"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("%+v.Decimal() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+
+	if got := (Money{123450, "RUB"}).String(); got != "1234.50 RUB" {
+		t.Errorf("String() = %q, want %q", got, "1234.50 RUB")
+	}
+}
@@ -1,9 +1,23 @@
 package model
 
+import "time"
+
 type NewOrder struct {
+	Name        string
 	Phone       string
 	Email       string
 	Description string
-	ProductId   string
+	Items       []NewOrderItem
 	Status      OrderStatus
+	Tags        []string
+	Fields      CustomFieldValues
+	EmailOptOut bool
+	SMSOptOut   bool
+	// TaxRate in basis points, taken from the configuration on creation
+	TaxRate int
+
+	// ExternalId and CreatedAt are set for orders imported from other
+	// systems. A zero CreatedAt means now.
+	ExternalId string
+	CreatedAt  time.Time
 }
@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// OrderEvent is something that happened to an order the customer is told
+// about
+type OrderEvent string
+
+const (
+	OrderReceived  OrderEvent = "received"
+	OrderAtWork    OrderEvent = "at_work"
+	OrderCompleted OrderEvent = "completed"
+	OrderRejected  OrderEvent = "rejected"
+)
+
+// StatusEvent returns the event of an order entering the status. Orders
+// going back to consideration are not announced.
+func StatusEvent(status OrderStatus) (OrderEvent, bool) {
+	switch status {
+	case AtWork:
+		return OrderAtWork, true
+	case Complete:
+		return OrderCompleted, true
+	case Refected:
+		return OrderRejected, true
+	}
+	return "", false
+}
+
+// NotificationChannel is how customers are notified
+type NotificationChannel string
+
+const (
+	ChannelEmail NotificationChannel = "email"
+	ChannelSMS   NotificationChannel = "sms"
+)
+
+// DeliveryStatus is the state of a queued notification
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	DeliverySent    DeliveryStatus = "sent"
+	// DeliveryFailed is given up after the last attempt or a permanent error
+	DeliveryFailed DeliveryStatus = "failed"
+	// DeliverySkipped was not sent, e.g. because the customer opted out
+	DeliverySkipped DeliveryStatus = "skipped"
+)
+
+// OutboxEmail is a queued notification. The message is rendered when it is
+// sent, from the order as it is then.
+type OutboxEmail struct {
+	EmailId   string
+	OrderId   string
+	Event     OrderEvent
+	Recipient string
+	Status    DeliveryStatus
+	// Attempts counts the deliveries started, including a running one
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	// SentAt is zero until the email is sent
+	SentAt    time.Time
+	CreatedAt time.Time
+}
+
+// OutboxSMS is a queued text message, see OutboxEmail. Recipient is the
+// normalized phone of the order until the message is sent and the number
+// in international format afterwards.
+type OutboxSMS struct {
+	SMSId     string
+	OrderId   string
+	Event     OrderEvent
+	Recipient string
+	Status    DeliveryStatus
+	// Attempts counts the deliveries started, including a running one
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	// ProviderMessageId is the id the SMS provider gave the message
+	ProviderMessageId string
+	SentAt            time.Time
+	CreatedAt         time.Time
+}
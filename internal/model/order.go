@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type OrderStatus int8
 
 const (
@@ -10,10 +12,93 @@ const (
 )
 
 type Order struct {
-	OrderId     string
+	OrderId    string
+	CustomerId string
+	// UserId is the employee the order is assigned to, empty if unassigned
+	UserId      string
 	Phone       string
 	Email       string
 	Description string
-	Product     Product
-	Status      OrderStatus
+	// Product is the product of the first item, kept for clients that
+	// predate multi-line orders
+	Product Product
+	Items   []OrderItem
+	Status  OrderStatus
+	Tags    []string
+	Fields  CustomFieldValues
+	// EmailOptOut and SMSOptOut stop notifications about the order to
+	// Email and Phone
+	EmailOptOut bool
+	SMSOptOut   bool
+
+	Currency        string
+	DiscountAmount  int64
+	DiscountPercent int
+	// TaxRate in basis points, 2000 = 20%
+	TaxRate int
+
+	CreatedAt time.Time
+}
+
+// OrderFilter selects orders like the order listing does. Empty Phone,
+// Email and UserId match every order.
+type OrderFilter struct {
+	Status OrderStatus
+	Phone  string
+	Email  string
+	UserId string
+	// Tags must all be present on the order
+	Tags []string
+	// Fields must all have the given values
+	Fields CustomFieldValues
+}
+
+// VisibleTo reports whether the user may see the order: Directors see
+// every order, other roles only the orders assigned to them
+func (o *Order) VisibleTo(userId string, role Role) bool {
+	return role == Director || o.UserId == userId
+}
+
+// TotalWeight sums the weight of all order items
+func (o *Order) TotalWeight() float32 {
+	var total float32
+	for _, item := range o.Items {
+		total += item.Weight()
+	}
+	return total
+}
+
+// Subtotal sums the price snapshots of all order items
+func (o *Order) Subtotal() Money {
+	var total int64
+	for _, item := range o.Items {
+		total += item.Total()
+	}
+	return Money{Amount: total, Currency: o.Currency}
+}
+
+// Discount returns the fixed and percentage discount, never more than the subtotal
+func (o *Order) Discount() Money {
+	subtotal := o.Subtotal().Amount
+	discount := o.DiscountAmount + percentOf(subtotal, int64(o.DiscountPercent)*100)
+	return Money{Amount: min(discount, subtotal), Currency: o.Currency}
+}
+
+// Tax is charged on the discounted subtotal
+func (o *Order) Tax() Money {
+	taxable := o.Subtotal().Amount - o.Discount().Amount
+	return Money{Amount: percentOf(taxable, int64(o.TaxRate)), Currency: o.Currency}
+}
+
+// Total is the discounted subtotal plus tax
+func (o *Order) Total() Money {
+	return Money{
+		Amount:   o.Subtotal().Amount - o.Discount().Amount + o.Tax().Amount,
+		Currency: o.Currency,
+	}
+}
+
+type OrderDiscount struct {
+	Amount  int64
+	Percent int
 }
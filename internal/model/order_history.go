@@ -0,0 +1,82 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type OrderField string
+
+const (
+	OrderFieldStatus      OrderField = "status"
+	OrderFieldAssignee    OrderField = "assignee"
+	OrderFieldTags        OrderField = "tags"
+	OrderFieldCustom      OrderField = "fields"
+	OrderFieldEmailOptOut OrderField = "email_opt_out"
+	OrderFieldSMSOptOut   OrderField = "sms_opt_out"
+)
+
+// OrderChange is an entry of the order history. Values are stored as text:
+// the status number, the assignee user id, the comma separated tags, a
+// JSON object with the changed custom fields or "true"/"false" for the
+// opt-outs.
+type OrderChange struct {
+	HistoryId string
+	OrderId   string
+	// UserId made the change, empty for changes made by the system
+	UserId   string
+	Username string
+	Field    OrderField
+	OldValue string
+	NewValue string
+	// BulkId is shared by the changes of one bulk operation
+	BulkId    string
+	CreatedAt time.Time
+}
+
+type BulkAction string
+
+const (
+	BulkSetStatus BulkAction = "setStatus"
+	BulkAssign    BulkAction = "assign"
+	BulkAddTag    BulkAction = "addTag"
+	BulkRemoveTag BulkAction = "removeTag"
+)
+
+// BulkOrderUpdate applies one action to many orders, selected either by
+// OrderIds or, when there are none, by Filter. Filter.UserId limits the
+// OrderIds as well, so other roles than Director only touch their orders.
+type BulkOrderUpdate struct {
+	OrderIds []string
+	Filter   OrderFilter
+	Action   BulkAction
+	Status   OrderStatus
+	// AssigneeId is the user to assign, empty to unassign
+	AssigneeId string
+	Tag        string
+	// Atomic applies the action to all orders or to none. Otherwise every
+	// order is updated on its own and failures are reported per order.
+	Atomic bool
+	// UserId is who makes the change
+	UserId string
+}
+
+// BulkOrderResult is the outcome for one order of a bulk update
+type BulkOrderResult struct {
+	OrderId string
+	// Changed is false when the order already had the value
+	Changed bool
+	Err     error
+}
+
+// NormalizeTag trims the tag and reports whether it is usable: non-empty,
+// at most 50 characters and without commas, which separate tags in the
+// history
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || utf8.RuneCountInString(tag) > 50 || strings.ContainsRune(tag, ',') {
+		return "", false
+	}
+	return tag, true
+}
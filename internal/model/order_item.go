@@ -0,0 +1,28 @@
+package model
+
+type OrderItem struct {
+	OrderItemId string
+	Product     Product
+	Quantity    int
+	// Snapshots taken when the order was placed
+	UnitWeight float32
+	UnitPrice  int64
+}
+
+// Weight returns the weight of the whole line
+func (i OrderItem) Weight() float32 {
+	return i.UnitWeight * float32(i.Quantity)
+}
+
+// Total returns the price of the whole line in minor units
+func (i OrderItem) Total() int64 {
+	return i.UnitPrice * int64(i.Quantity)
+}
+
+type NewOrderItem struct {
+	ProductId string
+	Quantity  int
+	// UnitPrice overrides the current product price, used for imported
+	// orders. Nil takes the price from the catalog.
+	UnitPrice *int64
+}
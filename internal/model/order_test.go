@@ -0,0 +1,72 @@
+package model
+
+import "testing"
+
+func TestOrderWeight(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []OrderItem
+		want  float32
+	}{
+		{"no items", nil, 0},
+		{"one item", []OrderItem{{Quantity: 1, UnitWeight: 2.5}}, 2.5},
+		{"quantity", []OrderItem{{Quantity: 4, UnitWeight: 1.25}}, 5},
+		{"several lines", []OrderItem{{Quantity: 2, UnitWeight: 0.5}, {Quantity: 3, UnitWeight: 10}}, 31},
+		{"weightless product", []OrderItem{{Quantity: 7}, {Quantity: 1, UnitWeight: 0.75}}, 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Order{Items: tt.items}
+			if got := o.TotalWeight(); got != tt.want {
+				t.Errorf("TotalWeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderTotals(t *testing.T) {
+	// 2 × 10.00 + 1 × 5.50
+	items := []OrderItem{{Quantity: 2, UnitPrice: 1000}, {Quantity: 1, UnitPrice: 550}}
+
+	tests := []struct {
+		name         string
+		order        Order
+		wantSubtotal int64
+		wantDiscount int64
+		wantTax      int64
+		wantTotal    int64
+	}{
+		{"empty", Order{}, 0, 0, 0, 0},
+		{"no discount or tax", Order{Items: items}, 2550, 0, 0, 2550},
+		{"tax", Order{Items: items, TaxRate: 2000}, 2550, 0, 510, 3060},
+		{"tax on the discounted subtotal", Order{Items: items, DiscountAmount: 550, TaxRate: 2000}, 2550, 550, 400, 2400},
+		{"percent discount", Order{Items: items, DiscountPercent: 10, TaxRate: 2000}, 2550, 255, 459, 2754},
+		{"fixed and percent discount", Order{Items: items, DiscountAmount: 100, DiscountPercent: 10, TaxRate: 2000}, 2550, 355, 439, 2634},
+		{"discount capped at the subtotal", Order{Items: items, DiscountAmount: 5000, TaxRate: 2000}, 2550, 2550, 0, 0},
+		{"percent discount rounds half up", Order{Items: []OrderItem{{Quantity: 5, UnitPrice: 1}}, DiscountPercent: 10}, 5, 1, 0, 4},
+		{"tax rounds half up", Order{Items: []OrderItem{{Quantity: 5, UnitPrice: 1}}, TaxRate: 1000}, 5, 0, 1, 6},
+		{"fractional tax rate", Order{Items: []OrderItem{{Quantity: 1, UnitPrice: 10000}}, TaxRate: 1250}, 10000, 0, 1250, 11250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.order.Currency = "RUB"
+			amounts := []struct {
+				name string
+				got  Money
+				want int64
+			}{
+				{"Subtotal", tt.order.Subtotal(), tt.wantSubtotal},
+				{"Discount", tt.order.Discount(), tt.wantDiscount},
+				{"Tax", tt.order.Tax(), tt.wantTax},
+				{"Total", tt.order.Total(), tt.wantTotal},
+			}
+			for _, a := range amounts {
+				if a.got.Amount != a.want || a.got.Currency != "RUB" {
+					t.Errorf("%s() = %v, want %d RUB", a.name, a.got, a.want)
+				}
+			}
+		})
+	}
+}
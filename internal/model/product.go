@@ -1,8 +1,32 @@
 package model
 
 type Product struct {
-	ProductId   string
+	ProductId string
+	// SKU is the stock keeping unit, empty if the product has none
+	SKU         string
 	Name        string
 	Weigth      float32
 	Description string
+	Price       Money
+	Stock       int
+	Reserved    int
+	CategoryId  string
+	Attributes  Attributes
+	Active      bool
+}
+
+// Attributes are free-form product properties. Values are JSON scalars:
+// strings, numbers or booleans.
+type Attributes map[string]any
+
+// ProductFilter narrows GetAll. Empty CategoryId matches all categories,
+// a category also matches its subcategories. Nil Active matches both.
+type ProductFilter struct {
+	CategoryId string
+	Active     *bool
+}
+
+// Available returns the stock that is not reserved by orders
+func (p *Product) Available() int {
+	return p.Stock - p.Reserved
 }
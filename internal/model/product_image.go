@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// ProductImage is a photo of a product. Both the original upload and its
+// thumbnail are kept in the blob store.
+type ProductImage struct {
+	ImageId    string
+	ProductId  string
+	Position   int
+	Width      int
+	Height     int
+	Original   BlobFile
+	Thumbnail  BlobFile
+	UploadedBy string
+	CreatedAt  time.Time
+}
+
+// BlobFile describes content kept in the blob store
+type BlobFile struct {
+	Key         string
+	ContentType string
+	Size        int64
+	SHA256      string
+}
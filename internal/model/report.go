@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+type ReportFormat string
+
+const (
+	ReportHTML ReportFormat = "html"
+	ReportPDF  ReportFormat = "pdf"
+)
+
+// ReportSchedule is a summary report produced automatically. The report
+// covers the Period before midnight of the day it runs, so a weekly report
+// running on Monday morning shows the previous Monday to Sunday.
+type ReportSchedule struct {
+	Name    string
+	Cron    string
+	Period  Grouping
+	Formats []ReportFormat
+	// To receives the report by email with the formats attached
+	To []string
+	// Directory receives the report files
+	Directory string
+}
+
+// ReportRun is the delivery of a scheduled report due at DueAt. Attempts
+// counts the deliveries started, only the latest may record the outcome.
+type ReportRun struct {
+	Name     string
+	DueAt    time.Time
+	Attempts int
+}
+
+// Report summarizes the orders of a period. Counts by status and new orders
+// cover the orders created in the period, completions the orders first
+// completed in it. Overdue orders are listed as of GeneratedAt.
+type Report struct {
+	Name string
+	// From is the first instant of the period, To the first after it
+	From        time.Time
+	To          time.Time
+	GeneratedAt time.Time
+
+	Statuses        []StatusCount
+	NewOrders       int
+	Completed       int
+	CompletedWeight float64
+
+	// OverdueAfter is how long an order may stay open before it is overdue
+	OverdueAfter time.Duration
+	OverdueTotal int
+	// Overdue lists the oldest overdue orders, at most MaxReportOverdue
+	Overdue []*OverdueOrder
+}
+
+// MaxReportOverdue limits the overdue orders listed in a report
+const MaxReportOverdue = 50
+
+// OverdueOrder is an order still in consideration or at work long after it
+// was created
+type OverdueOrder struct {
+	OrderId   string
+	Status    OrderStatus
+	CreatedAt time.Time
+	Phone     string
+	Email     string
+	// Username of the assignee, empty when unassigned
+	Username string
+}
@@ -0,0 +1,31 @@
+package model
+
+type SearchKind string
+
+const (
+	SearchOrder    SearchKind = "order"
+	SearchCustomer SearchKind = "customer"
+	SearchProduct  SearchKind = "product"
+)
+
+// SearchQuery describes a full-text search. Every word of Text has to
+// match the beginning of a word in a document.
+type SearchQuery struct {
+	Text string
+	// UserId restricts orders and customers to the orders assigned to this
+	// user. Empty searches everything.
+	UserId string
+	// IncludeInactive also returns discontinued products
+	IncludeInactive bool
+	Limit           int
+}
+
+// SearchHit is a matching order, customer or product. Hits of all kinds
+// are ranked against each other, a partial phone match outranks text.
+type SearchHit struct {
+	Kind     SearchKind
+	Id       string
+	Title    string
+	Subtitle string
+	Rank     float64
+}
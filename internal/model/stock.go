@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+type StockMovementKind string
+
+const (
+	// StockAdjusted is a manual correction or a receipt of goods
+	StockAdjusted StockMovementKind = "adjust"
+	// StockReserved is held for an order moved to AtWork
+	StockReserved StockMovementKind = "reserve"
+	// StockReleased returns a reservation, e.g. when the order is rejected
+	StockReleased StockMovementKind = "release"
+	// StockDeducted leaves the warehouse with a completed order
+	StockDeducted StockMovementKind = "deduct"
+	// StockReturned comes back when a completed order is reopened
+	StockReturned StockMovementKind = "return"
+)
+
+type StockMovement struct {
+	MovementId string
+	ProductId  string
+	OrderId    string
+	UserId     string
+	Kind       StockMovementKind
+	// Quantity is signed for adjustments and positive for order movements,
+	// the direction follows from Kind
+	Quantity  int
+	Note      string
+	CreatedAt time.Time
+}
+
+type StockAdjustment struct {
+	ProductId string
+	UserId    string
+	// Quantity is added to the stock, negative values write stock off
+	Quantity int
+	Note     string
+}
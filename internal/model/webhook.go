@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// WebhookEvent is an order event other systems can subscribe to
+type WebhookEvent string
+
+// Webhook events are the domain events of the same type
+const (
+	WebhookOrderCreated       = WebhookEvent(EventOrderCreated)
+	WebhookOrderStatusChanged = WebhookEvent(EventOrderStatusChanged)
+)
+
+// WebhookEvents lists the events in the order they are documented
+var WebhookEvents = []WebhookEvent{WebhookOrderCreated, WebhookOrderStatusChanged}
+
+// Webhook is a subscription of an external system to order events
+type Webhook struct {
+	WebhookId string
+	URL       string
+	Events    []WebhookEvent
+	// Secret signs the requests, see package webhook
+	Secret      string
+	Description string
+	// Active webhooks get deliveries, inactive ones keep their log
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribed reports whether the webhook receives the event
+func (w *Webhook) Subscribed(event WebhookEvent) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// WebhookDelivery is an event queued for a webhook, like OutboxEmail. It
+// keeps the outcome of its last attempt.
+type WebhookDelivery struct {
+	DeliveryId string
+	WebhookId  string
+	// EventId is shared by the deliveries of one event to every webhook
+	// and by redeliveries, receivers use it to drop duplicates
+	EventId string
+	Event   WebhookEvent
+	OrderId string
+	// Data holds the event details, e.g. the previous status
+	Data json.RawMessage
+	// RedeliveryOf is the delivery this one repeats, empty for the first
+	RedeliveryOf string
+	Status       DeliveryStatus
+	// Attempts counts the deliveries started, including a running one
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	// Response is nil until a request got an answer
+	Response  *WebhookResponse
+	SentAt    time.Time
+	CreatedAt time.Time
+}
+
+// WebhookResponse is what the receiver answered to the last attempt
+type WebhookResponse struct {
+	Status int
+	// Body is cut to the first kilobyte
+	Body     string
+	Duration time.Duration
+}
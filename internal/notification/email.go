@@ -0,0 +1,94 @@
+// Package notification renders the messages customers receive about their
+// orders.
+package notification
+
+import (
+	"backend_crm/internal/mail"
+	"backend_crm/internal/model"
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"strings"
+	textTemplate "text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	htmlTemplates = htmlTemplate.Must(htmlTemplate.ParseFS(templates, "templates/*.html"))
+	textTemplates = textTemplate.Must(textTemplate.ParseFS(templates, "templates/*.txt"))
+	smsTemplates  = textTemplate.Must(textTemplate.ParseFS(templates, "templates/sms/*.txt"))
+)
+
+var subjects = map[model.OrderEvent]string{
+	model.OrderReceived:  "Order %s received",
+	model.OrderAtWork:    "Order %s is being processed",
+	model.OrderCompleted: "Order %s is complete",
+	model.OrderRejected:  "Order %s was rejected",
+}
+
+// templateData is what the email and SMS templates see
+type templateData struct {
+	Subject string
+	Company string
+	Number  string
+	Items   []itemData
+	Total   string
+}
+
+type itemData struct {
+	Name     string
+	Quantity int
+	Total    string
+}
+
+// Number is the short order number shown to customers, the first group of
+// the order id in upper case
+func Number(orderId string) string {
+	number, _, _ := strings.Cut(orderId, "-")
+	return strings.ToUpper(number)
+}
+
+func newTemplateData(order *model.Order, company string) templateData {
+	data := templateData{
+		Company: company,
+		Number:  Number(order.OrderId),
+		Total:   order.Total().String(),
+	}
+	for _, item := range order.Items {
+		data.Items = append(data.Items, itemData{
+			Name:     item.Product.Name,
+			Quantity: item.Quantity,
+			Total:    model.Money{Amount: item.Total(), Currency: order.Currency}.String(),
+		})
+	}
+	return data
+}
+
+// Email renders the notification about event for the order. The recipient
+// is left to the caller.
+func Email(event model.OrderEvent, order *model.Order, company string) (*mail.Message, error) {
+	subject, ok := subjects[event]
+	if !ok {
+		return nil, fmt.Errorf("unknown order event %q", event)
+	}
+
+	data := newTemplateData(order, company)
+	data.Subject = fmt.Sprintf(subject, data.Number)
+
+	var html, text bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, string(event)+".html", data); err != nil {
+		return nil, err
+	}
+	if err := textTemplates.ExecuteTemplate(&text, string(event)+".txt", data); err != nil {
+		return nil, err
+	}
+
+	return &mail.Message{
+		Subject: data.Subject,
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
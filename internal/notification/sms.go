@@ -0,0 +1,21 @@
+package notification
+
+import (
+	"backend_crm/internal/model"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SMS renders the text message about event for the order
+func SMS(event model.OrderEvent, order *model.Order, company string) (string, error) {
+	if _, ok := subjects[event]; !ok {
+		return "", fmt.Errorf("unknown order event %q", event)
+	}
+
+	var text bytes.Buffer
+	if err := smsTemplates.ExecuteTemplate(&text, string(event)+".txt", newTemplateData(order, company)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text.String()), nil
+}
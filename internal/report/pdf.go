@@ -0,0 +1,196 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A4 in points
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	pageMargin = 50.0
+	lineFactor = 1.4
+)
+
+// pdfDocument lays out lines of text and table rows top to bottom on A4
+// pages. It uses the standard Helvetica fonts so nothing has to be
+// embedded. Those fonts cover Western European text only: Cyrillic is
+// transliterated, other characters print as '?'.
+type pdfDocument struct {
+	title string
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	// y is the baseline of the next line, from the bottom of the page
+	y float64
+}
+
+func newPDFDocument(title string) *pdfDocument {
+	return &pdfDocument{title: title}
+}
+
+// text writes a single line, cut to the page width
+func (d *pdfDocument) text(s string, size float64, bold bool) {
+	d.reserve(size * lineFactor)
+	d.show(pageMargin, d.y, fit(s, pageWidth-2*pageMargin, size), size, bold)
+	d.y -= size * lineFactor
+}
+
+// row writes table cells in columns of the given widths in points. A bold
+// row is underlined, as a table header.
+func (d *pdfDocument) row(cells []string, widths []float64, size float64, bold bool) {
+	d.reserve(size * lineFactor)
+	x := pageMargin
+	for i, cell := range cells {
+		d.show(x, d.y, fit(cell, widths[i]-4, size), size, bold)
+		x += widths[i]
+	}
+	if bold {
+		fmt.Fprintf(d.page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pageMargin, d.y-size-3, x, d.y-size-3)
+	}
+	d.y -= size * lineFactor
+}
+
+func (d *pdfDocument) space(height float64) {
+	d.y -= height
+}
+
+// reserve starts a new page if the next line does not fit
+func (d *pdfDocument) reserve(height float64) {
+	if d.page == nil || d.y-height < pageMargin {
+		d.page = &bytes.Buffer{}
+		d.pages = append(d.pages, d.page)
+		d.y = pageHeight - pageMargin
+	}
+}
+
+func (d *pdfDocument) show(x, y float64, s string, size float64, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y-size, pdfString(s))
+}
+
+// bytes assembles the PDF file: catalog, page tree, the two fonts, then a
+// page object and a content stream per page
+func (d *pdfDocument) bytes() ([]byte, error) {
+	d.reserve(0)
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 6
+	kids := make([]string, 0, len(d.pages))
+	for i := range d.pages {
+		kids = append(kids, strconv.Itoa(firstPage+2*i)+" 0 R")
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Title (" + pdfString(d.title) + ") /Producer (backend_crm) >>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+
+		var content bytes.Buffer
+		zw := zlib.NewWriter(&content)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes(), nil
+}
+
+// fit cuts s so that it fits into width points. Helvetica averages about
+// half the font size per character, which is close enough for tables.
+func fit(s string, width, size float64) string {
+	max := int(width / (size * 0.5))
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max <= 1 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
+
+// winAnsi maps the characters of WinAnsiEncoding outside Latin-1
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// pdfString encodes s for a literal string in WinAnsiEncoding
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if t, ok := cyrillic[unicode.ToLower(r)]; ok {
+			if unicode.IsUpper(r) && t != "" {
+				t = strings.ToUpper(t[:1]) + t[1:]
+			}
+			b.WriteString(t)
+			continue
+		}
+
+		var c byte
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			c = byte(r)
+		case r >= 0x20 && r < 0x7f:
+			c = byte(r)
+		case r >= 0xa0 && r <= 0xff:
+			c = byte(r)
+		default:
+			var ok bool
+			if c, ok = winAnsi[r]; !ok {
+				c = '?'
+			}
+		}
+		if c >= 0x80 {
+			fmt.Fprintf(&b, "\\%03o", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,164 @@
+// Package report renders order summaries as HTML and PDF documents.
+package report
+
+import (
+	"backend_crm/internal/model"
+	"bytes"
+	"embed"
+	"html/template"
+	"strconv"
+	"time"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04 MST"
+)
+
+//go:embed templates/report.html
+var templates embed.FS
+
+var htmlTemplate = template.Must(template.ParseFS(templates, "templates/report.html"))
+
+var statusNames = map[model.OrderStatus]string{
+	model.Consideration: "Consideration",
+	model.Refected:      "Rejected",
+	model.AtWork:        "At work",
+	model.Complete:      "Complete",
+}
+
+// view is the report with every value formatted for display. Times are
+// shown in the location they carry.
+type view struct {
+	Title           string
+	Period          string
+	Generated       string
+	Statuses        []statusView
+	NewOrders       int
+	Completed       int
+	CompletedWeight string
+	OverdueAfter    string
+	OverdueTotal    int
+	Overdue         []overdueView
+}
+
+type statusView struct {
+	Name   string
+	Orders int
+}
+
+type overdueView struct {
+	OrderId  string
+	Status   string
+	Created  string
+	Assignee string
+	Contact  string
+}
+
+func newView(r *model.Report) *view {
+	v := &view{
+		Title:           "Report " + r.Name,
+		Period:          period(r.From, r.To),
+		Generated:       r.GeneratedAt.Format(dateTimeLayout),
+		NewOrders:       r.NewOrders,
+		Completed:       r.Completed,
+		CompletedWeight: strconv.FormatFloat(r.CompletedWeight, 'f', 2, 64),
+		OverdueAfter:    duration(r.OverdueAfter),
+		OverdueTotal:    r.OverdueTotal,
+	}
+
+	for _, s := range r.Statuses {
+		v.Statuses = append(v.Statuses, statusView{Name: statusNames[s.Status], Orders: s.Orders})
+	}
+
+	for _, o := range r.Overdue {
+		contact := o.Phone
+		if contact == "" {
+			contact = o.Email
+		}
+		assignee := o.Username
+		if assignee == "" {
+			assignee = "-"
+		}
+		v.Overdue = append(v.Overdue, overdueView{
+			OrderId:  o.OrderId,
+			Status:   statusNames[o.Status],
+			Created:  o.CreatedAt.Format(dateLayout),
+			Assignee: assignee,
+			Contact:  contact,
+		})
+	}
+
+	return v
+}
+
+// period shows the range by its first and last day
+func period(from, to time.Time) string {
+	last := to.Add(-time.Nanosecond)
+	if from.Format(dateLayout) == last.Format(dateLayout) {
+		return from.Format(dateLayout)
+	}
+	return from.Format(dateLayout) + " to " + last.Format(dateLayout)
+}
+
+// duration shows whole days where possible, e.g. "3 days" for 72h
+func duration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		days := int(d / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return strconv.Itoa(days) + " days"
+	}
+	return d.String()
+}
+
+// HTML renders the report as a standalone HTML page that also works as an
+// email body
+func HTML(r *model.Report) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, newView(r)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PDF renders the report as an A4 PDF document
+func PDF(r *model.Report) ([]byte, error) {
+	v := newView(r)
+	doc := newPDFDocument(v.Title)
+
+	doc.text(v.Title, 18, true)
+	doc.text(v.Period, 10, false)
+	doc.text("Generated "+v.Generated, 10, false)
+	doc.space(12)
+
+	doc.text("Orders", 13, true)
+	widths := []float64{160, 80}
+	doc.row([]string{"Status", "Orders"}, widths, 10, true)
+	for _, s := range v.Statuses {
+		doc.row([]string{s.Name, strconv.Itoa(s.Orders)}, widths, 10, false)
+	}
+	doc.row([]string{"New orders", strconv.Itoa(v.NewOrders)}, widths, 10, false)
+	doc.space(12)
+
+	doc.text("Completed", 13, true)
+	doc.text(strconv.Itoa(v.Completed)+" orders completed, "+v.CompletedWeight+" kg in total.", 10, false)
+	doc.space(12)
+
+	doc.text("Overdue orders", 13, true)
+	summary := strconv.Itoa(v.OverdueTotal) + " orders open for more than " + v.OverdueAfter + "."
+	if v.OverdueTotal > len(v.Overdue) {
+		summary += " The oldest " + strconv.Itoa(len(v.Overdue)) + " are listed."
+	}
+	doc.text(summary, 10, false)
+	if len(v.Overdue) > 0 {
+		widths := []float64{150, 80, 70, 90, 105}
+		doc.row([]string{"Order", "Status", "Created", "Assignee", "Contact"}, widths, 9, true)
+		for _, o := range v.Overdue {
+			doc.row([]string{o.OrderId, o.Status, o.Created, o.Assignee, o.Contact}, widths, 9, false)
+		}
+	}
+
+	return doc.bytes()
+}
@@ -0,0 +1,37 @@
+package analytics
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"time"
+)
+
+// Repository computes dashboard numbers with SQL aggregates. Orders count
+// in the range they were created in, completions and rejections in the
+// range the status change was recorded in the order history.
+type Repository interface {
+	// OrdersByStatus counts the orders created in the range by their
+	// current status, every status is present
+	OrdersByStatus(ctx context.Context, r model.AnalyticsRange) ([]model.StatusCount, error)
+	// NewOrders counts the orders created per period, periods without
+	// orders included
+	NewOrders(ctx context.Context, r model.AnalyticsRange) ([]model.PeriodCount, error)
+	// CompletionTime measures orders first completed in the range, in total
+	// and per period of the completion
+	CompletionTime(ctx context.Context, r model.AnalyticsRange) (*model.CompletionTime, []model.PeriodCompletionTime, error)
+	// RejectionRate counts the orders created in the range, in total and
+	// per period of creation
+	RejectionRate(ctx context.Context, r model.AnalyticsRange) (*model.RejectionRate, []model.PeriodRejectionRate, error)
+	// TopProducts ranks the products of orders created in the range that
+	// were not rejected
+	TopProducts(ctx context.Context, r model.AnalyticsRange, by model.ProductRanking, limit int) ([]*model.ProductStats, error)
+	// Employees lists the users with open orders or status changes in the
+	// range, most completions first
+	Employees(ctx context.Context, r model.AnalyticsRange) ([]*model.EmployeeStats, error)
+	// Completed counts the orders first completed in the range and their
+	// total weight in kg
+	Completed(ctx context.Context, r model.AnalyticsRange) (int, float64, error)
+	// Overdue returns the oldest orders created before createdBefore that
+	// are still in consideration or at work, and how many there are
+	Overdue(ctx context.Context, createdBefore time.Time, limit int) ([]*model.OverdueOrder, int, error)
+}
@@ -0,0 +1,413 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/analytics"
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// periods lists the start of every period of the range, $1 to $2 grouped
+// by $3. Timestamps are truncated in UTC.
+const periods = `
+	periods AS (
+		SELECT generate_series(
+			date_trunc($3, $1::timestamptz AT TIME ZONE 'UTC'),
+			($2::timestamptz AT TIME ZONE 'UTC') - interval '1 microsecond',
+			('1 ' || $3)::interval
+		) AS period
+	)`
+
+// completions has the first completion of every order with the seconds it
+// took since the order was created. field and status are the placeholders
+// of the status field and the complete status as stored in the history.
+func completions(field, status string) string {
+	return `
+	completions AS (
+		SELECT o.order_id, min(h.created_at) AS at,
+			   extract(epoch FROM min(h.created_at) - o.created_at) AS seconds
+		FROM order_history h
+		JOIN orders o ON o.order_id = h.order_id
+		WHERE h.field = ` + field + ` AND h.new_value = ` + status + `
+		GROUP BY o.order_id, o.created_at
+	)`
+}
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) analytics.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) OrdersByStatus(ctx context.Context, rng model.AnalyticsRange) ([]model.StatusCount, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT status, count(*)
+		FROM orders
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY status
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, rng.From, rng.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[model.OrderStatus]int)
+	for rows.Next() {
+		var status model.OrderStatus
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.StatusCount, 0, model.Complete+1)
+	for status := model.OrderStatus(model.Consideration); status <= model.Complete; status++ {
+		result = append(result, model.StatusCount{Status: status, Orders: counts[status]})
+	}
+
+	return result, nil
+}
+
+func (r *repository) NewOrders(ctx context.Context, rng model.AnalyticsRange) ([]model.PeriodCount, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH ` + periods + `
+		SELECT p.period, count(o.order_id)
+		FROM periods p
+		LEFT JOIN orders o ON o.created_at >= $1 AND o.created_at < $2
+			AND date_trunc($3, o.created_at AT TIME ZONE 'UTC') = p.period
+		GROUP BY p.period
+		ORDER BY p.period
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, rng.From, rng.To, rng.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.PeriodCount
+	for rows.Next() {
+		var count model.PeriodCount
+		if err := rows.Scan(&count.Period, &count.Orders); err != nil {
+			return nil, err
+		}
+		count.Period = count.Period.UTC()
+		result = append(result, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) CompletionTime(ctx context.Context, rng model.AnalyticsRange) (*model.CompletionTime, []model.PeriodCompletionTime, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// The grouping set () adds the row with the totals, its period is NULL.
+	// Every completion of the range falls into exactly one period.
+	rows, err := r.db.QueryContext(ctx, `
+		WITH `+periods+`, `+completions("$4", "$5")+`
+		SELECT p.period, count(c.seconds), COALESCE(avg(c.seconds), 0),
+			   COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY c.seconds), 0)
+		FROM periods p
+		LEFT JOIN completions c ON c.at >= $1 AND c.at < $2
+			AND date_trunc($3, c.at AT TIME ZONE 'UTC') = p.period
+		GROUP BY GROUPING SETS ((p.period), ())
+		ORDER BY p.period NULLS FIRST
+	`, rng.From, rng.To, rng.GroupBy, model.OrderFieldStatus, strconv.Itoa(model.Complete))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var total model.CompletionTime
+	var result []model.PeriodCompletionTime
+	for rows.Next() {
+		var period sql.NullTime
+		var stats model.CompletionTime
+		var average, median float64
+		if err := rows.Scan(&period, &stats.Orders, &average, &median); err != nil {
+			return nil, nil, err
+		}
+		stats.Average = seconds(average)
+		stats.Median = seconds(median)
+
+		if !period.Valid {
+			total = stats
+			continue
+		}
+		result = append(result, model.PeriodCompletionTime{Period: period.Time.UTC(), CompletionTime: stats})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return &total, result, nil
+}
+
+func (r *repository) RejectionRate(ctx context.Context, rng model.AnalyticsRange) (*model.RejectionRate, []model.PeriodRejectionRate, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH `+periods+`
+		SELECT p.period, count(o.order_id),
+			   count(o.order_id) FILTER (WHERE o.status = $4),
+			   count(o.order_id) FILTER (WHERE o.status = $5)
+		FROM periods p
+		LEFT JOIN orders o ON o.created_at >= $1 AND o.created_at < $2
+			AND date_trunc($3, o.created_at AT TIME ZONE 'UTC') = p.period
+		GROUP BY p.period
+		ORDER BY p.period
+	`, rng.From, rng.To, rng.GroupBy, model.Refected, model.Complete)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// Every order of the range falls into exactly one period
+	var total model.RejectionRate
+	var result []model.PeriodRejectionRate
+	for rows.Next() {
+		var period model.PeriodRejectionRate
+		if err := rows.Scan(&period.Period, &period.Orders, &period.Rejected, &period.Completed); err != nil {
+			return nil, nil, err
+		}
+		period.Period = period.Period.UTC()
+		period.Rate = rejectionRate(period.Rejected, period.Completed)
+		result = append(result, period)
+
+		total.Orders += period.Orders
+		total.Rejected += period.Rejected
+		total.Completed += period.Completed
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	total.Rate = rejectionRate(total.Rejected, total.Completed)
+
+	return &total, result, nil
+}
+
+func (r *repository) TopProducts(ctx context.Context, rng model.AnalyticsRange, by model.ProductRanking, limit int) ([]*model.ProductStats, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	orderBy := "orders DESC, weight DESC"
+	if by == model.RankByWeight {
+		orderBy = "weight DESC, orders DESC"
+	}
+
+	query := `
+		SELECT p.product_id, p.name, count(DISTINCT i.order_id) AS orders,
+			   sum(i.quantity) AS quantity, sum(i.quantity * i.unit_weight) AS weight
+		FROM order_items i
+		JOIN orders o ON o.order_id = i.order_id
+		JOIN products p ON p.product_id = i.product_id
+		WHERE o.created_at >= $1 AND o.created_at < $2 AND o.status <> $3
+		GROUP BY p.product_id, p.name
+		ORDER BY ` + orderBy + `, p.name
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, rng.From, rng.To, model.Refected, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.ProductStats
+	for rows.Next() {
+		var stats model.ProductStats
+		if err := rows.Scan(&stats.ProductId, &stats.Name, &stats.Orders, &stats.Quantity, &stats.Weight); err != nil {
+			return nil, err
+		}
+		result = append(result, &stats)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Employees(ctx context.Context, rng model.AnalyticsRange) ([]*model.EmployeeStats, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH decided AS (
+			SELECT h.user_id, h.new_value, extract(epoch FROM h.created_at - o.created_at) AS seconds
+			FROM order_history h
+			JOIN orders o ON o.order_id = h.order_id
+			WHERE h.field = $3 AND h.new_value IN ($4, $5) AND h.user_id IS NOT NULL
+			  AND h.created_at >= $1 AND h.created_at < $2
+		),
+		open AS (
+			SELECT user_id, count(*) AS orders
+			FROM orders
+			WHERE user_id IS NOT NULL AND status IN ($6, $7)
+			GROUP BY user_id
+		)
+		SELECT u.user_id, u.username, COALESCE(open.orders, 0),
+			   count(d.user_id) FILTER (WHERE d.new_value = $4) AS completed,
+			   count(d.user_id) FILTER (WHERE d.new_value = $5),
+			   COALESCE(avg(d.seconds) FILTER (WHERE d.new_value = $4), 0)
+		FROM users u
+		LEFT JOIN open ON open.user_id = u.user_id
+		LEFT JOIN decided d ON d.user_id = u.user_id
+		GROUP BY u.user_id, u.username, open.orders
+		HAVING open.orders IS NOT NULL OR count(d.user_id) > 0
+		ORDER BY completed DESC, u.username
+	`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		rng.From,
+		rng.To,
+		model.OrderFieldStatus,
+		strconv.Itoa(model.Complete),
+		strconv.Itoa(model.Refected),
+		model.Consideration,
+		model.AtWork,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.EmployeeStats
+	for rows.Next() {
+		var stats model.EmployeeStats
+		var average float64
+		err := rows.Scan(
+			&stats.UserId,
+			&stats.Username,
+			&stats.Open,
+			&stats.Completed,
+			&stats.Rejected,
+			&average,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats.AverageCompletion = seconds(average)
+		result = append(result, &stats)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Completed(ctx context.Context, rng model.AnalyticsRange) (int, float64, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH ` + completions("$3", "$4") + `
+		SELECT count(DISTINCT c.order_id), COALESCE(sum(i.quantity * i.unit_weight), 0)
+		FROM completions c
+		JOIN order_items i ON i.order_id = c.order_id
+		WHERE c.at >= $1 AND c.at < $2
+	`
+
+	var orders int
+	var weight float64
+	err := r.db.QueryRowContext(ctx, query,
+		rng.From,
+		rng.To,
+		model.OrderFieldStatus,
+		strconv.Itoa(model.Complete),
+	).Scan(&orders, &weight)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return orders, weight, nil
+}
+
+func (r *repository) Overdue(ctx context.Context, createdBefore time.Time, limit int) ([]*model.OverdueOrder, int, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT o.order_id, o.status, o.created_at, o.phone, o.email, COALESCE(u.username, ''),
+			   count(*) OVER ()
+		FROM orders o
+		LEFT JOIN users u ON u.user_id = o.user_id
+		WHERE o.status IN ($2, $3) AND o.created_at < $1
+		ORDER BY o.created_at, o.order_id
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, createdBefore, model.Consideration, model.AtWork, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var result []*model.OverdueOrder
+	var total int
+	for rows.Next() {
+		var order model.OverdueOrder
+		err := rows.Scan(
+			&order.OrderId,
+			&order.Status,
+			&order.CreatedAt,
+			&order.Phone,
+			&order.Email,
+			&order.Username,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, &order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Second)
+}
+
+func rejectionRate(rejected, completed int) float64 {
+	if rejected+completed == 0 {
+		return 0
+	}
+	return float64(rejected) / float64(rejected+completed)
+}
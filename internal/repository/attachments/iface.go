@@ -0,0 +1,22 @@
+package attachments
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFoundAttachment = errors.New("not found attachment")
+	ErrNotFoundOrder      = errors.New("not found order")
+)
+
+type Repository interface {
+	Save(ctx context.Context, attachment *model.OrderAttachment) error
+	// GetByOrderId returns the attachments of an order, oldest first
+	GetByOrderId(ctx context.Context, orderId string) ([]*model.OrderAttachment, error)
+	GetById(ctx context.Context, orderId string, attachmentId string) (*model.OrderAttachment, error)
+	// Delete removes the attachment row and returns it so the caller can
+	// clean up the blob
+	Delete(ctx context.Context, orderId string, attachmentId string) (*model.OrderAttachment, error)
+}
@@ -0,0 +1,155 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/attachments"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) attachments.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+const attachmentColumns = `
+	a.attachment_id, a.order_id, a.filename, a.blob_key, a.content_type, a.size, a.sha256,
+	a.uploaded_by, u.username, a.created_at`
+
+func (r *repository) Save(ctx context.Context, attachment *model.OrderAttachment) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO order_attachments (order_id, filename, blob_key, content_type, size, sha256, uploaded_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING attachment_id, uploaded_by, created_at
+		)
+		SELECT i.attachment_id, u.username, i.created_at
+		FROM inserted i
+		JOIN users u ON u.user_id = i.uploaded_by
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		attachment.OrderId,
+		attachment.Filename,
+		attachment.File.Key,
+		attachment.File.ContentType,
+		attachment.File.Size,
+		attachment.File.SHA256,
+		attachment.UploadedBy,
+	).Scan(&attachment.AttachmentId, &attachment.Username, &attachment.CreatedAt)
+
+	if database.IsForeignKeyViolation(err) {
+		return attachments.ErrNotFoundOrder
+	}
+
+	return err
+}
+
+func (r *repository) GetByOrderId(ctx context.Context, orderId string) ([]*model.OrderAttachment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `SELECT ` + attachmentColumns + `
+		FROM order_attachments a
+		JOIN users u ON u.user_id = a.uploaded_by
+		WHERE a.order_id = $1
+		ORDER BY a.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.OrderAttachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) GetById(ctx context.Context, orderId string, attachmentId string) (*model.OrderAttachment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `SELECT ` + attachmentColumns + `
+		FROM order_attachments a
+		JOIN users u ON u.user_id = a.uploaded_by
+		WHERE a.order_id = $1 AND a.attachment_id = $2`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, orderId, attachmentId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, attachments.ErrNotFoundAttachment
+	}
+
+	return attachment, err
+}
+
+func (r *repository) Delete(ctx context.Context, orderId string, attachmentId string) (*model.OrderAttachment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		WITH a AS (
+			DELETE FROM order_attachments
+			WHERE order_id = $1 AND attachment_id = $2
+			RETURNING *
+		)
+		SELECT ` + attachmentColumns + `
+		FROM a
+		JOIN users u ON u.user_id = a.uploaded_by`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, orderId, attachmentId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, attachments.ErrNotFoundAttachment
+	}
+
+	return attachment, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttachment(row scanner) (*model.OrderAttachment, error) {
+	var attachment model.OrderAttachment
+	err := row.Scan(
+		&attachment.AttachmentId,
+		&attachment.OrderId,
+		&attachment.Filename,
+		&attachment.File.Key,
+		&attachment.File.ContentType,
+		&attachment.File.Size,
+		&attachment.File.SHA256,
+		&attachment.UploadedBy,
+		&attachment.Username,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
@@ -0,0 +1,24 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+)
+
+// MaxMessageSize is the largest message Postgres passes on, in bytes
+const MaxMessageSize = 7999
+
+var ErrTooLarge = errors.New("broadcast message too large")
+
+// Repository passes messages between the server instances through Postgres
+// LISTEN/NOTIFY. Messages are not stored, an instance misses the ones sent
+// while it is not listening.
+type Repository interface {
+	// Publish sends the message to every listening instance, this one
+	// included
+	Publish(ctx context.Context, message []byte) error
+	// Listen hands the messages to handle until ctx is done. After the
+	// connection was lost and restored handle gets nil, as messages may
+	// have been missed.
+	Listen(ctx context.Context, handle func(message []byte)) error
+}
@@ -0,0 +1,90 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/repository/broadcast"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnect = time.Second
+	maxReconnect = 30 * time.Second
+	// pingInterval checks the listening connection, which is otherwise only
+	// read from
+	pingInterval = time.Minute
+)
+
+type repository struct {
+	db           *sql.DB
+	dsn          string
+	channel      string
+	queryTimeout time.Duration
+}
+
+// NewRepository sends the messages on the channel. Listening takes a
+// connection of its own, opened with dsn.
+func NewRepository(db *sql.DB, dsn string, channel string, queryTimeout time.Duration) broadcast.Repository {
+	return &repository{
+		db:           db,
+		dsn:          dsn,
+		channel:      channel,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Publish(ctx context.Context, message []byte) error {
+	if len(message) > broadcast.MaxMessageSize {
+		return broadcast.ErrTooLarge
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, r.channel, string(message))
+	return err
+}
+
+func (r *repository) Listen(ctx context.Context, handle func(message []byte)) error {
+	listener := pq.NewListener(r.dsn, minReconnect, maxReconnect, nil)
+	defer listener.Close()
+
+	// Listen blocks while the database cannot be reached
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	if err := listener.Listen(r.channel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification, ok := <-listener.NotificationChannel():
+			if !ok {
+				// Closed when ctx is done
+				return nil
+			}
+			if notification == nil {
+				handle(nil)
+				continue
+			}
+			handle([]byte(notification.Extra))
+		case <-ticker.C:
+			// A lost connection is restored by the listener
+			listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package categories
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFoundCategory = errors.New("not found category")
+	ErrCategoryCycle    = errors.New("category cannot be its own ancestor")
+)
+
+type Repository interface {
+	Save(ctx context.Context, category *model.Category) error
+	GetAll(ctx context.Context) ([]*model.Category, error)
+	Update(ctx context.Context, category *model.Category) error
+}
@@ -0,0 +1,137 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/categories"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) categories.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Save(ctx context.Context, category *model.Category) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO categories (parent_id, name)
+		VALUES (NULLIF($1, '')::uuid, $2)
+		RETURNING category_id
+	`
+
+	err := r.db.QueryRowContext(ctx, query, category.ParentId, category.Name).Scan(&category.CategoryId)
+	if database.IsForeignKeyViolation(err) {
+		return categories.ErrNotFoundCategory
+	}
+
+	return err
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]*model.Category, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT category_id, COALESCE(parent_id::text, ''), name
+		FROM categories
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.Category
+	for rows.Next() {
+		var category model.Category
+		err := rows.Scan(
+			&category.CategoryId,
+			&category.ParentId,
+			&category.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Update(ctx context.Context, category *model.Category) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if category.ParentId != "" {
+		// Moves are serialized, so that two of them cannot each pass the
+		// check below against a tree the other one is about to change
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('category-tree'))`); err != nil {
+			return fmt.Errorf("lock category tree: %w", err)
+		}
+
+		// The new parent must not be the category itself or one of its
+		// descendants, otherwise the tree turns into a loop
+		var cycle bool
+		err := tx.QueryRowContext(ctx, `
+			WITH RECURSIVE tree AS (
+				SELECT category_id FROM categories WHERE category_id = $1
+				UNION ALL
+				SELECT c.category_id FROM categories c JOIN tree t ON c.parent_id = t.category_id
+			)
+			SELECT EXISTS (SELECT 1 FROM tree WHERE category_id = $2)
+		`, category.CategoryId, category.ParentId).Scan(&cycle)
+		if err != nil {
+			return fmt.Errorf("check cycle: %w", err)
+		}
+		if cycle {
+			return categories.ErrCategoryCycle
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE categories
+		SET parent_id = NULLIF($1, '')::uuid, name = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE category_id = $3
+	`, category.ParentId, category.Name, category.CategoryId)
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return categories.ErrNotFoundCategory
+		}
+		return fmt.Errorf("update category: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return categories.ErrNotFoundCategory
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,25 @@
+package comments
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFoundComment = errors.New("not found comment")
+	ErrNotFoundOrder   = errors.New("not found order")
+	// ErrInvalidParent is returned when replying to a comment of another order
+	ErrInvalidParent = errors.New("parent comment belongs to another order")
+)
+
+type Repository interface {
+	// Save stores the comment and the users mentioned in its body
+	Save(ctx context.Context, comment *model.OrderComment) error
+	// GetByOrderId returns all comments of an order, oldest first
+	GetByOrderId(ctx context.Context, orderId string) ([]*model.OrderComment, error)
+	GetById(ctx context.Context, commentId string) (*model.OrderComment, error)
+	// Update replaces the body, keeping the previous one in the edit history
+	Update(ctx context.Context, commentId string, userId string, body string) error
+	GetEdits(ctx context.Context, commentId string) ([]*model.OrderCommentEdit, error)
+}
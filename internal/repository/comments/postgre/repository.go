@@ -0,0 +1,333 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/mention"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/comments"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) comments.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Save(ctx context.Context, comment *model.OrderComment) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM orders WHERE order_id = $1)`,
+		comment.OrderId,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("check order: %w", err)
+	}
+	if !exists {
+		return comments.ErrNotFoundOrder
+	}
+
+	if comment.ParentId != "" {
+		var parentOrderId string
+		err := tx.QueryRowContext(ctx,
+			`SELECT order_id FROM order_comments WHERE comment_id = $1`,
+			comment.ParentId,
+		).Scan(&parentOrderId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return comments.ErrNotFoundComment
+			}
+			return fmt.Errorf("get parent comment: %w", err)
+		}
+		if parentOrderId != comment.OrderId {
+			return comments.ErrInvalidParent
+		}
+	}
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO order_comments (order_id, parent_id, user_id, body)
+			VALUES ($1, NULLIF($2, '')::uuid, $3, $4)
+			RETURNING comment_id, user_id, created_at, updated_at
+		)
+		SELECT i.comment_id, u.username, i.created_at, i.updated_at
+		FROM inserted i
+		JOIN users u ON u.user_id = i.user_id
+	`
+
+	err = tx.QueryRowContext(ctx, query,
+		comment.OrderId,
+		comment.ParentId,
+		comment.UserId,
+		comment.Body,
+	).Scan(&comment.CommentId, &comment.Username, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert comment: %w", err)
+	}
+
+	comment.Mentions, err = saveMentions(ctx, tx, comment.CommentId, comment.Body)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) GetByOrderId(ctx context.Context, orderId string) ([]*model.OrderComment, error) {
+	return r.getCommentsByFilter(ctx, "c.order_id = $1", orderId)
+}
+
+func (r *repository) GetById(ctx context.Context, commentId string) (*model.OrderComment, error) {
+	found, err := r.getCommentsByFilter(ctx, "c.comment_id = $1", commentId)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, comments.ErrNotFoundComment
+	}
+
+	return found[0], nil
+}
+
+func (r *repository) Update(ctx context.Context, commentId string, userId string, body string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRowContext(ctx,
+		`SELECT body FROM order_comments WHERE comment_id = $1 FOR UPDATE`,
+		commentId,
+	).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return comments.ErrNotFoundComment
+		}
+		return fmt.Errorf("lock comment: %w", err)
+	}
+
+	if previous == body {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO order_comment_edits (comment_id, user_id, body)
+		VALUES ($1, $2, $3)
+	`, commentId, userId, previous); err != nil {
+		return fmt.Errorf("insert comment edit: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE order_comments
+		SET body = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE comment_id = $2
+	`, body, commentId); err != nil {
+		return fmt.Errorf("update comment: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM order_comment_mentions WHERE comment_id = $1`,
+		commentId,
+	); err != nil {
+		return fmt.Errorf("delete mentions: %w", err)
+	}
+	if _, err := saveMentions(ctx, tx, commentId, body); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) GetEdits(ctx context.Context, commentId string) ([]*model.OrderCommentEdit, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT edit_id, comment_id, user_id, body, edited_at
+		FROM order_comment_edits
+		WHERE comment_id = $1
+		ORDER BY edited_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, commentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.OrderCommentEdit
+	for rows.Next() {
+		var edit model.OrderCommentEdit
+		if err := rows.Scan(
+			&edit.EditId,
+			&edit.CommentId,
+			&edit.UserId,
+			&edit.Body,
+			&edit.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, &edit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) getCommentsByFilter(ctx context.Context, filter string, args ...interface{}) ([]*model.OrderComment, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT c.comment_id, c.order_id, COALESCE(c.parent_id::text, ''), c.user_id, u.username, c.body,
+			   EXISTS (SELECT 1 FROM order_comment_edits e WHERE e.comment_id = c.comment_id),
+			   c.created_at, c.updated_at
+		FROM order_comments c
+		JOIN users u ON u.user_id = c.user_id
+		WHERE ` + filter + `
+		ORDER BY c.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.OrderComment
+	for rows.Next() {
+		var comment model.OrderComment
+		if err := rows.Scan(
+			&comment.CommentId,
+			&comment.OrderId,
+			&comment.ParentId,
+			&comment.UserId,
+			&comment.Username,
+			&comment.Body,
+			&comment.Edited,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := r.loadMentions(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// loadMentions fetches the mentioned users of all given comments with a
+// single query
+func (r *repository) loadMentions(ctx context.Context, result []*model.OrderComment) error {
+	if len(result) == 0 {
+		return nil
+	}
+
+	byId := make(map[string]*model.OrderComment, len(result))
+	ids := make([]string, 0, len(result))
+	for _, comment := range result {
+		byId[comment.CommentId] = comment
+		ids = append(ids, comment.CommentId)
+	}
+
+	query := `
+		SELECT m.comment_id, u.user_id, u.username
+		FROM order_comment_mentions m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.comment_id = ANY($1::uuid[])
+		ORDER BY u.username
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("load mentions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentId string
+		var m model.Mention
+		if err := rows.Scan(&commentId, &m.UserId, &m.Username); err != nil {
+			return err
+		}
+		if comment, ok := byId[commentId]; ok {
+			comment.Mentions = append(comment.Mentions, m)
+		}
+	}
+
+	return rows.Err()
+}
+
+// saveMentions links the comment to the users mentioned in body. Unknown
+// usernames are ignored, they are just text.
+func saveMentions(ctx context.Context, tx *sql.Tx, commentId string, body string) ([]model.Mention, error) {
+	usernames := mention.Usernames(body)
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO order_comment_mentions (comment_id, user_id)
+			SELECT $1, user_id FROM users WHERE username = ANY($2)
+			RETURNING user_id
+		)
+		SELECT u.user_id, u.username
+		FROM inserted i
+		JOIN users u ON u.user_id = i.user_id
+		ORDER BY u.username
+	`
+
+	rows, err := tx.QueryContext(ctx, query, commentId, pq.Array(usernames))
+	if err != nil {
+		return nil, fmt.Errorf("insert mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var mentions []model.Mention
+	for rows.Next() {
+		var m model.Mention
+		if err := rows.Scan(&m.UserId, &m.Username); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+
+	return mentions, rows.Err()
+}
@@ -0,0 +1,26 @@
+package customers
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFoundCustomer = errors.New("not found customer")
+	ErrInvalidMerge     = errors.New("invalid merge")
+	ErrInvalidSplit     = errors.New("invalid split")
+)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]*model.Customer, error)
+	GetByUserId(ctx context.Context, userId string) ([]*model.Customer, error)
+	GetById(ctx context.Context, customerId string) (*model.Customer, error)
+	Update(ctx context.Context, customerId string, update *model.CustomerUpdate) error
+	// Merge moves all orders and contacts of the source customers to the
+	// target and deletes the sources.
+	Merge(ctx context.Context, merge *model.CustomerMerge) error
+	// Split moves the given orders to a new customer and returns its id
+	Split(ctx context.Context, split *model.CustomerSplit) (string, error)
+	GetAudit(ctx context.Context, customerId string) ([]*model.CustomerAudit, error)
+}
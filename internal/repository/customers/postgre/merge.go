@@ -0,0 +1,380 @@
+package postgre
+
+import (
+	"backend_crm/internal/contact"
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customers"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+func (r *repository) Merge(ctx context.Context, merge *model.CustomerMerge) error {
+	// A source given twice is merged once
+	sourceIds := uniq(merge.SourceIds)
+	if len(sourceIds) == 0 || slices.Contains(sourceIds, merge.TargetId) {
+		return customers.ErrInvalidMerge
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := append([]string{merge.TargetId}, sourceIds...)
+	locked, err := lockCustomers(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	if len(locked) != len(ids) {
+		return customers.ErrNotFoundCustomer
+	}
+
+	target := locked[merge.TargetId]
+	sources := make([]*model.Customer, 0, len(sourceIds))
+	for _, id := range sourceIds {
+		source := locked[id]
+		sources = append(sources, source)
+
+		if target.Name == "" {
+			target.Name = source.Name
+		}
+		target.Phones = union(target.Phones, source.Phones)
+		target.Emails = union(target.Emails, source.Emails)
+		target.Tags = union(target.Tags, source.Tags)
+		if source.Notes != "" {
+			target.Notes = strings.TrimSpace(target.Notes + "\n\n" + source.Notes)
+		}
+	}
+
+	var movedOrders []string
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE orders
+		SET customer_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE customer_id = ANY($2::uuid[])
+		RETURNING order_id
+	`, merge.TargetId, pq.Array(sourceIds))
+	if err != nil {
+		return fmt.Errorf("move orders: %w", err)
+	}
+	for rows.Next() {
+		var orderId string
+		if err := rows.Scan(&orderId); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan order id: %w", err)
+		}
+		movedOrders = append(movedOrders, orderId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("move orders: %w", err)
+	}
+
+	if err := saveCustomer(ctx, tx, target); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM customers WHERE customer_id = ANY($1::uuid[])`,
+		pq.Array(sourceIds),
+	); err != nil {
+		return fmt.Errorf("delete sources: %w", err)
+	}
+
+	if err := writeAudit(ctx, tx, merge.TargetId, model.CustomerMerged, merge.UserId, map[string]any{
+		"sources": snapshots(sources),
+		"orders":  nonNil(movedOrders),
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) Split(ctx context.Context, split *model.CustomerSplit) (string, error) {
+	if len(split.OrderIds) == 0 {
+		return "", customers.ErrInvalidSplit
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockCustomers(ctx, tx, []string{split.CustomerId})
+	if err != nil {
+		return "", err
+	}
+	original, ok := locked[split.CustomerId]
+	if !ok {
+		return "", customers.ErrNotFoundCustomer
+	}
+
+	var remaining, selected int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE order_id <> ALL($2::uuid[])),
+		       COUNT(*) FILTER (WHERE order_id = ANY($2::uuid[]))
+		FROM orders
+		WHERE customer_id = $1
+	`, split.CustomerId, pq.Array(split.OrderIds)).Scan(&remaining, &selected); err != nil {
+		return "", fmt.Errorf("count orders: %w", err)
+	}
+
+	// Every selected order must belong to the customer and at least one
+	// order has to stay, otherwise this is a rename, not a split.
+	if selected != len(uniq(split.OrderIds)) || remaining == 0 {
+		return "", customers.ErrInvalidSplit
+	}
+
+	var newId string
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO customers (name) VALUES ($1) RETURNING customer_id`,
+		split.Name,
+	).Scan(&newId); err != nil {
+		return "", fmt.Errorf("insert customer: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE orders
+		SET customer_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE customer_id = $2 AND order_id = ANY($3::uuid[])
+	`, newId, split.CustomerId, pq.Array(split.OrderIds)); err != nil {
+		return "", fmt.Errorf("move orders: %w", err)
+	}
+
+	// Contacts of both customers are rebuilt from the orders they now own
+	before := snapshot(original)
+	for _, id := range []string{split.CustomerId, newId} {
+		if err := rebuildContacts(ctx, tx, id); err != nil {
+			return "", err
+		}
+	}
+
+	details := map[string]any{
+		"from":   before,
+		"to":     newId,
+		"orders": uniq(split.OrderIds),
+	}
+	if err := writeAudit(ctx, tx, split.CustomerId, model.CustomerSplitOff, split.UserId, details); err != nil {
+		return "", err
+	}
+	if err := writeAudit(ctx, tx, newId, model.CustomerSplitOff, split.UserId, details); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return newId, nil
+}
+
+func (r *repository) GetAudit(ctx context.Context, customerId string) ([]*model.CustomerAudit, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT audit_id, customer_id, action, COALESCE(user_id::text, ''), details, created_at
+		FROM customer_audit
+		WHERE customer_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, customerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*model.CustomerAudit
+	for rows.Next() {
+		var entry model.CustomerAudit
+		err := rows.Scan(
+			&entry.AuditId,
+			&entry.CustomerId,
+			&entry.Action,
+			&entry.UserId,
+			&entry.Details,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// lockCustomers selects the customers FOR UPDATE in a stable order so that
+// concurrent merges cannot deadlock.
+func lockCustomers(ctx context.Context, tx *sql.Tx, ids []string) (map[string]*model.Customer, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT customer_id, name, phones, emails, notes, tags
+		FROM customers
+		WHERE customer_id = ANY($1::uuid[])
+		ORDER BY customer_id
+		FOR UPDATE
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("lock customers: %w", err)
+	}
+	defer rows.Close()
+
+	locked := make(map[string]*model.Customer, len(ids))
+	for rows.Next() {
+		var customer model.Customer
+		err := rows.Scan(
+			&customer.CustomerId,
+			&customer.Name,
+			pq.Array(&customer.Phones),
+			pq.Array(&customer.Emails),
+			&customer.Notes,
+			pq.Array(&customer.Tags),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan customer: %w", err)
+		}
+		locked[customer.CustomerId] = &customer
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lock customers: %w", err)
+	}
+
+	return locked, nil
+}
+
+func saveCustomer(ctx context.Context, tx *sql.Tx, customer *model.Customer) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE customers
+		SET name = $1, phones = $2, emails = $3, notes = $4, tags = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE customer_id = $6
+	`,
+		customer.Name,
+		pq.Array(nonNil(customer.Phones)),
+		pq.Array(nonNil(customer.Emails)),
+		customer.Notes,
+		pq.Array(nonNil(customer.Tags)),
+		customer.CustomerId,
+	)
+	if err != nil {
+		return fmt.Errorf("update customer: %w", err)
+	}
+
+	return nil
+}
+
+func rebuildContacts(ctx context.Context, tx *sql.Tx, customerId string) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT phone, email FROM orders WHERE customer_id = $1 ORDER BY created_at`,
+		customerId,
+	)
+	if err != nil {
+		return fmt.Errorf("select contacts: %w", err)
+	}
+
+	var phones, emails []string
+	for rows.Next() {
+		var phone, email string
+		if err := rows.Scan(&phone, &email); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan contacts: %w", err)
+		}
+		if p := contact.NormalizePhone(phone); p != "" {
+			phones = union(phones, []string{p})
+		}
+		if e := contact.NormalizeEmail(email); e != "" {
+			emails = union(emails, []string{e})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("select contacts: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE customers
+		SET phones = $1, emails = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE customer_id = $3
+	`, pq.Array(nonNil(phones)), pq.Array(nonNil(emails)), customerId); err != nil {
+		return fmt.Errorf("update contacts: %w", err)
+	}
+
+	return nil
+}
+
+func writeAudit(ctx context.Context, tx *sql.Tx, customerId string, action model.CustomerAuditAction, userId string, details any) error {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("marshal audit details: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO customer_audit (customer_id, action, user_id, details)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4)
+	`, customerId, action, userId, b); err != nil {
+		return fmt.Errorf("insert audit: %w", err)
+	}
+
+	return nil
+}
+
+type customerSnapshot struct {
+	CustomerId string   `json:"customerId"`
+	Name       string   `json:"name"`
+	Phones     []string `json:"phones"`
+	Emails     []string `json:"emails"`
+	Notes      string   `json:"notes"`
+	Tags       []string `json:"tags"`
+}
+
+func snapshot(c *model.Customer) customerSnapshot {
+	return customerSnapshot{
+		CustomerId: c.CustomerId,
+		Name:       c.Name,
+		Phones:     nonNil(c.Phones),
+		Emails:     nonNil(c.Emails),
+		Notes:      c.Notes,
+		Tags:       nonNil(c.Tags),
+	}
+}
+
+func snapshots(cs []*model.Customer) []customerSnapshot {
+	result := make([]customerSnapshot, 0, len(cs))
+	for _, c := range cs {
+		result = append(result, snapshot(c))
+	}
+	return result
+}
+
+func union(a, b []string) []string {
+	for _, v := range b {
+		if !slices.Contains(a, v) {
+			a = append(a, v)
+		}
+	}
+	return a
+}
+
+func uniq(s []string) []string {
+	return union(nil, s)
+}
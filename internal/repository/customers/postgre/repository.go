@@ -0,0 +1,125 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customers"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) customers.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]*model.Customer, error) {
+	return r.getCustomersByFilter(ctx, "TRUE")
+}
+
+func (r *repository) GetByUserId(ctx context.Context, userId string) ([]*model.Customer, error) {
+	return r.getCustomersByFilter(ctx,
+		"EXISTS (SELECT 1 FROM orders o WHERE o.customer_id = c.customer_id AND o.user_id = $1)",
+		userId,
+	)
+}
+
+func (r *repository) GetById(ctx context.Context, customerId string) (*model.Customer, error) {
+	found, err := r.getCustomersByFilter(ctx, "c.customer_id = $1", customerId)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, customers.ErrNotFoundCustomer
+	}
+
+	return found[0], nil
+}
+
+func (r *repository) Update(ctx context.Context, customerId string, update *model.CustomerUpdate) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE customers
+		SET name = $1, notes = $2, tags = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE customer_id = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query,
+		update.Name,
+		update.Notes,
+		pq.Array(nonNil(update.Tags)),
+		customerId,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customers.ErrNotFoundCustomer
+	}
+
+	return nil
+}
+
+func (r *repository) getCustomersByFilter(ctx context.Context, filter string, args ...interface{}) ([]*model.Customer, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT c.customer_id, c.name, c.phones, c.emails, c.notes, c.tags
+		FROM customers c
+		WHERE ` + filter + `
+		ORDER BY c.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.Customer
+	for rows.Next() {
+		var customer model.Customer
+		err := rows.Scan(
+			&customer.CustomerId,
+			&customer.Name,
+			pq.Array(&customer.Phones),
+			pq.Array(&customer.Emails),
+			&customer.Notes,
+			pq.Array(&customer.Tags),
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &customer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,21 @@
+package customfields
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFoundField  = errors.New("not found custom field")
+	ErrDuplicateField = errors.New("custom field already exists")
+)
+
+type Repository interface {
+	Save(ctx context.Context, field *model.CustomField) error
+	GetAll(ctx context.Context) ([]*model.CustomField, error)
+	// Update changes label and options, key and type stay as they are
+	Update(ctx context.Context, field *model.CustomField) error
+	// Delete removes the field and its values from all orders
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,155 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/customfields"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) customfields.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Save(ctx context.Context, field *model.CustomField) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO custom_fields (key, label, type, options)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		field.Key,
+		field.Label,
+		field.Type,
+		pq.Array(options(field.Options)),
+	).Scan(&field.CreatedAt)
+	if database.IsUniqueViolation(err) {
+		return customfields.ErrDuplicateField
+	}
+
+	return err
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]*model.CustomField, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT key, label, type, options, created_at
+		FROM custom_fields
+		ORDER BY created_at, key
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.CustomField
+	for rows.Next() {
+		var field model.CustomField
+		err := rows.Scan(
+			&field.Key,
+			&field.Label,
+			&field.Type,
+			pq.Array(&field.Options),
+			&field.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &field)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) Update(ctx context.Context, field *model.CustomField) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE custom_fields
+		SET label = $1, options = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE key = $3
+	`
+
+	res, err := r.db.ExecContext(ctx, query, field.Label, pq.Array(options(field.Options)), field.Key)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Delete(ctx context.Context, key string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM custom_fields WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("delete field: %w", err)
+	}
+	if err := expectOne(res); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE orders
+		SET custom_fields = custom_fields - $1::text
+		WHERE custom_fields ? $1
+	`, key)
+	if err != nil {
+		return fmt.Errorf("remove values: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// options keeps a nil slice from being stored as NULL
+func options(o []string) []string {
+	if o == nil {
+		return []string{}
+	}
+	return o
+}
+
+func expectOne(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customfields.ErrNotFoundField
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package emails
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFoundEmail = errors.New("not found email")
+
+// Repository is the email outbox. Emails are queued by the subscriber of
+// the order events.
+type Repository interface {
+	// Enqueue queues the email about an event to the order email, unless
+	// the customer opted out or gave none. An event already queued is
+	// skipped, so the event may be handled more than once.
+	Enqueue(ctx context.Context, eventId string, orderId string, event model.OrderEvent) error
+	// Claim returns up to limit pending emails that are due, oldest first,
+	// and counts an attempt for each. They are not due again for lease,
+	// so other workers skip them while they are being sent and a crashed
+	// worker's emails are picked up again later.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxEmail, error)
+	MarkSent(ctx context.Context, emailId string) error
+	// Retry schedules the next attempt of a pending email
+	Retry(ctx context.Context, emailId string, at time.Time, lastError string) error
+	// Finish gives up on an email with status failed or skipped
+	Finish(ctx context.Context, emailId string, status model.DeliveryStatus, lastError string) error
+	// GetByOrderId lists the emails of an order, oldest first
+	GetByOrderId(ctx context.Context, orderId string) ([]*model.OutboxEmail, error)
+}
@@ -0,0 +1,188 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/emails"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) emails.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+const emailColumns = `email_id, order_id, event, recipient, status, attempts, next_attempt_at,
+	last_error, sent_at, created_at`
+
+func (r *repository) Enqueue(ctx context.Context, eventId string, orderId string, event model.OrderEvent) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO email_outbox (event_id, order_id, event, recipient)
+		SELECT $1, order_id, $3, email
+		FROM orders
+		WHERE order_id = $2 AND NOT email_opt_out AND email <> ''
+		ON CONFLICT (event_id) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, eventId, orderId, event)
+	return err
+}
+
+func (r *repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxEmail, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// SKIP LOCKED lets several server instances claim side by side
+	query := `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $3 * interval '1 millisecond'
+		WHERE email_id IN (
+			SELECT email_id
+			FROM email_outbox
+			WHERE status = $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + emailColumns
+
+	rows, err := r.db.QueryContext(ctx, query, model.DeliveryPending, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return scanEmails(rows)
+}
+
+func (r *repository) MarkSent(ctx context.Context, emailId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE email_outbox
+		SET status = $1, last_error = '', sent_at = CURRENT_TIMESTAMP
+		WHERE email_id = $2
+	`
+
+	res, err := r.db.ExecContext(ctx, query, model.DeliverySent, emailId)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Retry(ctx context.Context, emailId string, at time.Time, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE email_outbox
+		SET next_attempt_at = $1, last_error = $2
+		WHERE email_id = $3 AND status = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query, at, lastError, emailId, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Finish(ctx context.Context, emailId string, status model.DeliveryStatus, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE email_outbox
+		SET status = $1, last_error = $2
+		WHERE email_id = $3
+	`
+
+	res, err := r.db.ExecContext(ctx, query, status, lastError, emailId)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) GetByOrderId(ctx context.Context, orderId string) ([]*model.OutboxEmail, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + emailColumns + `
+		FROM email_outbox
+		WHERE order_id = $1
+		ORDER BY created_at, email_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		if database.IsInvalidText(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return scanEmails(rows)
+}
+
+func scanEmails(rows *sql.Rows) ([]*model.OutboxEmail, error) {
+	defer rows.Close()
+
+	var result []*model.OutboxEmail
+	for rows.Next() {
+		var email model.OutboxEmail
+		var sentAt sql.NullTime
+		err := rows.Scan(
+			&email.EmailId,
+			&email.OrderId,
+			&email.Event,
+			&email.Recipient,
+			&email.Status,
+			&email.Attempts,
+			&email.NextAttemptAt,
+			&email.LastError,
+			&sentAt,
+			&email.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		email.SentAt = sentAt.Time
+		result = append(result, &email)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func expectOne(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return emails.ErrNotFoundEmail
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package events
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFoundEvent = errors.New("not found event")
+
+// Repository is the domain event outbox. Events are stored by the orders
+// repository in the transaction of the change.
+type Repository interface {
+	// Claim returns up to limit events that are due and not yet handled by
+	// every subscriber, oldest first, counts an attempt for each and hides
+	// them for lease
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.DomainEvent, error)
+	// MarkHandled records that the subscriber is done with the event
+	MarkHandled(ctx context.Context, eventId string, subscriber string) error
+	// Retry schedules the next dispatch of an event
+	Retry(ctx context.Context, eventId string, at time.Time, lastError string) error
+	// MarkDispatched records that every subscriber handled the event
+	MarkDispatched(ctx context.Context, eventId string) error
+
+	// After returns up to limit events with a sequence above the given one,
+	// in sequence order. Sequences are taken when an event is stored and
+	// may become visible out of order as transactions commit, or never
+	// when they roll back.
+	After(ctx context.Context, sequence int64, limit int) ([]*model.DomainEvent, error)
+	// LastSequence returns the highest stored sequence, 0 without events
+	LastSequence(ctx context.Context) (int64, error)
+}
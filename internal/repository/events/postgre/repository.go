@@ -0,0 +1,184 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/events"
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) events.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+const eventColumns = `sequence, event_id, type, order_id, COALESCE(assignee_id::text, ''), data, created_at,
+	handled_by, attempts`
+
+func (r *repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.DomainEvent, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE domain_events
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $2 * interval '1 millisecond'
+		WHERE sequence IN (
+			SELECT sequence
+			FROM domain_events
+			WHERE dispatched_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY sequence
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + eventColumns
+
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING keeps no order
+	slices.SortFunc(result, func(a, b *model.DomainEvent) int {
+		return cmp.Compare(a.Sequence, b.Sequence)
+	})
+	return result, nil
+}
+
+func (r *repository) MarkHandled(ctx context.Context, eventId string, subscriber string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE domain_events
+		SET handled_by = array_append(handled_by, $1)
+		WHERE event_id = $2 AND NOT $1 = ANY(handled_by)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, subscriber, eventId)
+	return err
+}
+
+func (r *repository) Retry(ctx context.Context, eventId string, at time.Time, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE domain_events
+		SET next_attempt_at = $1, last_error = $2
+		WHERE event_id = $3 AND dispatched_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, at, lastError, eventId)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) MarkDispatched(ctx context.Context, eventId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE domain_events
+		SET dispatched_at = CURRENT_TIMESTAMP, last_error = ''
+		WHERE event_id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, eventId)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) After(ctx context.Context, sequence int64, limit int) ([]*model.DomainEvent, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM domain_events
+		WHERE sequence > $1
+		ORDER BY sequence
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sequence, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanEvents(rows)
+}
+
+func (r *repository) LastSequence(ctx context.Context) (int64, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var sequence int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(sequence), 0) FROM domain_events`).Scan(&sequence)
+	return sequence, err
+}
+
+func scanEvents(rows *sql.Rows) ([]*model.DomainEvent, error) {
+	defer rows.Close()
+
+	var result []*model.DomainEvent
+	for rows.Next() {
+		var event model.DomainEvent
+		err := rows.Scan(
+			&event.Sequence,
+			&event.EventId,
+			&event.Type,
+			&event.OrderId,
+			&event.AssigneeId,
+			&event.Data,
+			&event.CreatedAt,
+			pq.Array(&event.HandledBy),
+			&event.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func expectOne(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return events.ErrNotFoundEvent
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package locks
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFoundLock  = errors.New("not found lock")
+	ErrNotFoundOrder = errors.New("not found order")
+	ErrLocked        = errors.New("order is locked")
+)
+
+// Repository keeps the edit locks of orders. An expired lock counts as
+// released.
+type Repository interface {
+	// Acquire takes or renews the lock of the order for the session until
+	// ttl from now. When another session holds it, its lock is returned
+	// with ErrLocked.
+	Acquire(ctx context.Context, orderId string, userId string, sessionId string, ttl time.Duration) (*model.OrderLock, error)
+	// Release gives up the lock of the session
+	Release(ctx context.Context, orderId string, sessionId string) error
+	// Get returns the current lock of the order
+	Get(ctx context.Context, orderId string) (*model.OrderLock, error)
+}
@@ -0,0 +1,137 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/locks"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) locks.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Acquire(ctx context.Context, orderId string, userId string, sessionId string, ttl time.Duration) (*model.OrderLock, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// The row is only replaced when it is our own lock or an expired one
+	query := `
+		INSERT INTO order_locks (order_id, user_id, session_id, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + $4 * interval '1 millisecond')
+		ON CONFLICT (order_id) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+			session_id = EXCLUDED.session_id,
+			acquired_at = CASE
+				WHEN order_locks.session_id = EXCLUDED.session_id THEN order_locks.acquired_at
+				ELSE CURRENT_TIMESTAMP
+			END,
+			expires_at = EXCLUDED.expires_at
+		WHERE order_locks.session_id = EXCLUDED.session_id OR order_locks.expires_at <= CURRENT_TIMESTAMP
+		RETURNING acquired_at, expires_at, (SELECT username FROM users WHERE user_id = $2)
+	`
+
+	lock := &model.OrderLock{
+		OrderId:   orderId,
+		UserId:    userId,
+		SessionId: sessionId,
+	}
+	err := r.db.QueryRowContext(ctx, query, orderId, userId, sessionId, ttl.Milliseconds()).Scan(
+		&lock.AcquiredAt,
+		&lock.ExpiresAt,
+		&lock.Username,
+	)
+	if err == nil {
+		return lock, nil
+	}
+	if database.IsForeignKeyViolation(err) || database.IsInvalidText(err) {
+		return nil, locks.ErrNotFoundOrder
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Another session holds the lock
+	current, err := r.get(ctx, orderId)
+	if err != nil {
+		if errors.Is(err, locks.ErrNotFoundLock) {
+			// Released in the meantime, the caller may try again
+			return nil, locks.ErrLocked
+		}
+		return nil, err
+	}
+	return current, locks.ErrLocked
+}
+
+func (r *repository) Release(ctx context.Context, orderId string, sessionId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		DELETE FROM order_locks
+		WHERE order_id = $1 AND session_id = $2 AND expires_at > CURRENT_TIMESTAMP
+	`
+
+	res, err := r.db.ExecContext(ctx, query, orderId, sessionId)
+	if err != nil {
+		if database.IsInvalidText(err) {
+			return locks.ErrNotFoundLock
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return locks.ErrNotFoundLock
+	}
+
+	return nil
+}
+
+func (r *repository) Get(ctx context.Context, orderId string) (*model.OrderLock, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.get(ctx, orderId)
+}
+
+func (r *repository) get(ctx context.Context, orderId string) (*model.OrderLock, error) {
+	query := `
+		SELECT l.order_id, l.user_id, u.username, l.session_id, l.acquired_at, l.expires_at
+		FROM order_locks l
+		JOIN users u ON u.user_id = l.user_id
+		WHERE l.order_id = $1 AND l.expires_at > CURRENT_TIMESTAMP
+	`
+
+	var lock model.OrderLock
+	err := r.db.QueryRowContext(ctx, query, orderId).Scan(
+		&lock.OrderId,
+		&lock.UserId,
+		&lock.Username,
+		&lock.SessionId,
+		&lock.AcquiredAt,
+		&lock.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || database.IsInvalidText(err) {
+			return nil, locks.ErrNotFoundLock
+		}
+		return nil, err
+	}
+
+	return &lock, nil
+}
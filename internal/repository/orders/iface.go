@@ -3,11 +3,41 @@ package orders
 import (
 	"backend_crm/internal/model"
 	"context"
+	"errors"
 )
 
+// MaxBulkOrders limits the number of orders one bulk update may change
+const MaxBulkOrders = 500
+
+var (
+	ErrNotFoundOrder     = errors.New("not found order")
+	ErrEmptyOrder        = errors.New("order has no items")
+	ErrNotFoundProduct   = errors.New("not found product")
+	ErrInactiveProduct   = errors.New("product is discontinued")
+	ErrCurrencyMismatch  = errors.New("products have different currencies")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrNotFoundUser      = errors.New("not found user")
+	ErrTooManyOrders     = errors.New("too many orders")
+	// ErrLocked is returned by bulk updates for an order another user
+	// holds the edit lock of
+	ErrLocked = errors.New("order is locked")
+)
+
+// Events builds the domain events of a change. It is called within the
+// transaction of the change and the events it returns are stored with it,
+// so they are dispatched exactly when the change is committed. A nil Events
+// stores none.
+type Events func(transition *model.OrderTransition) ([]*model.DomainEvent, error)
+
 type Repository interface {
-	Save(ctx context.Context, newOrder *model.NewOrder) error
+	Save(ctx context.Context, newOrder *model.NewOrder, events Events) error
+	// Import stores historical orders in one transaction without moving
+	// stock. Orders whose ExternalId was imported before are skipped, a
+	// failing order is reported at its index in the results and does not
+	// abort the others. With dryRun nothing is kept.
+	Import(ctx context.Context, batch []*model.NewOrder, dryRun bool) ([]model.ImportResult, error)
 	GetAll(ctx context.Context) ([]*model.Order, error)
+	GetById(ctx context.Context, orderId string) (*model.Order, error)
 	GetByStatus(ctx context.Context, status model.OrderStatus) ([]*model.Order, error)
 	GetByStatusAndPhone(ctx context.Context, status model.OrderStatus, phone string) ([]*model.Order, error)
 	GetByStatusAndEmail(ctx context.Context, status model.OrderStatus, email string) ([]*model.Order, error)
@@ -16,5 +46,30 @@ type Repository interface {
 	GetByUserIdAndStatusAndPhone(ctx context.Context, userId string, status model.OrderStatus, phone string) ([]*model.Order, error)
 	GetByUserIdAndStatusAndEmail(ctx context.Context, userId string, status model.OrderStatus, email string) ([]*model.Order, error)
 	GetByUserIdAndStatusAndPhoneAndEmail(ctx context.Context, userId string, status model.OrderStatus, phone string, email string) ([]*model.Order, error)
-	UpdateOrderStatus(ctx context.Context, orderId string, status model.OrderStatus) error
+	GetByCustomerId(ctx context.Context, customerId string) ([]*model.Order, error)
+	GetByCustomerIdAndUserId(ctx context.Context, customerId string, userId string) ([]*model.Order, error)
+	GetByFilter(ctx context.Context, filter model.OrderFilter) ([]*model.Order, error)
+	// Stream calls fn for every order matching the filter, oldest first,
+	// without loading all of them into memory. Returning an error from fn
+	// stops the iteration and is returned.
+	Stream(ctx context.Context, filter model.OrderFilter, fn func(*model.Order) error) error
+	// UpdateOrderStatus records the change in the order history as made by
+	// userId. events is not called when the order already has the status.
+	UpdateOrderStatus(ctx context.Context, orderId string, status model.OrderStatus, userId string, events Events) error
+	UpdateDiscount(ctx context.Context, orderId string, discount model.OrderDiscount) error
+	// UpdateTags replaces the tags of the order and records the change
+	UpdateTags(ctx context.Context, orderId string, tags []string, userId string) error
+	// UpdateFields merges the values into the custom fields of the order, a
+	// nil value removes the field. Changes are recorded in the history.
+	UpdateFields(ctx context.Context, orderId string, values model.CustomFieldValues, userId string) error
+	// UpdateOptOut stops or resumes the customer notifications of the order
+	// on the channel and records the change
+	UpdateOptOut(ctx context.Context, orderId string, channel model.NotificationChannel, optOut bool, userId string) error
+	// BulkUpdate applies the action to every selected order and records
+	// the history. The results follow the order of the selection; in atomic
+	// mode they end with the order that failed and nothing is stored.
+	// ErrTooManyOrders is returned when more than MaxBulkOrders match.
+	// events is called for every status change.
+	BulkUpdate(ctx context.Context, update *model.BulkOrderUpdate, events Events) ([]model.BulkOrderResult, error)
+	GetHistory(ctx context.Context, orderId string) ([]*model.OrderChange, error)
 }
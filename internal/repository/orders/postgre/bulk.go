@@ -0,0 +1,288 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// lockedOrder is the part of an order a change needs, read under a row lock
+type lockedOrder struct {
+	orderId string
+	status  model.OrderStatus
+	userId  string
+	tags    []string
+	fields  model.CustomFieldValues
+
+	emailOptOut bool
+	smsOptOut   bool
+}
+
+func (r *repository) BulkUpdate(ctx context.Context, update *model.BulkOrderUpdate, events orders.Events) ([]model.BulkOrderResult, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := update.OrderIds
+	if len(ids) == 0 {
+		if ids, err = selectOrderIds(ctx, tx, update.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) > orders.MaxBulkOrders {
+		return nil, orders.ErrTooManyOrders
+	}
+
+	var bulkId string
+	if err := tx.QueryRowContext(ctx, `SELECT gen_random_uuid()::text`).Scan(&bulkId); err != nil {
+		return nil, fmt.Errorf("bulk id: %w", err)
+	}
+
+	apply := func(orderId string) (bool, error) {
+		locked, err := lockOrder(ctx, tx, orderId, update.Filter.UserId)
+		if err != nil {
+			return false, err
+		}
+		if err := checkEditLock(ctx, tx, orderId, update.UserId); err != nil {
+			return false, err
+		}
+		return applyBulkAction(ctx, tx, locked, update, bulkId, events)
+	}
+
+	results := make([]model.BulkOrderResult, 0, len(ids))
+	for _, orderId := range ids {
+		result := model.BulkOrderResult{OrderId: orderId}
+
+		if update.Atomic {
+			result.Changed, result.Err = apply(orderId)
+			results = append(results, result)
+			if result.Err != nil {
+				if !isOrderError(result.Err) {
+					return nil, result.Err
+				}
+				return results, nil
+			}
+			continue
+		}
+
+		result.Err = database.Savepoint(ctx, tx, func() error {
+			var err error
+			result.Changed, err = apply(orderId)
+			return err
+		})
+		if result.Err != nil {
+			if !isOrderError(result.Err) {
+				return nil, result.Err
+			}
+			result.Changed = false
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return results, nil
+}
+
+// isOrderError tells problems with a single order, which are reported in
+// its result, from failures that abort the whole operation
+func isOrderError(err error) bool {
+	return errors.Is(err, orders.ErrNotFoundOrder) ||
+		errors.Is(err, orders.ErrInsufficientStock) ||
+		errors.Is(err, orders.ErrNotFoundUser) ||
+		errors.Is(err, orders.ErrLocked)
+}
+
+// selectOrderIds returns the ids of the orders matching the filter, oldest
+// first, reading one more than allowed so the caller can tell the limit was
+// exceeded
+func selectOrderIds(ctx context.Context, tx *sql.Tx, filter model.OrderFilter) ([]string, error) {
+	conditions, args, err := filterConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, orders.MaxBulkOrders+1)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT o.order_id
+		FROM orders o
+		WHERE `+conditions+`
+		ORDER BY o.created_at, o.order_id
+		LIMIT $`+strconv.Itoa(len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("select orders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("select orders: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select orders: %w", err)
+	}
+
+	return ids, nil
+}
+
+// lockOrder reads the order for update. A non-empty ownerId only finds
+// orders assigned to that user.
+func lockOrder(ctx context.Context, tx *sql.Tx, orderId string, ownerId string) (*lockedOrder, error) {
+	locked := lockedOrder{orderId: orderId}
+	err := tx.QueryRowContext(ctx, `
+		SELECT status, COALESCE(user_id::text, ''), tags, custom_fields, email_opt_out, sms_opt_out
+		FROM orders
+		WHERE order_id = $1 AND ($2 = '' OR user_id::text = $2)
+		FOR UPDATE
+	`, orderId, ownerId).Scan(&locked.status, &locked.userId, pq.Array(&locked.tags), fieldValues{&locked.fields}, &locked.emailOptOut, &locked.smsOptOut)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || database.IsInvalidText(err) {
+			return nil, orders.ErrNotFoundOrder
+		}
+		return nil, fmt.Errorf("lock order: %w", err)
+	}
+
+	return &locked, nil
+}
+
+// checkEditLock returns ErrLocked when another user than userId holds the
+// edit lock of the order
+func checkEditLock(ctx context.Context, tx *sql.Tx, orderId string, userId string) error {
+	var locked bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM order_locks
+			WHERE order_id = $1 AND expires_at > CURRENT_TIMESTAMP AND user_id::text <> $2
+		)
+	`, orderId, userId).Scan(&locked)
+	if err != nil {
+		return fmt.Errorf("check edit lock: %w", err)
+	}
+	if locked {
+		return orders.ErrLocked
+	}
+
+	return nil
+}
+
+func applyBulkAction(ctx context.Context, tx *sql.Tx, locked *lockedOrder, update *model.BulkOrderUpdate, bulkId string, events orders.Events) (bool, error) {
+	switch update.Action {
+	case model.BulkSetStatus:
+		return setStatus(ctx, tx, locked, update.Status, update.UserId, bulkId, events)
+	case model.BulkAssign:
+		return setAssignee(ctx, tx, locked, update.AssigneeId, update.UserId, bulkId)
+	case model.BulkAddTag:
+		if slices.Contains(locked.tags, update.Tag) {
+			return false, nil
+		}
+		return setTags(ctx, tx, locked, append(slices.Clone(locked.tags), update.Tag), update.UserId, bulkId)
+	case model.BulkRemoveTag:
+		if !slices.Contains(locked.tags, update.Tag) {
+			return false, nil
+		}
+		tags := slices.DeleteFunc(slices.Clone(locked.tags), func(t string) bool { return t == update.Tag })
+		return setTags(ctx, tx, locked, tags, update.UserId, bulkId)
+	}
+
+	return false, fmt.Errorf("unknown bulk action %q", update.Action)
+}
+
+// setStatus changes the status, moves stock accordingly and stores the
+// events of the change
+func setStatus(ctx context.Context, tx *sql.Tx, locked *lockedOrder, status model.OrderStatus, userId, bulkId string, events orders.Events) (bool, error) {
+	if locked.status == status {
+		return false, nil
+	}
+
+	if err := moveStock(ctx, tx, locked.orderId, locked.status, status); err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE orders
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2
+	`
+
+	if _, err := tx.ExecContext(ctx, query, status, locked.orderId); err != nil {
+		return false, fmt.Errorf("update status: %w", err)
+	}
+
+	old := strconv.Itoa(int(locked.status))
+	new := strconv.Itoa(int(status))
+	if err := recordChange(ctx, tx, locked.orderId, userId, model.OrderFieldStatus, old, new, bulkId); err != nil {
+		return false, err
+	}
+
+	return true, storeEvents(ctx, tx, events, &model.OrderTransition{
+		OrderId:    locked.orderId,
+		AssigneeId: locked.userId,
+		From:       locked.status,
+		To:         status,
+		UserId:     userId,
+	})
+}
+
+func setAssignee(ctx context.Context, tx *sql.Tx, locked *lockedOrder, assigneeId, userId, bulkId string) (bool, error) {
+	if locked.userId == assigneeId {
+		return false, nil
+	}
+
+	query := `
+		UPDATE orders
+		SET user_id = NULLIF($1, '')::uuid, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2
+	`
+
+	if _, err := tx.ExecContext(ctx, query, assigneeId, locked.orderId); err != nil {
+		if database.IsForeignKeyViolation(err) || database.IsInvalidText(err) {
+			return false, orders.ErrNotFoundUser
+		}
+		return false, fmt.Errorf("update assignee: %w", err)
+	}
+
+	return true, recordChange(ctx, tx, locked.orderId, userId, model.OrderFieldAssignee, locked.userId, assigneeId, bulkId)
+}
+
+func setTags(ctx context.Context, tx *sql.Tx, locked *lockedOrder, tags []string, userId, bulkId string) (bool, error) {
+	if tags == nil {
+		// A nil array would be stored as NULL
+		tags = []string{}
+	}
+
+	query := `
+		UPDATE orders
+		SET tags = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2
+	`
+
+	if _, err := tx.ExecContext(ctx, query, pq.Array(tags), locked.orderId); err != nil {
+		return false, fmt.Errorf("update tags: %w", err)
+	}
+
+	old := strings.Join(locked.tags, ",")
+	new := strings.Join(tags, ",")
+	return true, recordChange(ctx, tx, locked.orderId, userId, model.OrderFieldTags, old, new, bulkId)
+}
@@ -0,0 +1,43 @@
+package postgre
+
+import (
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// storeEvents builds the events of the transition and stores them in the
+// transaction of the change
+func storeEvents(ctx context.Context, tx *sql.Tx, events orders.Events, transition *model.OrderTransition) error {
+	if events == nil {
+		return nil
+	}
+
+	built, err := events(transition)
+	if err != nil {
+		return fmt.Errorf("build events: %w", err)
+	}
+
+	query := `
+		INSERT INTO domain_events (type, order_id, assignee_id, data)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4)
+		RETURNING sequence, event_id, created_at
+	`
+
+	for _, event := range built {
+		data := string(event.Data)
+		if data == "" {
+			data = "{}"
+		}
+
+		err := tx.QueryRowContext(ctx, query, event.Type, event.OrderId, event.AssigneeId, data).
+			Scan(&event.Sequence, &event.EventId, &event.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("store event: %w", err)
+		}
+	}
+
+	return nil
+}
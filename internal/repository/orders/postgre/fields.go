@@ -0,0 +1,134 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+func (r *repository) GetByFilter(ctx context.Context, filter model.OrderFilter) ([]*model.Order, error) {
+	conditions, args, err := filterConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.getOrdersByFilter(ctx, conditions, args...)
+}
+
+func (r *repository) UpdateTags(ctx context.Context, orderId string, tags []string, userId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockOrder(ctx, tx, orderId, "")
+	if err != nil {
+		return err
+	}
+
+	if slices.Equal(locked.tags, tags) {
+		return nil
+	}
+	if _, err := setTags(ctx, tx, locked, tags, userId, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) UpdateFields(ctx context.Context, orderId string, values model.CustomFieldValues, userId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockOrder(ctx, tx, orderId, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err := setFields(ctx, tx, locked, values, userId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setFields merges the values into the custom fields of the order, a nil
+// value removes the field. The history holds the old and new values of the
+// changed fields only, with null for a field that was not set.
+func setFields(ctx context.Context, tx *sql.Tx, locked *lockedOrder, values model.CustomFieldValues, userId string) (bool, error) {
+	merged := make(model.CustomFieldValues, len(locked.fields)+len(values))
+	for key, value := range locked.fields {
+		merged[key] = value
+	}
+
+	old := make(model.CustomFieldValues)
+	new := make(model.CustomFieldValues)
+	for key, value := range values {
+		current, ok := locked.fields[key]
+		if (value == nil && !ok) || (ok && current == value) {
+			continue
+		}
+		old[key] = current
+		new[key] = value
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	if len(new) == 0 {
+		return false, nil
+	}
+
+	fields, err := encodeFields(merged)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE orders
+		SET custom_fields = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2
+	`
+
+	if _, err := tx.ExecContext(ctx, query, string(fields), locked.orderId); err != nil {
+		return false, fmt.Errorf("update custom fields: %w", err)
+	}
+
+	oldValue, err := encodeFields(old)
+	if err != nil {
+		return false, err
+	}
+	newValue, err := encodeFields(new)
+	if err != nil {
+		return false, err
+	}
+	return true, recordChange(ctx, tx, locked.orderId, userId, model.OrderFieldCustom, string(oldValue), string(newValue), "")
+}
+
+// encodeFields returns the JSON object stored in custom_fields
+func encodeFields(values model.CustomFieldValues) ([]byte, error) {
+	if values == nil {
+		return []byte("{}"), nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("encode custom fields: %w", err)
+	}
+	return data, nil
+}
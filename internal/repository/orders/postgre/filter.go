@@ -0,0 +1,74 @@
+package postgre
+
+import (
+	"backend_crm/internal/model"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// filterConditions turns the filter into a WHERE clause on the orders table
+// aliased as o, numbering the parameters from $1
+func filterConditions(filter model.OrderFilter) (string, []interface{}, error) {
+	conditions := []string{"o.status = $1"}
+	args := []interface{}{filter.Status}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.Phone != "" {
+		add("o.phone = $?", filter.Phone)
+	}
+	if filter.Email != "" {
+		add("o.email = $?", filter.Email)
+	}
+	if filter.UserId != "" {
+		add("o.user_id = $?", filter.UserId)
+	}
+	if len(filter.Tags) > 0 {
+		add("o.tags @> $?::text[]", pq.Array(filter.Tags))
+	}
+	if len(filter.Fields) > 0 {
+		fields, err := json.Marshal(filter.Fields)
+		if err != nil {
+			return "", nil, fmt.Errorf("encode field filter: %w", err)
+		}
+		add("o.custom_fields @> $?::jsonb", string(fields))
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// fieldValues scans a custom_fields column
+type fieldValues struct {
+	values *model.CustomFieldValues
+}
+
+func (f fieldValues) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*f.values = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("custom fields: unexpected type %T", src)
+	}
+
+	var values model.CustomFieldValues
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("custom fields: %w", err)
+	}
+	if len(values) == 0 {
+		values = nil
+	}
+	*f.values = values
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (r *repository) GetHistory(ctx context.Context, orderId string) ([]*model.OrderChange, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT h.history_id, h.order_id, COALESCE(h.user_id::text, ''), COALESCE(u.username, ''),
+			   h.field, h.old_value, h.new_value, COALESCE(h.bulk_id::text, ''), h.created_at
+		FROM order_history h
+		LEFT JOIN users u ON u.user_id = h.user_id
+		WHERE h.order_id = $1
+		ORDER BY h.created_at DESC, h.history_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.OrderChange
+	for rows.Next() {
+		var change model.OrderChange
+		if err := rows.Scan(
+			&change.HistoryId,
+			&change.OrderId,
+			&change.UserId,
+			&change.Username,
+			&change.Field,
+			&change.OldValue,
+			&change.NewValue,
+			&change.BulkId,
+			&change.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// recordChange adds an entry to the order history. Empty userId and bulkId
+// are stored as NULL.
+func recordChange(ctx context.Context, tx *sql.Tx, orderId, userId string, field model.OrderField, old, new, bulkId string) error {
+	query := `
+		INSERT INTO order_history (order_id, user_id, field, old_value, new_value, bulk_id)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, NULLIF($6, '')::uuid)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, orderId, userId, field, old, new, bulkId); err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"context"
+	"fmt"
+)
+
+func (r *repository) Import(ctx context.Context, batch []*model.NewOrder, dryRun bool) ([]model.ImportResult, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]model.ImportResult, len(batch))
+	for i, newOrder := range batch {
+		if newOrder.ExternalId != "" {
+			var exists bool
+			err := tx.QueryRowContext(ctx,
+				`SELECT EXISTS (SELECT 1 FROM orders WHERE external_id = $1)`,
+				newOrder.ExternalId,
+			).Scan(&exists)
+			if err != nil {
+				return nil, fmt.Errorf("find imported order: %w", err)
+			}
+			if exists {
+				results[i].Skipped = true
+				continue
+			}
+		}
+
+		// Historical orders have long been served and other systems have
+		// seen them, they emit no events
+		err := database.Savepoint(ctx, tx, func() error {
+			return saveOrder(ctx, tx, newOrder, true, nil)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			results[i].Err = err
+			continue
+		}
+		results[i].Created = true
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return results, nil
+}
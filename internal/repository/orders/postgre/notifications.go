@@ -0,0 +1,78 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// optOutColumns maps a channel to its column on orders, its outbox table
+// and its history field
+var optOutColumns = map[model.NotificationChannel]struct {
+	column string
+	outbox string
+	field  model.OrderField
+}{
+	model.ChannelEmail: {"email_opt_out", "email_outbox", model.OrderFieldEmailOptOut},
+	model.ChannelSMS:   {"sms_opt_out", "sms_outbox", model.OrderFieldSMSOptOut},
+}
+
+// UpdateOptOut switches the notifications of an order on a channel. Opting
+// out also skips the messages still waiting in the outbox.
+func (r *repository) UpdateOptOut(ctx context.Context, orderId string, channel model.NotificationChannel, optOut bool, userId string) error {
+	columns, ok := optOutColumns[channel]
+	if !ok {
+		return fmt.Errorf("unknown notification channel %q", channel)
+	}
+
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockOrder(ctx, tx, orderId, "")
+	if err != nil {
+		return err
+	}
+	current := locked.emailOptOut
+	if channel == model.ChannelSMS {
+		current = locked.smsOptOut
+	}
+	if current == optOut {
+		return nil
+	}
+
+	query := `
+		UPDATE orders
+		SET ` + columns.column + ` = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, optOut, orderId); err != nil {
+		return fmt.Errorf("update %s opt-out: %w", channel, err)
+	}
+
+	if optOut {
+		query := `
+			UPDATE ` + columns.outbox + `
+			SET status = $1, last_error = 'opted out'
+			WHERE order_id = $2 AND status = $3
+		`
+		if _, err := tx.ExecContext(ctx, query, model.DeliverySkipped, orderId, model.DeliveryPending); err != nil {
+			return fmt.Errorf("skip %s: %w", channel, err)
+		}
+	}
+
+	old := strconv.FormatBool(current)
+	new := strconv.FormatBool(optOut)
+	if err := recordChange(ctx, tx, orderId, userId, columns.field, old, new, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
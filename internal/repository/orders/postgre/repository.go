@@ -1,78 +1,269 @@
 package postgre
 
 import (
+	"backend_crm/internal/contact"
+	"backend_crm/internal/database"
 	"backend_crm/internal/model"
 	"backend_crm/internal/repository/orders"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type repository struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
 }
 
-func NewRepository(db *sql.DB) orders.Repository {
-	return &repository{db: db}
+func NewRepository(db *sql.DB, queryTimeout time.Duration) orders.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Save(ctx context.Context, newOrder *model.NewOrder, events orders.Events) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveOrder(ctx, tx, newOrder, false, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *repository) Save(ctx context.Context, newOrder *model.NewOrder) error {
+// saveOrder inserts the order with its items and links it to a customer.
+// Historical orders may reference discontinued products.
+func saveOrder(ctx context.Context, tx *sql.Tx, newOrder *model.NewOrder, historical bool, events orders.Events) error {
+	if len(newOrder.Items) == 0 {
+		return orders.ErrEmptyOrder
+	}
+
+	currency, err := orderCurrency(ctx, tx, newOrder.Items, historical)
+	if err != nil {
+		return err
+	}
+
+	customerId, err := linkCustomer(ctx, tx, newOrder.Name, newOrder.Phone, newOrder.Email)
+	if err != nil {
+		return fmt.Errorf("link customer: %w", err)
+	}
+
+	var createdAt *time.Time
+	if !newOrder.CreatedAt.IsZero() {
+		createdAt = &newOrder.CreatedAt
+	}
+
+	tags := newOrder.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	fields, err := encodeFields(newOrder.Fields)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO orders (product_id, phone, email, description, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO orders (product_id, customer_id, phone, email, description, status, currency, tax_rate,
+			external_id, created_at, tags, custom_fields, email_opt_out, sms_opt_out)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), COALESCE($10, CURRENT_TIMESTAMP), $11, $12, $13, $14)
+		RETURNING order_id
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
-		newOrder.ProductId,
+	var orderId string
+	err = tx.QueryRowContext(ctx, query,
+		newOrder.Items[0].ProductId,
+		customerId,
 		newOrder.Phone,
 		newOrder.Email,
 		newOrder.Description,
 		newOrder.Status,
-	)
+		currency,
+		newOrder.TaxRate,
+		newOrder.ExternalId,
+		createdAt,
+		pq.Array(tags),
+		string(fields),
+		newOrder.EmailOptOut,
+		newOrder.SMSOptOut,
+	).Scan(&orderId)
+	if err != nil {
+		return fmt.Errorf("insert order: %w", err)
+	}
 
-	return err
+	if err := insertItems(ctx, tx, orderId, newOrder.Items); err != nil {
+		return err
+	}
+
+	return storeEvents(ctx, tx, events, &model.OrderTransition{
+		OrderId: orderId,
+		Created: true,
+		To:      newOrder.Status,
+	})
 }
 
-func (r *repository) GetAll(ctx context.Context) ([]*model.Order, error) {
-	query := `
-		SELECT o.order_id, o.phone, o.email, o.description, o.status,
-			   p.product_id, p.name, p.weight, p.description
-		FROM orders o
-		JOIN products p ON o.product_id = p.product_id
-	`
+// orderCurrency checks that all products exist, are still sold unless
+// allowInactive is set and are priced in the same currency and returns it.
+func orderCurrency(ctx context.Context, tx *sql.Tx, items []model.NewOrderItem, allowInactive bool) (string, error) {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductId)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT product_id, currency, active FROM products WHERE product_id = ANY($1::uuid[])`,
+		pq.Array(ids),
+	)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("select product currencies: %w", err)
 	}
 	defer rows.Close()
 
-	var orders []*model.Order
+	currencies := make(map[string]string, len(ids))
 	for rows.Next() {
-		var order model.Order
-		var product model.Product
-		err := rows.Scan(
-			&order.OrderId,
-			&order.Phone,
-			&order.Email,
-			&order.Description,
-			&order.Status,
-			&product.ProductId,
-			&product.Name,
-			&product.Weigth,
-			&product.Description,
-		)
+		var productId, currency string
+		var active bool
+		if err := rows.Scan(&productId, &currency, &active); err != nil {
+			return "", fmt.Errorf("scan product currency: %w", err)
+		}
+		if !active && !allowInactive {
+			return "", orders.ErrInactiveProduct
+		}
+		currencies[productId] = currency
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("select product currencies: %w", err)
+	}
+
+	var currency string
+	for _, id := range ids {
+		c, ok := currencies[id]
+		if !ok {
+			return "", orders.ErrNotFoundProduct
+		}
+		if currency != "" && c != currency {
+			return "", orders.ErrCurrencyMismatch
+		}
+		currency = c
+	}
+
+	return currency, nil
+}
+
+// insertItems stores the order lines together with a snapshot of the
+// product weight and price, unless the item brings its own price.
+func insertItems(ctx context.Context, tx *sql.Tx, orderId string, items []model.NewOrderItem) error {
+	query := `
+		INSERT INTO order_items (order_id, product_id, position, quantity, unit_weight, unit_price)
+		SELECT $1, p.product_id, $3, $4, p.weight, COALESCE($5, p.price)
+		FROM products p
+		WHERE p.product_id = $2
+	`
+
+	for i, item := range items {
+		res, err := tx.ExecContext(ctx, query, orderId, item.ProductId, i, item.Quantity, item.UnitPrice)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("insert order item: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("insert order item: %w", err)
+		}
+		if n == 0 {
+			return orders.ErrNotFoundProduct
 		}
-		order.Product = product
-		orders = append(orders, &order)
 	}
 
-	if err = rows.Err(); err != nil {
+	return nil
+}
+
+// linkCustomer finds the customer owning the normalized phone or email, adds
+// any contact it does not know yet, or creates a new customer.
+func linkCustomer(ctx context.Context, tx *sql.Tx, name, phone, email string) (string, error) {
+	phone = contact.NormalizePhone(phone)
+	email = contact.NormalizeEmail(email)
+
+	// Serialize concurrent orders with the same contacts, otherwise both
+	// could miss each other and create two customers.
+	for _, key := range []string{phone, email} {
+		if key == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('customer:' || $1))`, key); err != nil {
+			return "", fmt.Errorf("lock contacts: %w", err)
+		}
+	}
+
+	var customerId string
+	err := tx.QueryRowContext(ctx, `
+		SELECT customer_id
+		FROM customers
+		WHERE ($1 <> '' AND phones @> ARRAY[$1]::text[])
+		   OR ($2 <> '' AND emails @> ARRAY[$2]::text[])
+		ORDER BY created_at
+		LIMIT 1
+		FOR UPDATE
+	`, phone, email).Scan(&customerId)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO customers (name, phones, emails)
+			VALUES ($1, array_remove(ARRAY[$2]::text[], ''), array_remove(ARRAY[$3]::text[], ''))
+			RETURNING customer_id
+		`, name, phone, email).Scan(&customerId)
+		if err != nil {
+			return "", fmt.Errorf("insert customer: %w", err)
+		}
+	case err != nil:
+		return "", fmt.Errorf("find customer: %w", err)
+	default:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE customers
+			SET phones = CASE WHEN $2 = '' OR phones @> ARRAY[$2]::text[] THEN phones ELSE array_append(phones, $2) END,
+				emails = CASE WHEN $3 = '' OR emails @> ARRAY[$3]::text[] THEN emails ELSE array_append(emails, $3) END,
+				name = CASE WHEN name = '' THEN $4 ELSE name END,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE customer_id = $1
+		`, customerId, phone, email, name)
+		if err != nil {
+			return "", fmt.Errorf("update customer: %w", err)
+		}
+	}
+
+	return customerId, nil
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]*model.Order, error) {
+	return r.getOrdersByFilter(ctx, "TRUE")
+}
+
+func (r *repository) GetById(ctx context.Context, orderId string) (*model.Order, error) {
+	found, err := r.getOrdersByFilter(ctx, "o.order_id = $1", orderId)
+	if err != nil {
+		// A malformed id cannot name an order
+		if database.IsInvalidText(err) {
+			return nil, orders.ErrNotFoundOrder
+		}
 		return nil, err
 	}
+	if len(found) == 0 {
+		return nil, orders.ErrNotFoundOrder
+	}
 
-	return orders, nil
+	return found[0], nil
 }
 
 func (r *repository) GetByStatus(ctx context.Context, status model.OrderStatus) ([]*model.Order, error) {
@@ -107,21 +298,72 @@ func (r *repository) GetByUserIdAndStatusAndPhoneAndEmail(ctx context.Context, u
 	return r.getOrdersByFilter(ctx, "user_id = $1 AND status = $2 AND phone = $3 AND email = $4", userId, status, phone, email)
 }
 
-func (r *repository) UpdateOrderStatus(ctx context.Context, orderId string, status model.OrderStatus) error {
+func (r *repository) GetByCustomerId(ctx context.Context, customerId string) ([]*model.Order, error) {
+	return r.getOrdersByFilter(ctx, "customer_id = $1", customerId)
+}
+
+func (r *repository) GetByCustomerIdAndUserId(ctx context.Context, customerId string, userId string) ([]*model.Order, error) {
+	return r.getOrdersByFilter(ctx, "customer_id = $1 AND user_id = $2", customerId, userId)
+}
+
+// UpdateOrderStatus changes the status and moves stock accordingly: orders at
+// work hold a reservation, completed orders have their goods deducted.
+func (r *repository) UpdateOrderStatus(ctx context.Context, orderId string, status model.OrderStatus, userId string, events orders.Events) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := lockOrder(ctx, tx, orderId, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err := setStatus(ctx, tx, locked, status, userId, "", events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) UpdateDiscount(ctx context.Context, orderId string, discount model.OrderDiscount) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	query := `
 		UPDATE orders
-		SET status = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE order_id = $2
+		SET discount_amount = $1, discount_percent = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $3
 	`
 
-	_, err := r.db.ExecContext(ctx, query, status, orderId)
-	return err
+	res, err := r.db.ExecContext(ctx, query, discount.Amount, discount.Percent, orderId)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return orders.ErrNotFoundOrder
+	}
+
+	return nil
 }
 
 func (r *repository) getOrdersByFilter(ctx context.Context, filter string, args ...interface{}) ([]*model.Order, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	query := `
-		SELECT o.order_id, o.phone, o.email, o.description, o.status,
-			   p.product_id, p.name, p.weight, p.description
+		SELECT o.order_id, COALESCE(o.customer_id::text, ''), COALESCE(o.user_id::text, ''), o.phone, o.email, o.description, o.status,
+			   o.currency, o.discount_amount, o.discount_percent, o.tax_rate, o.created_at, o.tags,
+			   o.custom_fields, o.email_opt_out, o.sms_opt_out, p.product_id, p.name, p.weight, p.description, p.price, p.currency
 		FROM orders o
 		JOIN products p ON o.product_id = p.product_id
 		WHERE ` + filter
@@ -132,31 +374,104 @@ func (r *repository) getOrdersByFilter(ctx context.Context, filter string, args
 	}
 	defer rows.Close()
 
-	var orders []*model.Order
+	var result []*model.Order
 	for rows.Next() {
 		var order model.Order
 		var product model.Product
 		err := rows.Scan(
 			&order.OrderId,
+			&order.CustomerId,
+			&order.UserId,
 			&order.Phone,
 			&order.Email,
 			&order.Description,
 			&order.Status,
+			&order.Currency,
+			&order.DiscountAmount,
+			&order.DiscountPercent,
+			&order.TaxRate,
+			&order.CreatedAt,
+			pq.Array(&order.Tags),
+			fieldValues{&order.Fields},
+			&order.EmailOptOut,
+			&order.SMSOptOut,
 			&product.ProductId,
 			&product.Name,
 			&product.Weigth,
 			&product.Description,
+			&product.Price.Amount,
+			&product.Price.Currency,
 		)
 		if err != nil {
 			return nil, err
 		}
 		order.Product = product
-		orders = append(orders, &order)
+		result = append(result, &order)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
+
+	if err := r.loadItems(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// loadItems fetches the lines of all given orders with a single query
+func (r *repository) loadItems(ctx context.Context, result []*model.Order) error {
+	if len(result) == 0 {
+		return nil
+	}
+
+	byId := make(map[string]*model.Order, len(result))
+	ids := make([]string, 0, len(result))
+	for _, order := range result {
+		byId[order.OrderId] = order
+		ids = append(ids, order.OrderId)
+	}
+
+	query := `
+		SELECT i.order_id, i.order_item_id, i.quantity, i.unit_weight, i.unit_price,
+			   p.product_id, p.name, p.weight, p.description, p.price, p.currency
+		FROM order_items i
+		JOIN products p ON i.product_id = p.product_id
+		WHERE i.order_id = ANY($1::uuid[])
+		ORDER BY i.order_id, i.position
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderId string
+		var item model.OrderItem
+		err := rows.Scan(
+			&orderId,
+			&item.OrderItemId,
+			&item.Quantity,
+			&item.UnitWeight,
+			&item.UnitPrice,
+			&item.Product.ProductId,
+			&item.Product.Name,
+			&item.Product.Weigth,
+			&item.Product.Description,
+			&item.Product.Price.Amount,
+			&item.Product.Price.Currency,
+		)
+		if err != nil {
+			return err
+		}
+		if order, ok := byId[orderId]; ok {
+			order.Items = append(order.Items, item)
+		}
+	}
 
-	return orders, nil
+	return rows.Err()
 }
@@ -0,0 +1,161 @@
+package postgre
+
+import (
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/orders"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type stockLine struct {
+	productId string
+	quantity  int
+	// reserved and deducted are what the movements of the order still hold
+	// or took from the product
+	reserved int
+	deducted int
+}
+
+type stockStep struct {
+	kind     model.StockMovementKind
+	stock    int // sign applied to the stock on hand
+	reserved int // sign applied to the reserved stock
+}
+
+// stockSteps returns what has to happen to the stock when an order moves
+// from old to new status. The effect of the old status is undone first,
+// then the effect of the new one is applied. Only what the movements of the
+// order recorded is undone: orders that were at work or complete before
+// stock was tracked, or that were imported, never reserved or took stock.
+func stockSteps(old, new model.OrderStatus) []stockStep {
+	if old == new {
+		return nil
+	}
+
+	var steps []stockStep
+	switch old {
+	case model.AtWork:
+		steps = append(steps, stockStep{kind: model.StockReleased, reserved: -1})
+	case model.Complete:
+		steps = append(steps, stockStep{kind: model.StockReturned, stock: +1})
+	}
+	switch new {
+	case model.AtWork:
+		steps = append(steps, stockStep{kind: model.StockReserved, reserved: +1})
+	case model.Complete:
+		steps = append(steps, stockStep{kind: model.StockDeducted, stock: -1})
+	}
+
+	return steps
+}
+
+// moveStock applies the stock changes of a status transition. Product rows
+// are locked in a stable order so concurrent transitions serialize instead
+// of deadlocking.
+func moveStock(ctx context.Context, tx *sql.Tx, orderId string, old, new model.OrderStatus) error {
+	steps := stockSteps(old, new)
+	if len(steps) == 0 {
+		return nil
+	}
+
+	lines, err := orderLines(ctx, tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		var stock, reserved int
+		err := tx.QueryRowContext(ctx,
+			`SELECT stock, reserved FROM products WHERE product_id = $1 FOR UPDATE`,
+			line.productId,
+		).Scan(&stock, &reserved)
+		if err != nil {
+			return fmt.Errorf("lock product: %w", err)
+		}
+
+		quantities := make([]int, len(steps))
+		for i, step := range steps {
+			quantities[i] = line.stepQuantity(step)
+			stock += step.stock * quantities[i]
+			reserved += step.reserved * quantities[i]
+		}
+		if stock < 0 || reserved < 0 || reserved > stock {
+			return fmt.Errorf("product %s: %w", line.productId, orders.ErrInsufficientStock)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE products
+			SET stock = $1, reserved = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE product_id = $3
+		`, stock, reserved, line.productId); err != nil {
+			return fmt.Errorf("update stock: %w", err)
+		}
+
+		for i, step := range steps {
+			if quantities[i] == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO stock_movements (product_id, order_id, kind, quantity)
+				VALUES ($1, $2, $3, $4)
+			`, line.productId, orderId, step.kind, quantities[i]); err != nil {
+				return fmt.Errorf("insert stock movement: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// stepQuantity is the quantity a step moves: the whole line when applying a
+// status, what is still recorded when undoing one
+func (l stockLine) stepQuantity(step stockStep) int {
+	switch step.kind {
+	case model.StockReleased:
+		return l.reserved
+	case model.StockReturned:
+		return l.deducted
+	}
+	return l.quantity
+}
+
+// orderLines sums the quantities of an order per product, ordered by
+// product, with the stock its movements still reserve and deducted
+func orderLines(ctx context.Context, tx *sql.Tx, orderId string) ([]stockLine, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT i.product_id, i.quantity,
+			COALESCE(SUM(m.quantity) FILTER (WHERE m.kind = 'reserve'), 0)
+				- COALESCE(SUM(m.quantity) FILTER (WHERE m.kind = 'release'), 0),
+			COALESCE(SUM(m.quantity) FILTER (WHERE m.kind = 'deduct'), 0)
+				- COALESCE(SUM(m.quantity) FILTER (WHERE m.kind = 'return'), 0)
+		FROM (
+			SELECT product_id, SUM(quantity) AS quantity
+			FROM order_items
+			WHERE order_id = $1
+			GROUP BY product_id
+		) i
+		LEFT JOIN stock_movements m ON m.order_id = $1 AND m.product_id = i.product_id
+		GROUP BY i.product_id, i.quantity
+		ORDER BY i.product_id
+	`, orderId)
+	if err != nil {
+		return nil, fmt.Errorf("select order lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []stockLine
+	for rows.Next() {
+		var line stockLine
+		if err := rows.Scan(&line.productId, &line.quantity, &line.reserved, &line.deducted); err != nil {
+			return nil, fmt.Errorf("scan order line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select order lines: %w", err)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,70 @@
+package postgre
+
+import (
+	"backend_crm/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestStockSteps(t *testing.T) {
+	reserve := stockStep{kind: model.StockReserved, reserved: +1}
+	release := stockStep{kind: model.StockReleased, reserved: -1}
+	deduct := stockStep{kind: model.StockDeducted, stock: -1}
+	giveBack := stockStep{kind: model.StockReturned, stock: +1}
+
+	tests := []struct {
+		name     string
+		old, new model.OrderStatus
+		want     []stockStep
+	}{
+		{"unchanged consideration", model.Consideration, model.Consideration, nil},
+		{"unchanged at work", model.AtWork, model.AtWork, nil},
+		{"unchanged complete", model.Complete, model.Complete, nil},
+		{"unchanged rejected", model.Refected, model.Refected, nil},
+		{"consideration to rejected", model.Consideration, model.Refected, nil},
+		{"rejected to consideration", model.Refected, model.Consideration, nil},
+		{"consideration to at work", model.Consideration, model.AtWork, []stockStep{reserve}},
+		{"rejected to at work", model.Refected, model.AtWork, []stockStep{reserve}},
+		{"consideration to complete", model.Consideration, model.Complete, []stockStep{deduct}},
+		{"rejected to complete", model.Refected, model.Complete, []stockStep{deduct}},
+		{"at work to consideration", model.AtWork, model.Consideration, []stockStep{release}},
+		{"at work to rejected", model.AtWork, model.Refected, []stockStep{release}},
+		{"at work to complete", model.AtWork, model.Complete, []stockStep{release, deduct}},
+		{"complete to consideration", model.Complete, model.Consideration, []stockStep{giveBack}},
+		{"complete to rejected", model.Complete, model.Refected, []stockStep{giveBack}},
+		{"complete to at work", model.Complete, model.AtWork, []stockStep{giveBack, reserve}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stockSteps(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stockSteps(%d, %d) = %+v, want %+v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepQuantity(t *testing.T) {
+	tests := []struct {
+		name string
+		line stockLine
+		kind model.StockMovementKind
+		want int
+	}{
+		{"reserve takes the line", stockLine{quantity: 5}, model.StockReserved, 5},
+		{"deduct takes the line", stockLine{quantity: 5, reserved: 5}, model.StockDeducted, 5},
+		{"release undoes the reservation", stockLine{quantity: 5, reserved: 5}, model.StockReleased, 5},
+		{"release after the line grew", stockLine{quantity: 8, reserved: 5}, model.StockReleased, 5},
+		{"release without reservation", stockLine{quantity: 5}, model.StockReleased, 0},
+		{"return undoes the deduction", stockLine{quantity: 5, deducted: 5}, model.StockReturned, 5},
+		{"return without deduction", stockLine{quantity: 5}, model.StockReturned, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.line.stepQuantity(stockStep{kind: tt.kind}); got != tt.want {
+				t.Errorf("stepQuantity(%s) = %d, want %d", tt.kind, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package postgre
+
+import (
+	"backend_crm/internal/model"
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// Stream reads orders and their items with a single query and hands out
+// each order as soon as its last item has been read. The query timeout does
+// not apply: the query lives as long as the caller consumes orders, ctx is
+// expected to carry a deadline.
+func (r *repository) Stream(ctx context.Context, filter model.OrderFilter, fn func(*model.Order) error) error {
+	conditions, args, err := filterConditions(filter)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT o.order_id, COALESCE(o.customer_id::text, ''), COALESCE(o.user_id::text, ''), o.phone, o.email,
+			   COALESCE(o.description, ''), o.status, o.currency, o.discount_amount, o.discount_percent,
+			   o.tax_rate, o.created_at, o.tags, o.custom_fields,
+			   i.order_item_id, i.quantity, i.unit_weight, i.unit_price,
+			   p.product_id, p.name, p.weight, COALESCE(p.description, ''), p.price, p.currency
+		FROM orders o
+		JOIN order_items i ON i.order_id = o.order_id
+		JOIN products p ON p.product_id = i.product_id
+		WHERE ` + conditions + `
+		ORDER BY o.created_at, o.order_id, i.position`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current *model.Order
+	for rows.Next() {
+		var order model.Order
+		var item model.OrderItem
+		err := rows.Scan(
+			&order.OrderId,
+			&order.CustomerId,
+			&order.UserId,
+			&order.Phone,
+			&order.Email,
+			&order.Description,
+			&order.Status,
+			&order.Currency,
+			&order.DiscountAmount,
+			&order.DiscountPercent,
+			&order.TaxRate,
+			&order.CreatedAt,
+			pq.Array(&order.Tags),
+			fieldValues{&order.Fields},
+			&item.OrderItemId,
+			&item.Quantity,
+			&item.UnitWeight,
+			&item.UnitPrice,
+			&item.Product.ProductId,
+			&item.Product.Name,
+			&item.Product.Weigth,
+			&item.Product.Description,
+			&item.Product.Price.Amount,
+			&item.Product.Price.Currency,
+		)
+		if err != nil {
+			return err
+		}
+
+		if current == nil || current.OrderId != order.OrderId {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+			order.Product = item.Product
+			current = &order
+		}
+		current.Items = append(current.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fn(current)
+	}
+
+	return nil
+}
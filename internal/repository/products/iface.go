@@ -3,10 +3,38 @@ package products
 import (
 	"backend_crm/internal/model"
 	"context"
+	"errors"
+)
+
+var (
+	ErrNotFoundProduct   = errors.New("not found product")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrNotFoundCategory  = errors.New("not found category")
+	ErrNotFoundImage     = errors.New("not found image")
+	ErrDuplicateSKU      = errors.New("sku is already used by another product")
+	ErrAmbiguousProduct  = errors.New("several products have this name")
 )
 
 type Repository interface {
 	Save(ctx context.Context, product *model.Product) error
-	GetAll() ([]*model.Product, error)
-	GetById(id string) (*model.Product, error)
+	GetAll(ctx context.Context, filter model.ProductFilter) ([]*model.Product, error)
+	GetById(ctx context.Context, id string) (*model.Product, error)
+	Update(ctx context.Context, product *model.Product) error
+	// SetActive hides discontinued products from intake without deleting
+	// them, so existing orders keep resolving
+	SetActive(ctx context.Context, id string, active bool) error
+	AdjustStock(ctx context.Context, adjustment *model.StockAdjustment) error
+	GetStockMovements(ctx context.Context, productId string) ([]*model.StockMovement, error)
+	// SaveImage appends the image to the product's gallery
+	SaveImage(ctx context.Context, image *model.ProductImage) error
+	GetImages(ctx context.Context, productId string) ([]*model.ProductImage, error)
+	GetImage(ctx context.Context, productId string, imageId string) (*model.ProductImage, error)
+	// DeleteImage removes the image row and returns it so the caller can
+	// clean up the blobs
+	DeleteImage(ctx context.Context, productId string, imageId string) (*model.ProductImage, error)
+	// Upsert matches every product by SKU, or by name when it has none, and
+	// updates the match or inserts a new product. The batch runs in one
+	// transaction; a failing product is reported at its index in the
+	// results and does not abort the others. With dryRun nothing is kept.
+	Upsert(ctx context.Context, batch []*model.Product, dryRun bool) ([]model.ImportResult, error)
 }
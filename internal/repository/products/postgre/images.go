@@ -0,0 +1,150 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/products"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const imageColumns = `
+	image_id, product_id, position, width, height,
+	blob_key, content_type, size, sha256,
+	thumbnail_key, thumbnail_content_type, thumbnail_size, thumbnail_sha256,
+	COALESCE(uploaded_by::text, ''), created_at`
+
+func (r *repository) SaveImage(ctx context.Context, image *model.ProductImage) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO product_images (
+			product_id, position, width, height,
+			blob_key, content_type, size, sha256,
+			thumbnail_key, thumbnail_content_type, thumbnail_size, thumbnail_sha256,
+			uploaded_by
+		)
+		VALUES (
+			$1,
+			(SELECT COALESCE(MAX(position), 0) + 1 FROM product_images WHERE product_id = $1),
+			$2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+			NULLIF($12, '')::uuid
+		)
+		RETURNING image_id, position, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		image.ProductId,
+		image.Width,
+		image.Height,
+		image.Original.Key,
+		image.Original.ContentType,
+		image.Original.Size,
+		image.Original.SHA256,
+		image.Thumbnail.Key,
+		image.Thumbnail.ContentType,
+		image.Thumbnail.Size,
+		image.Thumbnail.SHA256,
+		image.UploadedBy,
+	).Scan(&image.ImageId, &image.Position, &image.CreatedAt)
+	if database.IsForeignKeyViolation(err) {
+		return products.ErrNotFoundProduct
+	}
+
+	return err
+}
+
+func (r *repository) GetImages(ctx context.Context, productId string) ([]*model.ProductImage, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `SELECT ` + imageColumns + `
+		FROM product_images
+		WHERE product_id = $1
+		ORDER BY position`
+
+	rows, err := r.db.QueryContext(ctx, query, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.ProductImage
+	for rows.Next() {
+		image, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) GetImage(ctx context.Context, productId string, imageId string) (*model.ProductImage, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `SELECT ` + imageColumns + `
+		FROM product_images
+		WHERE product_id = $1 AND image_id = $2`
+
+	image, err := scanImage(r.db.QueryRowContext(ctx, query, productId, imageId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, products.ErrNotFoundImage
+	}
+
+	return image, err
+}
+
+func (r *repository) DeleteImage(ctx context.Context, productId string, imageId string) (*model.ProductImage, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `DELETE FROM product_images
+		WHERE product_id = $1 AND image_id = $2
+		RETURNING ` + imageColumns
+
+	image, err := scanImage(r.db.QueryRowContext(ctx, query, productId, imageId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, products.ErrNotFoundImage
+	}
+
+	return image, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanImage(row scanner) (*model.ProductImage, error) {
+	var image model.ProductImage
+	err := row.Scan(
+		&image.ImageId,
+		&image.ProductId,
+		&image.Position,
+		&image.Width,
+		&image.Height,
+		&image.Original.Key,
+		&image.Original.ContentType,
+		&image.Original.Size,
+		&image.Original.SHA256,
+		&image.Thumbnail.Key,
+		&image.Thumbnail.ContentType,
+		&image.Thumbnail.Size,
+		&image.Thumbnail.SHA256,
+		&image.UploadedBy,
+		&image.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
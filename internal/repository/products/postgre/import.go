@@ -0,0 +1,163 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/products"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func (r *repository) Upsert(ctx context.Context, batch []*model.Product, dryRun bool) ([]model.ImportResult, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]model.ImportResult, len(batch))
+	for i, product := range batch {
+		err := database.Savepoint(ctx, tx, func() error {
+			created, err := upsertProduct(ctx, tx, product)
+			results[i].Created = created
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			results[i] = model.ImportResult{Err: err}
+		}
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return results, nil
+}
+
+// upsertProduct updates the product matching by SKU or name or inserts it.
+// A product with a SKU may also match a product of the same name that has
+// no SKU yet, so catalogs created by hand can be imported over.
+func upsertProduct(ctx context.Context, tx *sql.Tx, product *model.Product) (bool, error) {
+	// Serialize concurrent imports of the same product, otherwise both
+	// could miss each other and insert it twice
+	for _, key := range []string{product.SKU, strings.ToLower(product.Name)} {
+		if key == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('product:' || $1))`, key); err != nil {
+			return false, fmt.Errorf("lock product: %w", err)
+		}
+	}
+
+	productId, err := matchProduct(ctx, tx, product)
+	if err != nil {
+		return false, err
+	}
+
+	if productId == "" {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO products (sku, name, weight, description, price, currency, active)
+			VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7)
+			RETURNING product_id
+		`,
+			product.SKU,
+			product.Name,
+			product.Weigth,
+			product.Description,
+			product.Price.Amount,
+			product.Price.Currency,
+			product.Active,
+		).Scan(&product.ProductId)
+		if err != nil {
+			return false, fmt.Errorf("insert product: %w", err)
+		}
+		return true, nil
+	}
+
+	// Category, attributes and stock are managed in the CRM and left alone,
+	// an empty description or zero weight keeps the current one
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products
+		SET sku = COALESCE(NULLIF($1, ''), sku), name = $2, weight = COALESCE(NULLIF($3::numeric, 0), weight),
+			description = COALESCE(NULLIF($4, ''), description), price = $5, currency = $6,
+			active = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE product_id = $8
+	`,
+		product.SKU,
+		product.Name,
+		product.Weigth,
+		product.Description,
+		product.Price.Amount,
+		product.Price.Currency,
+		product.Active,
+		productId,
+	)
+	if err != nil {
+		return false, fmt.Errorf("update product: %w", err)
+	}
+	product.ProductId = productId
+
+	return false, nil
+}
+
+// matchProduct returns the id of the product to update or an empty string
+// when a new product has to be created
+func matchProduct(ctx context.Context, tx *sql.Tx, product *model.Product) (string, error) {
+	if product.SKU != "" {
+		var productId string
+		err := tx.QueryRowContext(ctx,
+			`SELECT product_id FROM products WHERE sku = $1`,
+			product.SKU,
+		).Scan(&productId)
+		if err == nil {
+			return productId, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("find product by sku: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT product_id
+		FROM products
+		WHERE lower(name) = lower($1) AND ($2 = '' OR sku IS NULL)
+		LIMIT 2
+	`, product.Name, product.SKU)
+	if err != nil {
+		return "", fmt.Errorf("find product by name: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var productId string
+		if err := rows.Scan(&productId); err != nil {
+			return "", fmt.Errorf("find product by name: %w", err)
+		}
+		ids = append(ids, productId)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("find product by name: %w", err)
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", nil
+	case 1:
+		return ids[0], nil
+	default:
+		return "", products.ErrAmbiguousProduct
+	}
+}
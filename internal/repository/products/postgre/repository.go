@@ -1,91 +1,238 @@
 package postgre
 
 import (
+	"backend_crm/internal/database"
 	"backend_crm/internal/model"
 	"backend_crm/internal/repository/products"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type repository struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
 }
 
-func NewRepository(db *sql.DB) products.Repository {
-	return &repository{db: db}
+func NewRepository(db *sql.DB, queryTimeout time.Duration) products.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
 }
 
 func (r *repository) Save(ctx context.Context, product *model.Product) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO products (name, weight, description)
-		VALUES ($1, $2, $3)
+		INSERT INTO products (name, weight, description, price, currency, category_id, attributes, active, sku)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::uuid, $7, $8, NULLIF($9, ''))
 		RETURNING product_id
 	`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err = r.db.QueryRowContext(ctx, query,
 		product.Name,
 		product.Weigth,
 		product.Description,
+		product.Price.Amount,
+		product.Price.Currency,
+		product.CategoryId,
+		attributes,
+		product.Active,
+		product.SKU,
 	).Scan(&product.ProductId)
+	if database.IsForeignKeyViolation(err) {
+		return products.ErrNotFoundCategory
+	}
+	if database.IsUniqueViolation(err) {
+		return products.ErrDuplicateSKU
+	}
 
 	return err
 }
 
-func (r *repository) GetAll() ([]*model.Product, error) {
+func (r *repository) GetAll(ctx context.Context, filter model.ProductFilter) ([]*model.Product, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.CategoryId != "" {
+		args = append(args, filter.CategoryId)
+		conditions = append(conditions, `category_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT category_id FROM categories WHERE category_id = $`+strconv.Itoa(len(args))+`
+				UNION ALL
+				SELECT c.category_id FROM categories c JOIN tree t ON c.parent_id = t.category_id
+			)
+			SELECT category_id FROM tree
+		)`)
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, "active = $"+strconv.Itoa(len(args)))
+	}
+
+	where := "TRUE"
+	if len(conditions) > 0 {
+		where = strings.Join(conditions, " AND ")
+	}
+
+	return r.getProductsByFilter(ctx, where, args...)
+}
+
+func (r *repository) GetById(ctx context.Context, id string) (*model.Product, error) {
+	found, err := r.getProductsByFilter(ctx, "product_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, products.ErrNotFoundProduct
+	}
+
+	return found[0], nil
+}
+
+func (r *repository) Update(ctx context.Context, product *model.Product) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		SELECT product_id, name, weight, description
-		FROM products
+		UPDATE products
+		SET name = $1, weight = $2, description = $3, price = $4, currency = $5,
+			category_id = NULLIF($6, '')::uuid, attributes = $7, active = $8,
+			sku = NULLIF($10, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE product_id = $9
+	`
+
+	res, err := r.db.ExecContext(ctx, query,
+		product.Name,
+		product.Weigth,
+		product.Description,
+		product.Price.Amount,
+		product.Price.Currency,
+		product.CategoryId,
+		attributes,
+		product.Active,
+		product.ProductId,
+		product.SKU,
+	)
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return products.ErrNotFoundCategory
+		}
+		if database.IsUniqueViolation(err) {
+			return products.ErrDuplicateSKU
+		}
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) SetActive(ctx context.Context, id string, active bool) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE products
+		SET active = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE product_id = $2
 	`
 
-	rows, err := r.db.Query(query)
+	res, err := r.db.ExecContext(ctx, query, active, id)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) getProductsByFilter(ctx context.Context, filter string, args ...interface{}) ([]*model.Product, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT product_id, COALESCE(sku, ''), name, weight, COALESCE(description, ''), price, currency, stock, reserved,
+			   COALESCE(category_id::text, ''), attributes, active
+		FROM products
+		WHERE ` + filter + `
+		ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var products []*model.Product
+	var result []*model.Product
 	for rows.Next() {
 		var product model.Product
+		var attributes []byte
 		err := rows.Scan(
 			&product.ProductId,
+			&product.SKU,
 			&product.Name,
 			&product.Weigth,
 			&product.Description,
+			&product.Price.Amount,
+			&product.Price.Currency,
+			&product.Stock,
+			&product.Reserved,
+			&product.CategoryId,
+			&attributes,
+			&product.Active,
 		)
 		if err != nil {
 			return nil, err
 		}
-		products = append(products, &product)
+		if err := json.Unmarshal(attributes, &product.Attributes); err != nil {
+			return nil, fmt.Errorf("unmarshal attributes: %w", err)
+		}
+		result = append(result, &product)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return products, nil
+	return result, nil
 }
 
-func (r *repository) GetById(id string) (*model.Product, error) {
-	query := `
-		SELECT product_id, name, weight, description
-		FROM products
-		WHERE product_id = $1
-	`
+func marshalAttributes(attributes model.Attributes) ([]byte, error) {
+	if attributes == nil {
+		attributes = model.Attributes{}
+	}
 
-	var product model.Product
-	err := r.db.QueryRow(query, id).Scan(
-		&product.ProductId,
-		&product.Name,
-		&product.Weigth,
-		&product.Description,
-	)
+	b, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("marshal attributes: %w", err)
+	}
+
+	return b, nil
+}
 
+func expectOne(res sql.Result) error {
+	n, err := res.RowsAffected()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
+		return err
+	}
+	if n == 0 {
+		return products.ErrNotFoundProduct
 	}
 
-	return &product, nil
+	return nil
 }
@@ -0,0 +1,107 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/products"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (r *repository) AdjustStock(ctx context.Context, adjustment *model.StockAdjustment) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var stock, reserved int
+	err = tx.QueryRowContext(ctx,
+		`SELECT stock, reserved FROM products WHERE product_id = $1 FOR UPDATE`,
+		adjustment.ProductId,
+	).Scan(&stock, &reserved)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return products.ErrNotFoundProduct
+		}
+		return fmt.Errorf("lock product: %w", err)
+	}
+
+	// Reserved goods cannot be written off
+	stock += adjustment.Quantity
+	if stock < reserved {
+		return products.ErrInsufficientStock
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE products
+		SET stock = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE product_id = $2
+	`, stock, adjustment.ProductId); err != nil {
+		return fmt.Errorf("update stock: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_movements (product_id, user_id, kind, quantity, note)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5)
+	`,
+		adjustment.ProductId,
+		adjustment.UserId,
+		model.StockAdjusted,
+		adjustment.Quantity,
+		adjustment.Note,
+	); err != nil {
+		return fmt.Errorf("insert stock movement: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) GetStockMovements(ctx context.Context, productId string) ([]*model.StockMovement, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT movement_id, product_id, COALESCE(order_id::text, ''), COALESCE(user_id::text, ''),
+			   kind, quantity, note, created_at
+		FROM stock_movements
+		WHERE product_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []*model.StockMovement
+	for rows.Next() {
+		var movement model.StockMovement
+		err := rows.Scan(
+			&movement.MovementId,
+			&movement.ProductId,
+			&movement.OrderId,
+			&movement.UserId,
+			&movement.Kind,
+			&movement.Quantity,
+			&movement.Note,
+			&movement.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, &movement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
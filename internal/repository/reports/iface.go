@@ -0,0 +1,32 @@
+package reports
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAlreadyClaimed = errors.New("report run already claimed")
+	// ErrNotClaimed is returned for an attempt that no longer owns its run,
+	// because its lease ran out and the run was claimed again
+	ErrNotClaimed = errors.New("report run not claimed by this attempt")
+)
+
+// Repository records the scheduled report runs, so that every run is
+// delivered by one instance and failed runs are tried again
+type Repository interface {
+	// Claim records the run of the named report due at as its first
+	// attempt. Only one instance gets it, the others get
+	// ErrAlreadyClaimed. The run is not due for a retry before lease.
+	Claim(ctx context.Context, name string, at time.Time, lease time.Duration) (*model.ReportRun, error)
+	// ClaimDue returns up to limit pending runs that are due for a retry,
+	// oldest first, counts an attempt for each and hides them for lease
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.ReportRun, error)
+	MarkSent(ctx context.Context, run *model.ReportRun) error
+	// Retry schedules the next attempt of a pending run
+	Retry(ctx context.Context, run *model.ReportRun, at time.Time, lastError string) error
+	// Finish gives up on a run
+	Finish(ctx context.Context, run *model.ReportRun, lastError string) error
+}
@@ -0,0 +1,155 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/reports"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) reports.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Claim(ctx context.Context, name string, at time.Time, lease time.Duration) (*model.ReportRun, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO report_runs (name, due_at, attempts, next_attempt_at)
+		VALUES ($1, $2, 1, CURRENT_TIMESTAMP + $3 * interval '1 millisecond')
+		ON CONFLICT (name, due_at) DO NOTHING
+	`
+
+	res, err := r.db.ExecContext(ctx, query, name, at, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, reports.ErrAlreadyClaimed
+	}
+
+	return &model.ReportRun{Name: name, DueAt: at, Attempts: 1}, nil
+}
+
+func (r *repository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.ReportRun, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// SKIP LOCKED lets several server instances claim side by side
+	query := `
+		UPDATE report_runs
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $3 * interval '1 millisecond'
+		WHERE (name, due_at) IN (
+			SELECT name, due_at
+			FROM report_runs
+			WHERE status = $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING name, due_at, attempts
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, model.DeliveryPending, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.ReportRun
+	for rows.Next() {
+		var run model.ReportRun
+		if err := rows.Scan(&run.Name, &run.DueAt, &run.Attempts); err != nil {
+			return nil, err
+		}
+		result = append(result, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) MarkSent(ctx context.Context, run *model.ReportRun) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE report_runs
+		SET status = $1, last_error = '', sent_at = CURRENT_TIMESTAMP
+		WHERE name = $2 AND due_at = $3 AND attempts = $4 AND status = $5
+	`
+
+	res, err := r.db.ExecContext(ctx, query, model.DeliverySent, run.Name, run.DueAt, run.Attempts, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Retry(ctx context.Context, run *model.ReportRun, at time.Time, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE report_runs
+		SET next_attempt_at = $1, last_error = $2
+		WHERE name = $3 AND due_at = $4 AND attempts = $5 AND status = $6
+	`
+
+	res, err := r.db.ExecContext(ctx, query, at, lastError, run.Name, run.DueAt, run.Attempts, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Finish(ctx context.Context, run *model.ReportRun, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE report_runs
+		SET status = $1, last_error = $2
+		WHERE name = $3 AND due_at = $4 AND attempts = $5 AND status = $6
+	`
+
+	res, err := r.db.ExecContext(ctx, query, model.DeliveryFailed, lastError, run.Name, run.DueAt, run.Attempts, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+// expectOne reports ErrNotClaimed when the attempt lost its run
+func expectOne(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return reports.ErrNotClaimed
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package search
+
+import (
+	"backend_crm/internal/model"
+	"context"
+)
+
+type Repository interface {
+	// Search returns the best matching orders, customers and products,
+	// highest rank first
+	Search(ctx context.Context, query model.SearchQuery) ([]*model.SearchHit, error)
+}
@@ -0,0 +1,152 @@
+package postgre
+
+import (
+	"backend_crm/internal/contact"
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/search"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// minPhoneDigits avoids matching nearly every phone number on short input
+const minPhoneDigits = 4
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) search.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (r *repository) Search(ctx context.Context, query model.SearchQuery) ([]*model.SearchHit, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tsQuery := prefixQuery(query.Text)
+	phone := phoneDigits(query.Text)
+	if tsQuery == "" && phone == "" {
+		return nil, nil
+	}
+
+	args := []interface{}{tsQuery, phone, query.Limit}
+	orderFilter := "TRUE"
+	customerFilter := "TRUE"
+	if query.UserId != "" {
+		args = append(args, query.UserId)
+		orderFilter = "o.user_id = $4"
+		customerFilter = "EXISTS (SELECT 1 FROM orders uo WHERE uo.customer_id = c.customer_id AND uo.user_id = $4)"
+	}
+	productFilter := "p.active"
+	if query.IncludeInactive {
+		productFilter = "TRUE"
+	}
+
+	sqlQuery := `
+		WITH q AS (SELECT to_tsquery('simple', $1) AS query)
+		SELECT kind, id, title, subtitle, rank
+		FROM (
+			SELECT 'order' AS kind, o.order_id::text AS id,
+				   COALESCE(NULLIF(c.name, ''), o.phone) AS title,
+				   COALESCE(o.description, '') AS subtitle,
+				   ts_rank(o.search_vector, q.query) +
+				   CASE WHEN $2 <> '' AND o.phone_digits LIKE '%' || $2 || '%' THEN 1 ELSE 0 END AS rank
+			FROM orders o
+			CROSS JOIN q
+			LEFT JOIN customers c ON c.customer_id = o.customer_id
+			WHERE (o.search_vector @@ q.query OR ($2 <> '' AND o.phone_digits LIKE '%' || $2 || '%'))
+			  AND ` + orderFilter + `
+
+			UNION ALL
+
+			SELECT 'customer', c.customer_id::text,
+				   COALESCE(NULLIF(c.name, ''), array_to_string(c.phones, ', ')),
+				   array_to_string(c.phones || c.emails, ', '),
+				   ts_rank(c.search_vector, q.query) +
+				   CASE WHEN $2 <> '' AND c.phone_digits LIKE '%' || $2 || '%' THEN 1 ELSE 0 END
+			FROM customers c
+			CROSS JOIN q
+			WHERE (c.search_vector @@ q.query OR ($2 <> '' AND c.phone_digits LIKE '%' || $2 || '%'))
+			  AND ` + customerFilter + `
+
+			UNION ALL
+
+			SELECT 'product', p.product_id::text, p.name, COALESCE(p.description, ''),
+				   ts_rank(p.search_vector, q.query)
+			FROM products p
+			CROSS JOIN q
+			WHERE p.search_vector @@ q.query
+			  AND ` + productFilter + `
+		) hits
+		ORDER BY rank DESC, title
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.SearchHit
+	for rows.Next() {
+		var hit model.SearchHit
+		if err := rows.Scan(
+			&hit.Kind,
+			&hit.Id,
+			&hit.Title,
+			&hit.Subtitle,
+			&hit.Rank,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, &hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// prefixQuery turns user input into a tsquery matching documents that
+// contain every word, each as a prefix. Words are quoted so operators in
+// the input are taken literally.
+func prefixQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		if !strings.ContainsFunc(word, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) {
+			continue
+		}
+		word = strings.ReplaceAll(word, `\`, `\\`)
+		word = strings.ReplaceAll(word, `'`, `''`)
+		terms = append(terms, "'"+word+"':*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// phoneDigits returns the digits to look for in phone numbers if the
+// input looks like a (partial) phone number
+func phoneDigits(text string) string {
+	for _, r := range text {
+		if !unicode.IsDigit(r) && !strings.ContainsRune(" +-()", r) {
+			return ""
+		}
+	}
+
+	digits := contact.NormalizePhone(text)
+	if len(digits) < minPhoneDigits {
+		return ""
+	}
+	return digits
+}
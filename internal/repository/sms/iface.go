@@ -0,0 +1,35 @@
+package sms
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFoundSMS = errors.New("not found sms")
+
+// Repository is the SMS outbox, it works like the email outbox
+type Repository interface {
+	// Enqueue queues the message about an event to the order phone, unless
+	// the customer opted out or gave none, see the email outbox
+	Enqueue(ctx context.Context, eventId string, orderId string, event model.OrderEvent) error
+	// Claim returns up to limit pending messages that are due, oldest
+	// first, counts an attempt for each and hides them for lease
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxSMS, error)
+	// MarkSent records the number the message went to and the id the
+	// provider gave it
+	MarkSent(ctx context.Context, smsId string, recipient string, providerMessageId string) error
+	// Retry schedules the next attempt of a pending message
+	Retry(ctx context.Context, smsId string, at time.Time, lastError string) error
+	// Postpone moves a claimed message to at without counting the attempt,
+	// e.g. when the recipient has had enough messages for now
+	Postpone(ctx context.Context, smsId string, at time.Time, reason string) error
+	// Finish gives up on a message with status failed or skipped
+	Finish(ctx context.Context, smsId string, status model.DeliveryStatus, lastError string) error
+	// SentSince counts the messages sent to recipient since the time and
+	// returns when the oldest of them was sent
+	SentSince(ctx context.Context, recipient string, since time.Time) (int, time.Time, error)
+	// GetByOrderId lists the messages of an order, oldest first
+	GetByOrderId(ctx context.Context, orderId string) ([]*model.OutboxSMS, error)
+}
@@ -0,0 +1,226 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/sms"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) sms.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+const smsColumns = `sms_id, order_id, event, recipient, status, attempts, next_attempt_at,
+	last_error, provider_message_id, sent_at, created_at`
+
+func (r *repository) Enqueue(ctx context.Context, eventId string, orderId string, event model.OrderEvent) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO sms_outbox (event_id, order_id, event, recipient)
+		SELECT $1, order_id, $3, phone_digits
+		FROM orders
+		WHERE order_id = $2 AND NOT sms_opt_out AND phone_digits <> ''
+		ON CONFLICT (event_id) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, eventId, orderId, event)
+	return err
+}
+
+func (r *repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxSMS, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE sms_outbox
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $3 * interval '1 millisecond'
+		WHERE sms_id IN (
+			SELECT sms_id
+			FROM sms_outbox
+			WHERE status = $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + smsColumns
+
+	rows, err := r.db.QueryContext(ctx, query, model.DeliveryPending, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSMS(rows)
+}
+
+func (r *repository) MarkSent(ctx context.Context, smsId string, recipient string, providerMessageId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE sms_outbox
+		SET status = $1, recipient = $2, provider_message_id = $3, last_error = '', sent_at = CURRENT_TIMESTAMP
+		WHERE sms_id = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query, model.DeliverySent, recipient, providerMessageId, smsId)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Retry(ctx context.Context, smsId string, at time.Time, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE sms_outbox
+		SET next_attempt_at = $1, last_error = $2
+		WHERE sms_id = $3 AND status = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query, at, lastError, smsId, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Postpone(ctx context.Context, smsId string, at time.Time, reason string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE sms_outbox
+		SET next_attempt_at = $1, last_error = $2, attempts = GREATEST(attempts - 1, 0)
+		WHERE sms_id = $3 AND status = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query, at, reason, smsId, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) Finish(ctx context.Context, smsId string, status model.DeliveryStatus, lastError string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE sms_outbox
+		SET status = $1, last_error = $2
+		WHERE sms_id = $3
+	`
+
+	res, err := r.db.ExecContext(ctx, query, status, lastError, smsId)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res)
+}
+
+func (r *repository) SentSince(ctx context.Context, recipient string, since time.Time) (int, time.Time, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT count(*), min(sent_at)
+		FROM sms_outbox
+		WHERE recipient = $1 AND status = $2 AND sent_at >= $3
+	`
+
+	var count int
+	var oldest sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, recipient, model.DeliverySent, since).Scan(&count, &oldest)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return count, oldest.Time, nil
+}
+
+func (r *repository) GetByOrderId(ctx context.Context, orderId string) ([]*model.OutboxSMS, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + smsColumns + `
+		FROM sms_outbox
+		WHERE order_id = $1
+		ORDER BY created_at, sms_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		if database.IsInvalidText(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return scanSMS(rows)
+}
+
+func scanSMS(rows *sql.Rows) ([]*model.OutboxSMS, error) {
+	defer rows.Close()
+
+	var result []*model.OutboxSMS
+	for rows.Next() {
+		var msg model.OutboxSMS
+		var sentAt sql.NullTime
+		err := rows.Scan(
+			&msg.SMSId,
+			&msg.OrderId,
+			&msg.Event,
+			&msg.Recipient,
+			&msg.Status,
+			&msg.Attempts,
+			&msg.NextAttemptAt,
+			&msg.LastError,
+			&msg.ProviderMessageId,
+			&sentAt,
+			&msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		msg.SentAt = sentAt.Time
+		result = append(result, &msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func expectOne(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sms.ErrNotFoundSMS
+	}
+
+	return nil
+}
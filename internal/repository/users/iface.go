@@ -12,5 +12,6 @@ var (
 
 type Repository interface {
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetById(ctx context.Context, userId string) (*model.User, error)
 	Save(ctx context.Context, register *model.Register) error
 }
@@ -1,22 +1,31 @@
 package postgre
 
 import (
+	"backend_crm/internal/database"
 	"backend_crm/internal/model"
 	"backend_crm/internal/repository/users"
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
 type repository struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
 }
 
-func NewRepository(db *sql.DB) users.Repository {
-	return &repository{db: db}
+func NewRepository(db *sql.DB, queryTimeout time.Duration) users.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
 }
 
 func (r *repository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	query := `
 		SELECT user_id, role, username, pass_hash
 		FROM users
@@ -41,7 +50,38 @@ func (r *repository) GetByUsername(ctx context.Context, username string) (*model
 	return &user, nil
 }
 
+func (r *repository) GetById(ctx context.Context, userId string) (*model.User, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT user_id, role, username, pass_hash
+		FROM users
+		WHERE user_id = $1
+	`
+
+	var user model.User
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(
+		&user.UserId,
+		&user.Role,
+		&user.Username,
+		&user.PassHash,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, users.ErrNotFoundUser
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (r *repository) Save(ctx context.Context, register *model.Register) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	query := `
 		INSERT INTO users (role, username, pass_hash)
 		VALUES ($1, $2, $3)
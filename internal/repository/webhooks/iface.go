@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFoundWebhook  = errors.New("not found webhook")
+	ErrNotFoundDelivery = errors.New("not found webhook delivery")
+	// ErrNotClaimed is returned for an attempt that no longer owns its
+	// delivery, because its lease ran out and the delivery was claimed again
+	ErrNotClaimed = errors.New("webhook delivery not claimed by this attempt")
+)
+
+// Repository keeps the webhook subscriptions and their deliveries.
+// Deliveries are queued by the subscriber of the order events and handled
+// like the email outbox.
+type Repository interface {
+	Save(ctx context.Context, webhook *model.Webhook) error
+	GetAll(ctx context.Context) ([]*model.Webhook, error)
+	GetById(ctx context.Context, webhookId string) (*model.Webhook, error)
+	// Update changes url, events, description, active and secret
+	Update(ctx context.Context, webhook *model.Webhook) error
+	// Delete removes the webhook with its deliveries
+	Delete(ctx context.Context, webhookId string) error
+
+	// Enqueue queues a delivery of the event to every active webhook
+	// subscribed to it. Webhooks that already have the event are skipped,
+	// so the event may be handled more than once.
+	Enqueue(ctx context.Context, event *model.DomainEvent) error
+	// Claim returns up to limit pending deliveries that are due, oldest
+	// first, counts an attempt for each and hides them for lease
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	// MarkSent, Retry and Finish record the outcome of the attempt the
+	// delivery was claimed for. They return ErrNotClaimed when the delivery
+	// was claimed again since, the later attempt records its own outcome.
+	MarkSent(ctx context.Context, deliveryId string, attempt int, response *model.WebhookResponse) error
+	// Retry schedules the next attempt of a pending delivery. response is
+	// nil when the request failed without one.
+	Retry(ctx context.Context, deliveryId string, attempt int, at time.Time, lastError string, response *model.WebhookResponse) error
+	// Finish gives up on a delivery with status failed or skipped
+	Finish(ctx context.Context, deliveryId string, attempt int, status model.DeliveryStatus, lastError string, response *model.WebhookResponse) error
+	// GetDeliveries lists the deliveries of a webhook, newest first,
+	// created before the given time unless it is zero
+	GetDeliveries(ctx context.Context, webhookId string, before time.Time, limit int) ([]*model.WebhookDelivery, error)
+	// Redeliver queues the event of a delivery again as a new delivery
+	Redeliver(ctx context.Context, webhookId string, deliveryId string) (*model.WebhookDelivery, error)
+}
@@ -0,0 +1,238 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/webhooks"
+	"context"
+	"database/sql"
+	"time"
+)
+
+const deliveryColumns = `delivery_id, webhook_id, event_id, event, order_id, data, COALESCE(redelivery_of::text, ''),
+	status, attempts, next_attempt_at, last_error, response_status, response_body, duration_ms, sent_at, created_at`
+
+func (r *repository) Enqueue(ctx context.Context, event *model.DomainEvent) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	data := string(event.Data)
+	if data == "" {
+		data = "{}"
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, order_id, data)
+		SELECT webhook_id, $1, $2, $3, $4
+		FROM webhooks
+		WHERE active AND $2 = ANY(events)
+		ON CONFLICT (webhook_id, event_id) WHERE redelivery_of IS NULL DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, event.EventId, event.Type, event.OrderId, data)
+	return err
+}
+
+func (r *repository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + $3 * interval '1 millisecond'
+		WHERE delivery_id IN (
+			SELECT delivery_id
+			FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+
+	rows, err := r.db.QueryContext(ctx, query, model.DeliveryPending, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+// The outcome of an attempt is only recorded while the delivery is still
+// pending with the attempts of the claim, so an instance that outlived its
+// lease cannot overwrite the log of the attempt that took over.
+
+func (r *repository) MarkSent(ctx context.Context, deliveryId string, attempt int, response *model.WebhookResponse) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	status, body, duration := responseColumns(response)
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, last_error = '', response_status = $2, response_body = $3, duration_ms = $4,
+			sent_at = CURRENT_TIMESTAMP
+		WHERE delivery_id = $5 AND attempts = $6 AND status = $7
+	`
+
+	res, err := r.db.ExecContext(ctx, query, model.DeliverySent, status, body, duration, deliveryId, attempt, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res, webhooks.ErrNotClaimed)
+}
+
+func (r *repository) Retry(ctx context.Context, deliveryId string, attempt int, at time.Time, lastError string, response *model.WebhookResponse) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	status, body, duration := responseColumns(response)
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $1, last_error = $2, response_status = $3, response_body = $4, duration_ms = $5
+		WHERE delivery_id = $6 AND attempts = $7 AND status = $8
+	`
+
+	res, err := r.db.ExecContext(ctx, query, at, lastError, status, body, duration, deliveryId, attempt, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res, webhooks.ErrNotClaimed)
+}
+
+func (r *repository) Finish(ctx context.Context, deliveryId string, attempt int, status model.DeliveryStatus, lastError string, response *model.WebhookResponse) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	responseStatus, body, duration := responseColumns(response)
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, last_error = $2, response_status = $3, response_body = $4, duration_ms = $5
+		WHERE delivery_id = $6 AND attempts = $7 AND status = $8
+	`
+
+	res, err := r.db.ExecContext(ctx, query, status, lastError, responseStatus, body, duration, deliveryId, attempt, model.DeliveryPending)
+	if err != nil {
+		return err
+	}
+
+	return expectOne(res, webhooks.ErrNotClaimed)
+}
+
+func (r *repository) GetDeliveries(ctx context.Context, webhookId string, before time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var beforeArg *time.Time
+	if !before.IsZero() {
+		beforeArg = &before
+	}
+
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::timestamptz IS NULL OR created_at < $2)
+		ORDER BY created_at DESC, delivery_id
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, webhookId, beforeArg, limit)
+	if err != nil {
+		if database.IsInvalidText(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+func (r *repository) Redeliver(ctx context.Context, webhookId string, deliveryId string) (*model.WebhookDelivery, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, order_id, data, redelivery_of)
+		SELECT webhook_id, event_id, event, order_id, data, delivery_id
+		FROM webhook_deliveries
+		WHERE delivery_id = $1 AND webhook_id = $2
+		RETURNING ` + deliveryColumns
+
+	rows, err := r.db.QueryContext(ctx, query, deliveryId, webhookId)
+	if err != nil {
+		if database.IsInvalidText(err) {
+			return nil, webhooks.ErrNotFoundDelivery
+		}
+		return nil, err
+	}
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, webhooks.ErrNotFoundDelivery
+	}
+
+	return deliveries[0], nil
+}
+
+// responseColumns splits a response into the nullable status and duration
+// columns and the body
+func responseColumns(response *model.WebhookResponse) (sql.NullInt64, string, sql.NullInt64) {
+	if response == nil {
+		return sql.NullInt64{}, "", sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(response.Status), Valid: true},
+		response.Body,
+		sql.NullInt64{Int64: response.Duration.Milliseconds(), Valid: true}
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*model.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var result []*model.WebhookDelivery
+	for rows.Next() {
+		var delivery model.WebhookDelivery
+		var responseStatus, duration sql.NullInt64
+		var responseBody string
+		var sentAt sql.NullTime
+		err := rows.Scan(
+			&delivery.DeliveryId,
+			&delivery.WebhookId,
+			&delivery.EventId,
+			&delivery.Event,
+			&delivery.OrderId,
+			&delivery.Data,
+			&delivery.RedeliveryOf,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastError,
+			&responseStatus,
+			&responseBody,
+			&duration,
+			&sentAt,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if responseStatus.Valid {
+			delivery.Response = &model.WebhookResponse{
+				Status:   int(responseStatus.Int64),
+				Body:     responseBody,
+				Duration: time.Duration(duration.Int64) * time.Millisecond,
+			}
+		}
+		delivery.SentAt = sentAt.Time
+		result = append(result, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package postgre
+
+import (
+	"backend_crm/internal/database"
+	"backend_crm/internal/model"
+	"backend_crm/internal/repository/webhooks"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type repository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRepository(db *sql.DB, queryTimeout time.Duration) webhooks.Repository {
+	return &repository{
+		db:           db,
+		queryTimeout: queryTimeout,
+	}
+}
+
+const webhookColumns = `webhook_id, url, events, secret, description, active, created_at, updated_at`
+
+func (r *repository) Save(ctx context.Context, webhook *model.Webhook) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO webhooks (url, events, secret, description, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING webhook_id, created_at, updated_at
+	`
+
+	return r.db.QueryRowContext(ctx, query,
+		webhook.URL,
+		pq.Array(webhook.Events),
+		webhook.Secret,
+		webhook.Description,
+		webhook.Active,
+	).Scan(&webhook.WebhookId, &webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]*model.Webhook, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		ORDER BY created_at, webhook_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *repository) GetById(ctx context.Context, webhookId string) (*model.Webhook, error) {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE webhook_id = $1
+	`
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhookId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || database.IsInvalidText(err) {
+			return nil, webhooks.ErrNotFoundWebhook
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (r *repository) Update(ctx context.Context, webhook *model.Webhook) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE webhooks
+		SET url = $1, events = $2, secret = $3, description = $4, active = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE webhook_id = $6
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		webhook.URL,
+		pq.Array(webhook.Events),
+		webhook.Secret,
+		webhook.Description,
+		webhook.Active,
+		webhook.WebhookId,
+	).Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) || database.IsInvalidText(err) {
+		return webhooks.ErrNotFoundWebhook
+	}
+
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, webhookId string) error {
+	ctx, cancel := database.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE webhook_id = $1`, webhookId)
+	if err != nil {
+		if database.IsInvalidText(err) {
+			return webhooks.ErrNotFoundWebhook
+		}
+		return err
+	}
+
+	return expectOne(res, webhooks.ErrNotFoundWebhook)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := row.Scan(
+		&webhook.WebhookId,
+		&webhook.URL,
+		pq.Array(&webhook.Events),
+		&webhook.Secret,
+		&webhook.Description,
+		&webhook.Active,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func expectOne(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"empty", ""},
+		{"four fields", "* * * *"},
+		{"six fields", "0 * * * * *"},
+		{"minute too large", "60 * * * *"},
+		{"hour too large", "* 24 * * *"},
+		{"day of month zero", "* * 0 * *"},
+		{"month too large", "* * * 13 *"},
+		{"day of week too large", "* * * * 8"},
+		{"zero step", "*/0 * * * *"},
+		{"negative step", "*/-5 * * * *"},
+		{"reversed range", "5-1 * * * *"},
+		{"not a number", "a * * * *"},
+		{"bad range end", "1-x * * * *"},
+		{"empty list item", "1,,2 * * * *"},
+		{"unknown shortcut", "@yearly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.spec); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", tt.spec)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	local := func(s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"later the same day", "0 8 * * 1", utc("2026-10-19 07:59"), utc("2026-10-19 08:00")},
+		{"strictly after", "0 8 * * 1", utc("2026-10-19 08:00"), utc("2026-10-26 08:00")},
+		{"seconds are dropped", "0 8 * * 1", utc("2026-10-19 07:59").Add(30 * time.Second), utc("2026-10-19 08:00")},
+		{"step", "*/15 * * * *", utc("2026-10-19 10:07"), utc("2026-10-19 10:15")},
+		{"step wraps the hour", "*/15 * * * *", utc("2026-10-19 10:45"), utc("2026-10-19 11:00")},
+		{"range with step", "0 9-17/4 * * *", utc("2026-10-19 10:00"), utc("2026-10-19 13:00")},
+		{"value with step", "0 10/6 * * *", utc("2026-10-19 17:00"), utc("2026-10-19 22:00")},
+		{"list", "5,40 * * * *", utc("2026-10-19 10:06"), utc("2026-10-19 10:40")},
+		{"first of next month", "0 0 1 * *", utc("2026-01-31 12:00"), utc("2026-02-01 00:00")},
+		{"first of next year", "@monthly", utc("2026-12-15 00:00"), utc("2027-01-01 00:00")},
+		{"hourly", "@hourly", utc("2026-10-19 10:00"), utc("2026-10-19 11:00")},
+		{"daily", "@daily", utc("2026-10-19 10:00"), utc("2026-10-20 00:00")},
+		{"weekly is Monday", "@weekly", utc("2026-10-25 12:00"), utc("2026-10-26 00:00")},
+		{"seven is Sunday", "0 0 * * 7", utc("2026-10-19 00:00"), utc("2026-10-25 00:00")},
+		{"zero is Sunday", "0 0 * * 0", utc("2026-10-19 00:00"), utc("2026-10-25 00:00")},
+		{"either day, weekday first", "0 0 10 * 5", utc("2026-11-01 00:00"), utc("2026-11-06 00:00")},
+		{"either day, day of month first", "0 0 10 * 5", utc("2026-11-07 00:00"), utc("2026-11-10 00:00")},
+		{"weekday alone", "0 0 * * 5", utc("2026-11-07 00:00"), utc("2026-11-13 00:00")},
+		{"day of month alone", "0 0 10 * *", utc("2026-11-11 00:00"), utc("2026-12-10 00:00")},
+		{"day missing in short months", "0 0 31 * *", utc("2026-04-01 00:00"), utc("2026-05-31 00:00")},
+		{"leap day", "30 2 29 2 *", utc("2026-03-01 00:00"), utc("2028-02-29 02:30")},
+		{"never", "0 0 30 2 *", utc("2026-01-01 00:00"), time.Time{}},
+		{"in the location of t", "0 8 * * *", local("2026-10-19 09:00"), local("2026-10-20 08:00")},
+		{"hour skipped by the clock change", "30 2 * * *", local("2026-03-29 00:00"), local("2026-03-30 02:30")},
+		{"after the clock change", "0 3 * * *", local("2026-03-29 00:00"), local("2026-03-29 03:00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			got := s.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+			if !got.IsZero() && got.Location() != tt.from.Location() {
+				t.Errorf("Next(%v) is in %v, want %v", tt.from, got.Location(), tt.from.Location())
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Job is run at the scheduled time with the time it was due
+type Job func(ctx context.Context, at time.Time) error
+
+type entry struct {
+	name     string
+	schedule *Schedule
+	job      Job
+	next     time.Time
+}
+
+// Scheduler runs jobs one after another in a single goroutine. A job that
+// is still running when another one is due delays it; runs missed while
+// the process was down are not caught up.
+type Scheduler struct {
+	loc     *time.Location
+	logger  zerolog.Logger
+	entries []*entry
+}
+
+// New returns a scheduler reading cron expressions in loc
+func New(loc *time.Location, logger zerolog.Logger) *Scheduler {
+	return &Scheduler{
+		loc:    loc,
+		logger: logger,
+	}
+}
+
+// Add registers a job. It must be called before Run.
+func (s *Scheduler) Add(name, spec string, job Job) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	if _, err := schedule.next(time.Now().In(s.loc)); err != nil {
+		return fmt.Errorf("cron %q: %w", spec, err)
+	}
+
+	s.entries = append(s.entries, &entry{
+		name:     name,
+		schedule: schedule,
+		job:      job,
+	})
+	return nil
+}
+
+// Run waits for the jobs to become due until ctx is done. The context
+// passed to jobs is ctx, so a running job is cancelled on shutdown.
+func (s *Scheduler) Run(ctx context.Context) {
+	now := time.Now().In(s.loc)
+	for _, e := range s.entries {
+		e.next = e.schedule.Next(now)
+		s.logger.Info().Str("job", e.name).Time("next", e.next).Msg("job scheduled")
+	}
+
+	for {
+		var due *entry
+		for _, e := range s.entries {
+			if !e.next.IsZero() && (due == nil || e.next.Before(due.next)) {
+				due = e
+			}
+		}
+		if due == nil {
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(due.next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		at := due.next
+		due.next = due.schedule.Next(time.Now().In(s.loc))
+		s.run(ctx, due, at)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, e *entry, at time.Time) {
+	start := time.Now()
+	if err := e.job(ctx, at); err != nil {
+		s.logger.Error().Err(err).Str("job", e.name).Time("at", at).Msg("job failed")
+		return
+	}
+	s.logger.Info().Str("job", e.name).Dur("took", time.Since(start)).Time("next", e.next).Msg("job finished")
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"backend_crm/internal/blob"
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ServeBlob streams obj as the response body and takes ownership of it.
+//
+// etag must change whenever the content does; blobs are never rewritten in
+// place, so clients may cache them for good. Conditional requests are
+// answered with 304 and a single byte range with 206. Multiple ranges are
+// not supported and fall back to the full content.
+func ServeBlob(ctx *fasthttp.RequestCtx, obj blob.Object, contentType string, etag string) {
+	quoted := strconv.Quote(etag)
+	setCacheHeaders := func() {
+		ctx.Response.Header.Set(fasthttp.HeaderETag, quoted)
+		ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "private, max-age=31536000, immutable")
+		ctx.Response.Header.Set(fasthttp.HeaderAcceptRanges, "bytes")
+		ctx.Response.Header.Set(fasthttp.HeaderLastModified, obj.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	if etagMatches(ctx.Request.Header.Peek(fasthttp.HeaderIfNoneMatch), quoted) {
+		obj.Close()
+		ctx.NotModified()
+		setCacheHeaders()
+		return
+	}
+
+	setCacheHeaders()
+	ctx.SetContentType(contentType)
+
+	size := int(obj.Size())
+	start, end := 0, size-1
+	status := fasthttp.StatusOK
+
+	byteRange := ctx.Request.Header.Peek(fasthttp.HeaderRange)
+	ifRange := ctx.Request.Header.Peek(fasthttp.HeaderIfRange)
+	if len(byteRange) > 0 && bytes.IndexByte(byteRange, ',') < 0 &&
+		(len(ifRange) == 0 || string(ifRange) == quoted) {
+		var err error
+		start, end, err = fasthttp.ParseByteRange(byteRange, size)
+		if err != nil {
+			obj.Close()
+			ctx.Error("Requested range not satisfiable", fasthttp.StatusRequestedRangeNotSatisfiable)
+			ctx.Response.Header.Set(fasthttp.HeaderContentRange, "bytes */"+strconv.Itoa(size))
+			return
+		}
+		ctx.Response.Header.SetContentRange(start, end, size)
+		status = fasthttp.StatusPartialContent
+	}
+
+	if start > 0 {
+		if _, err := obj.Seek(int64(start), io.SeekStart); err != nil {
+			obj.Close()
+			ctx.Error("Error on the server", fasthttp.StatusInternalServerError)
+			return
+		}
+	}
+
+	n := end - start + 1
+	ctx.SetStatusCode(status)
+	// The response closes the body stream once it has been written
+	ctx.SetBodyStream(struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(obj, int64(n)), obj}, n)
+}
+
+// etagMatches implements the weak comparison If-None-Match asks for
+func etagMatches(header []byte, etag string) bool {
+	for _, candidate := range bytes.Split(header, []byte(",")) {
+		candidate = bytes.TrimSpace(candidate)
+		candidate = bytes.TrimPrefix(candidate, []byte("W/"))
+		if string(candidate) == "*" || string(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// memObject is a blob held in memory that records being closed
+type memObject struct {
+	*bytes.Reader
+	closed bool
+}
+
+func (o *memObject) Close() error       { o.closed = true; return nil }
+func (o *memObject) ModTime() time.Time { return time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC) }
+
+func TestServeBlob(t *testing.T) {
+	const content = "0123456789"
+
+	tests := []struct {
+		name             string
+		headers          map[string]string
+		wantStatus       int
+		wantBody         string
+		wantContentRange string
+	}{
+		{"full", nil, fasthttp.StatusOK, content, ""},
+		{"etag matches", map[string]string{"If-None-Match": `"v1"`}, fasthttp.StatusNotModified, "", ""},
+		{"weak etag matches", map[string]string{"If-None-Match": `W/"v1"`}, fasthttp.StatusNotModified, "", ""},
+		{"etag in a list", map[string]string{"If-None-Match": `"v0", "v1"`}, fasthttp.StatusNotModified, "", ""},
+		{"any etag", map[string]string{"If-None-Match": "*"}, fasthttp.StatusNotModified, "", ""},
+		{"other etag", map[string]string{"If-None-Match": `"v0"`}, fasthttp.StatusOK, content, ""},
+		{"unquoted etag", map[string]string{"If-None-Match": "v1"}, fasthttp.StatusOK, content, ""},
+		{"range", map[string]string{"Range": "bytes=2-5"}, fasthttp.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"open range", map[string]string{"Range": "bytes=7-"}, fasthttp.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"suffix range", map[string]string{"Range": "bytes=-3"}, fasthttp.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"range past the end", map[string]string{"Range": "bytes=5-100"}, fasthttp.StatusPartialContent, "56789", "bytes 5-9/10"},
+		{"first byte", map[string]string{"Range": "bytes=0-0"}, fasthttp.StatusPartialContent, "0", "bytes 0-0/10"},
+		{"unsatisfiable range", map[string]string{"Range": "bytes=20-30"}, fasthttp.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"multiple ranges", map[string]string{"Range": "bytes=0-1,4-5"}, fasthttp.StatusOK, content, ""},
+		{"if-range matches", map[string]string{"Range": "bytes=2-5", "If-Range": `"v1"`}, fasthttp.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"if-range changed", map[string]string{"Range": "bytes=2-5", "If-Range": `"v0"`}, fasthttp.StatusOK, content, ""},
+		{"etag wins over range", map[string]string{"Range": "bytes=2-5", "If-None-Match": `"v1"`}, fasthttp.StatusNotModified, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctx fasthttp.RequestCtx
+			for name, value := range tt.headers {
+				ctx.Request.Header.Set(name, value)
+			}
+			obj := &memObject{Reader: bytes.NewReader([]byte(content))}
+
+			ServeBlob(&ctx, obj, "image/png", "v1")
+
+			if got := ctx.Response.StatusCode(); got != tt.wantStatus {
+				t.Errorf("status %d, want %d", got, tt.wantStatus)
+			}
+			if tt.wantStatus != fasthttp.StatusRequestedRangeNotSatisfiable {
+				if got := string(ctx.Response.Body()); got != tt.wantBody {
+					t.Errorf("body %q, want %q", got, tt.wantBody)
+				}
+				if got := string(ctx.Response.Header.Peek(fasthttp.HeaderETag)); got != `"v1"` {
+					t.Errorf("ETag %q", got)
+				}
+			}
+			if got := string(ctx.Response.Header.Peek(fasthttp.HeaderContentRange)); got != tt.wantContentRange {
+				t.Errorf("Content-Range %q, want %q", got, tt.wantContentRange)
+			}
+			if !obj.closed {
+				t.Error("blob not closed")
+			}
+		})
+	}
+}
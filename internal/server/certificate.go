@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// CertificateStore keeps the currently served TLS certificate and allows
+// replacing it without restarting the listener.
+type CertificateStore struct {
+	cert atomic.Pointer[tls.Certificate]
+}
+
+func NewCertificateStore(certFile, keyFile string) (*CertificateStore, error) {
+	s := &CertificateStore{}
+	if err := s.Load(certFile, keyFile); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewSelfSignedCertificateStore generates a self-signed certificate in
+// memory for the given hosts. It is intended for local development only.
+func NewSelfSignedCertificateStore(hosts ...string) (*CertificateStore, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"backend_crm development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if h != "" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	s := &CertificateStore{}
+	s.cert.Store(&tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	})
+
+	return s, nil
+}
+
+// Load reads the key pair from disk and swaps it in. The previous
+// certificate stays active if the new one cannot be loaded.
+func (s *CertificateStore) Load(certFile, keyFile string) error {
+	cert, err := LoadCertificate(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	s.Set(cert)
+
+	return nil
+}
+
+// Set swaps in a certificate loaded before, e.g. once every other part of a
+// reload was checked
+func (s *CertificateStore) Set(cert *tls.Certificate) {
+	s.cert.Store(cert)
+}
+
+// LoadCertificate reads and parses a key pair without serving it
+func LoadCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load x509 key pair: %w", err)
+	}
+	return &cert, nil
+}
+
+// GetCertificate is meant to be used as tls.Config.GetCertificate
+func (s *CertificateStore) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return s.cert.Load(), nil
+}
+
+// TLSConfig returns a tls.Config that always serves the current certificate
+func (s *CertificateStore) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: s.GetCertificate,
+	}
+}
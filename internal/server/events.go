@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strconv"
+	"time"
+)
+
+// EventStream writes Server-Sent Events to a response body stream.
+//
+// fasthttp sets the write deadline once per response, so a stream that
+// stays open longer than the write timeout pushes it forward on every
+// flush instead.
+type EventStream struct {
+	w            *bufio.Writer
+	conn         net.Conn
+	writeTimeout time.Duration
+}
+
+// NewEventStream wraps the writer of fasthttp.RequestCtx.SetBodyStreamWriter.
+// conn is the connection of the request, nil to leave the deadline alone.
+func NewEventStream(w *bufio.Writer, conn net.Conn, writeTimeout time.Duration) *EventStream {
+	return &EventStream{
+		w:            w,
+		conn:         conn,
+		writeTimeout: writeTimeout,
+	}
+}
+
+// Retry tells the client how long to wait before reconnecting
+func (s *EventStream) Retry(delay time.Duration) {
+	s.w.WriteString("retry: ")
+	s.w.WriteString(strconv.FormatInt(delay.Milliseconds(), 10))
+	s.w.WriteString("\n\n")
+}
+
+// Event writes an event. An empty name leaves the client's default
+// "message", empty data only moves the id the client resumes from.
+func (s *EventStream) Event(id string, name string, data []byte) {
+	if id != "" {
+		s.w.WriteString("id: ")
+		s.w.WriteString(id)
+		s.w.WriteString("\n")
+	}
+	if name != "" {
+		s.w.WriteString("event: ")
+		s.w.WriteString(name)
+		s.w.WriteString("\n")
+	}
+	if len(data) > 0 {
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			s.w.WriteString("data: ")
+			s.w.Write(line)
+			s.w.WriteString("\n")
+		}
+	}
+	s.w.WriteString("\n")
+}
+
+// Comment writes a line clients ignore, e.g. as a heartbeat
+func (s *EventStream) Comment(text string) {
+	s.w.WriteString(": ")
+	s.w.WriteString(text)
+	s.w.WriteString("\n\n")
+}
+
+// Flush sends the buffered events. An error means the client is gone.
+func (s *EventStream) Flush() error {
+	s.extendDeadline()
+	return s.w.Flush()
+}
+
+// Close leaves the write timeout for the end of the response
+func (s *EventStream) Close() {
+	s.extendDeadline()
+}
+
+func (s *EventStream) extendDeadline() {
+	if s.conn != nil && s.writeTimeout > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// ProxyResolver determines the real client address and scheme of a request.
+// Forwarding headers are honored only when the direct peer is one of the
+// trusted proxies, otherwise anyone could spoof them.
+type ProxyResolver struct {
+	trusted []netip.Prefix
+	logger  zerolog.Logger
+}
+
+func NewProxyResolver(trusted []netip.Prefix, logger zerolog.Logger) *ProxyResolver {
+	return &ProxyResolver{trusted: trusted, logger: logger}
+}
+
+// Middleware logs every request with the resolved client address and
+// scheme, so requests passing a proxy show the real client
+func (p *ProxyResolver) Middleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next(ctx)
+
+		ip, scheme := p.Resolve(ctx)
+		p.logger.Info().
+			Str("client_ip", ip).
+			Str("scheme", scheme).
+			Bytes("method", ctx.Method()).
+			Bytes("path", ctx.Path()).
+			Int("status", ctx.Response.StatusCode()).
+			Dur("duration", time.Since(start)).
+			Msg("request")
+	}
+}
+
+// Resolve returns the client address and the scheme the client used
+func (p *ProxyResolver) Resolve(ctx *fasthttp.RequestCtx) (string, string) {
+	scheme := "http"
+	if ctx.IsTLS() {
+		scheme = "https"
+	}
+
+	remote, ok := netip.AddrFromSlice(ctx.RemoteIP())
+	if !ok {
+		return ctx.RemoteIP().String(), scheme
+	}
+	remote = remote.Unmap()
+
+	if !p.isTrusted(remote) {
+		return remote.String(), scheme
+	}
+
+	if proto := firstValue(ctx.Request.Header.Peek("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	// Walk X-Forwarded-For from the right and skip our own proxies, the
+	// first untrusted address is the client.
+	client := remote
+	hops := strings.Split(string(ctx.Request.Header.Peek("X-Forwarded-For")), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !p.isTrusted(client) {
+			break
+		}
+	}
+
+	return client.String(), scheme
+}
+
+func (p *ProxyResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range p.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstValue(header []byte) string {
+	v, _, _ := strings.Cut(string(header), ",")
+	return strings.ToLower(strings.TrimSpace(v))
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+// requestFrom builds a request arriving from the peer remote with the
+// given headers
+func requestFrom(remote string, headers map[string]string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.SetRequestURI("/api/v1/orders/0?x=1")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, &net.TCPAddr{IP: net.ParseIP(remote), Port: 50000}, nil)
+	return &ctx
+}
+
+func TestResolve(t *testing.T) {
+	resolver := NewProxyResolver([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}, zerolog.Nop())
+
+	tests := []struct {
+		name       string
+		remote     string
+		headers    map[string]string
+		wantIP     string
+		wantScheme string
+	}{
+		{"direct", "203.0.113.7", nil, "203.0.113.7", "http"},
+		{
+			"untrusted peer cannot spoof",
+			"203.0.113.7",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"},
+			"203.0.113.7", "http",
+		},
+		{
+			"trusted proxy",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"},
+			"198.51.100.1", "https",
+		},
+		{"trusted proxy without headers", "10.0.0.2", nil, "10.0.0.2", "http"},
+		{
+			"chain of trusted proxies",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, 10.1.1.1, 10.2.2.2"},
+			"198.51.100.1", "http",
+		},
+		{
+			"spoofed entries left of the client are ignored",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"},
+			"198.51.100.1", "http",
+		},
+		{
+			"garbage stops the walk",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-For": "198.51.100.1, unknown, 10.1.1.1"},
+			"10.1.1.1", "http",
+		},
+		{
+			"only trusted hops",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-For": "10.1.1.1"},
+			"10.1.1.1", "http",
+		},
+		{
+			"first proto of a list",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-Proto": " HTTPS , http"},
+			"10.0.0.2", "https",
+		},
+		{
+			"unknown proto is ignored",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-Proto": "wss"},
+			"10.0.0.2", "http",
+		},
+		{
+			"ipv6 proxy",
+			"fd00::1",
+			map[string]string{"X-Forwarded-For": "2001:db8::5"},
+			"2001:db8::5", "http",
+		},
+		{
+			"ipv4 mapped client",
+			"10.0.0.2",
+			map[string]string{"X-Forwarded-For": "::ffff:198.51.100.1"},
+			"198.51.100.1", "http",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, scheme := resolver.Resolve(requestFrom(tt.remote, tt.headers))
+			if ip != tt.wantIP || scheme != tt.wantScheme {
+				t.Errorf("Resolve() = %s, %s, want %s, %s", ip, scheme, tt.wantIP, tt.wantScheme)
+			}
+		})
+	}
+}
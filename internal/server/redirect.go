@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RedirectToHTTPS returns a handler that sends every request to the same
+// host and path on the HTTPS port. Requests a trusted proxy received over
+// HTTPS are passed to next instead, redirecting them would loop.
+func RedirectToHTTPS(httpsPort int, proxies *ProxyResolver, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if _, scheme := proxies.Resolve(ctx); scheme == "https" {
+			next(ctx)
+			return
+		}
+
+		host := string(ctx.Host())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if host == "" {
+			ctx.Error("Bad Request", fasthttp.StatusBadRequest)
+			return
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		status := fasthttp.StatusPermanentRedirect
+		if ctx.IsGet() || ctx.IsHead() {
+			status = fasthttp.StatusMovedPermanently
+		}
+
+		ctx.Redirect("https://"+host+string(ctx.RequestURI()), status)
+	}
+}
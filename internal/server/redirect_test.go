@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	proxies := NewProxyResolver([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, zerolog.Nop())
+	next := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusTeapot)
+	}
+
+	tests := []struct {
+		name         string
+		httpsPort    int
+		method       string
+		remote       string
+		headers      map[string]string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			"get", 443, fasthttp.MethodGet, "203.0.113.7",
+			map[string]string{"Host": "crm.example.com"},
+			fasthttp.StatusMovedPermanently, "https://crm.example.com/api/v1/orders/0?x=1",
+		},
+		{
+			"head", 443, fasthttp.MethodHead, "203.0.113.7",
+			map[string]string{"Host": "crm.example.com"},
+			fasthttp.StatusMovedPermanently, "https://crm.example.com/api/v1/orders/0?x=1",
+		},
+		{
+			"post keeps the method", 443, fasthttp.MethodPost, "203.0.113.7",
+			map[string]string{"Host": "crm.example.com"},
+			fasthttp.StatusPermanentRedirect, "https://crm.example.com/api/v1/orders/0?x=1",
+		},
+		{
+			"port replaced", 8443, fasthttp.MethodGet, "203.0.113.7",
+			map[string]string{"Host": "crm.example.com:8080"},
+			fasthttp.StatusMovedPermanently, "https://crm.example.com:8443/api/v1/orders/0?x=1",
+		},
+		{
+			"default port dropped", 443, fasthttp.MethodGet, "203.0.113.7",
+			map[string]string{"Host": "crm.example.com:80"},
+			fasthttp.StatusMovedPermanently, "https://crm.example.com/api/v1/orders/0?x=1",
+		},
+		{
+			"ipv6 host", 443, fasthttp.MethodGet, "203.0.113.7",
+			map[string]string{"Host": "[2001:db8::1]:80"},
+			fasthttp.StatusMovedPermanently, "https://[2001:db8::1]/api/v1/orders/0?x=1",
+		},
+		{
+			"ipv6 host with port", 8443, fasthttp.MethodGet, "203.0.113.7",
+			map[string]string{"Host": "[2001:db8::1]"},
+			fasthttp.StatusMovedPermanently, "https://[2001:db8::1]:8443/api/v1/orders/0?x=1",
+		},
+		{
+			"no host", 443, fasthttp.MethodGet, "203.0.113.7", nil,
+			fasthttp.StatusBadRequest, "",
+		},
+		{
+			"https at a trusted proxy is served", 443, fasthttp.MethodGet, "10.0.0.2",
+			map[string]string{"Host": "crm.example.com", "X-Forwarded-Proto": "https"},
+			fasthttp.StatusTeapot, "",
+		},
+		{
+			"https claimed by anyone else is redirected", 443, fasthttp.MethodGet, "203.0.113.7",
+			map[string]string{"Host": "crm.example.com", "X-Forwarded-Proto": "https"},
+			fasthttp.StatusMovedPermanently, "https://crm.example.com/api/v1/orders/0?x=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := requestFrom(tt.remote, tt.headers)
+			ctx.Request.Header.SetMethod(tt.method)
+
+			RedirectToHTTPS(tt.httpsPort, proxies, next)(ctx)
+
+			if got := ctx.Response.StatusCode(); got != tt.wantStatus {
+				t.Errorf("status %d, want %d", got, tt.wantStatus)
+			}
+			if got := string(ctx.Response.Header.Peek("Location")); got != tt.wantLocation {
+				t.Errorf("Location %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
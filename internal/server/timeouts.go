@@ -0,0 +1,44 @@
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Timeouts holds read and write timeouts that can be changed while the
+// server is running. fasthttp reads Server.ReadTimeout and
+// Server.WriteTimeout without synchronization, so the values are applied
+// per request through Server.HeaderReceived instead.
+type Timeouts struct {
+	read  atomic.Int64
+	write atomic.Int64
+}
+
+func NewTimeouts(read, write time.Duration) *Timeouts {
+	t := &Timeouts{}
+	t.Set(read, write)
+	return t
+}
+
+func (t *Timeouts) Set(read, write time.Duration) {
+	t.read.Store(int64(read))
+	t.write.Store(int64(write))
+}
+
+func (t *Timeouts) Read() time.Duration {
+	return time.Duration(t.read.Load())
+}
+
+func (t *Timeouts) Write() time.Duration {
+	return time.Duration(t.write.Load())
+}
+
+// HeaderReceived is meant to be used as fasthttp.Server.HeaderReceived
+func (t *Timeouts) HeaderReceived(*fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return fasthttp.RequestConfig{
+		ReadTimeout:  t.Read(),
+		WriteTimeout: t.Write(),
+	}
+}
@@ -0,0 +1,78 @@
+// Package file is an SMS sink for development: messages are logged and
+// appended to a file instead of being sent.
+package file
+
+import (
+	"backend_crm/internal/sms"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type sender struct {
+	path   string
+	logger zerolog.Logger
+	mu     sync.Mutex
+}
+
+// NewSender appends every message as a JSON line to the file at path. With
+// an empty path the messages are only logged.
+func NewSender(path string, logger zerolog.Logger) (sms.Sender, error) {
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("create sms directory: %w", err)
+		}
+	}
+
+	return &sender{
+		path:   path,
+		logger: logger,
+	}, nil
+}
+
+type line struct {
+	Id   string    `json:"id"`
+	Time time.Time `json:"time"`
+	To   string    `json:"to"`
+	Text string    `json:"text"`
+}
+
+func (s *sender) Send(ctx context.Context, msg *sms.Message) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+
+	s.logger.Info().Str("id", id).Str("to", msg.To).Str("text", msg.Text).Msg("sms")
+
+	if s.path == "" {
+		return id, nil
+	}
+
+	data, err := json.Marshal(line{Id: id, Time: time.Now().UTC(), To: msg.To, Text: msg.Text})
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return "", err
+	}
+	return id, f.Close()
+}
@@ -0,0 +1,117 @@
+// Package httpapi adapts SMS providers with a JSON over HTTP API.
+package httpapi
+
+import (
+	"backend_crm/internal/sms"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxResponseSize bounds the provider response read for the message id
+const maxResponseSize = 64 * 1024
+
+// Config describes the provider API. Every message is sent as a POST of
+// {"from": From, "to": "+79001234567", "text": "..."} to URL.
+type Config struct {
+	URL string
+	// Token is sent as a bearer token, Username and Password as basic
+	// authentication when there is no token
+	Token    string
+	Username string
+	Password string
+	// From is the sender name or number registered with the provider
+	From string
+	// IdField is the field of the JSON response holding the message id
+	IdField string
+	Timeout time.Duration
+}
+
+type sender struct {
+	cfg    Config
+	client *http.Client
+}
+
+// NewSender returns a sender posting to the configured provider
+func NewSender(cfg Config) (sms.Sender, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("sms provider url is empty")
+	}
+	if cfg.IdField == "" {
+		cfg.IdField = "id"
+	}
+
+	return &sender{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+type request struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+func (s *sender) Send(ctx context.Context, msg *sms.Message) (string, error) {
+	body, err := json.Marshal(request{From: s.cfg.From, To: msg.To, Text: msg.Text})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", sms.ErrPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	} else if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("provider answered %s: %s", resp.Status, bytes.TrimSpace(data))
+		// Rate limits and server errors are worth another try
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %v", sms.ErrPermanent, err)
+	}
+
+	return messageId(data, s.cfg.IdField), nil
+}
+
+// messageId reads the id from a JSON object response. Providers answering
+// with something else still sent the message, it just has no id.
+func messageId(data []byte, field string) string {
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+
+	switch id := fields[field].(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+	return ""
+}
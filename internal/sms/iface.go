@@ -0,0 +1,23 @@
+// Package sms sends text messages through a pluggable provider.
+package sms
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPermanent wraps provider errors that will not go away by retrying,
+// such as an invalid number or rejected credentials
+var ErrPermanent = errors.New("permanent sms failure")
+
+type Message struct {
+	// To is the number in international format, e.g. "+79001234567"
+	To   string
+	Text string
+}
+
+type Sender interface {
+	// Send hands the message to the provider and returns the id the
+	// provider gave it, empty if it has none
+	Send(ctx context.Context, msg *Message) (string, error)
+}
@@ -0,0 +1,69 @@
+package collab
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+var (
+	ErrStopped = errors.New("collaboration hub stopped")
+	// ErrLocked is returned with the lock of another session
+	ErrLocked = errors.New("order is locked")
+)
+
+type MessageType string
+
+const (
+	// MessagePresence lists the users viewing the order
+	MessagePresence MessageType = "presence"
+	// MessageLock tells who holds the edit lock
+	MessageLock MessageType = "lock"
+	// MessageComment carries a new or edited comment
+	MessageComment MessageType = "comment"
+	// MessageStatus carries a status change
+	MessageStatus MessageType = "status"
+)
+
+// Message is sent to the sessions viewing an order
+type Message struct {
+	Type MessageType
+	// Viewers is set for presence messages
+	Viewers []model.Viewer
+	// Lock is set for lock messages, nil when nobody holds the lock
+	Lock *model.OrderLock
+	// Comment is set for comment messages
+	Comment *model.OrderComment
+	// Event is set for status messages
+	Event *model.DomainEvent
+}
+
+// Session is a user viewing an order
+type Session struct {
+	SessionId string
+	OrderId   string
+	Viewer    model.Viewer
+	// Messages delivers what the client is sent, starting with the viewers
+	// and the lock. It is closed when the session left, the hub stopped or
+	// the client fell too far behind.
+	Messages <-chan *Message
+}
+
+// Hub keeps the sessions of the users viewing orders and tells them what
+// happens to the order, on this server instance or any other
+type Hub interface {
+	Join(ctx context.Context, orderId string, viewer model.Viewer) (*Session, error)
+	// Leave ends the session and releases its lock
+	Leave(ctx context.Context, session *Session)
+	// Lock takes or renews the edit lock of the order for the session. The
+	// lock of another session is returned with ErrLocked.
+	Lock(ctx context.Context, session *Session) (*model.OrderLock, error)
+	// Unlock releases the lock of the session, if it holds it
+	Unlock(ctx context.Context, session *Session) error
+	// CommentChanged tells the viewers of the order about a new or edited
+	// comment
+	CommentChanged(ctx context.Context, orderId string, commentId string)
+	// Run passes messages between the instances until ctx is done and then
+	// ends every session
+	Run(ctx context.Context)
+}
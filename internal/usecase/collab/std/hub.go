@@ -0,0 +1,575 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	broadcastRepo "backend_crm/internal/repository/broadcast"
+	commentsRepo "backend_crm/internal/repository/comments"
+	locksRepo "backend_crm/internal/repository/locks"
+	"backend_crm/internal/usecase/collab"
+	"backend_crm/internal/usecase/events"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// sessionBuffer is the number of messages a client may lag behind
+	// before its session is ended
+	sessionBuffer = 64
+	// presenceInterval is how often every instance repeats its viewers,
+	// the viewers of an instance not heard of for presenceExpiry are dropped
+	presenceInterval = 30 * time.Second
+	presenceExpiry   = 3 * presenceInterval
+	// retryDelay follows a failure of the event feed or the broadcast
+	retryDelay = 5 * time.Second
+)
+
+var _ collab.Hub = &hub{}
+
+type hub struct {
+	instance  string
+	broadcast broadcastRepo.Repository
+	locks     locksRepo.Repository
+	comments  commentsRepo.Repository
+	feed      events.Feed
+	lockTTL   time.Duration
+	logger    zerolog.Logger
+
+	mu      sync.Mutex
+	stopped bool
+	rooms   map[string]*room
+}
+
+// room holds the viewers of an order
+type room struct {
+	// sessions of this instance by id
+	sessions map[string]*session
+	// remote holds the viewers of the other instances by instance
+	remote map[string]*remotePresence
+}
+
+type session struct {
+	*collab.Session
+	send   chan *collab.Message
+	closed bool
+}
+
+type remotePresence struct {
+	viewers []model.Viewer
+	seenAt  time.Time
+}
+
+// envelope is a message between the instances. Comments may be too large
+// for the broadcast and are loaded by their id.
+type envelope struct {
+	Instance  string             `json:"instance"`
+	OrderId   string             `json:"orderId"`
+	Type      collab.MessageType `json:"type"`
+	Viewers   []viewer           `json:"viewers,omitempty"`
+	Lock      *lock              `json:"lock,omitempty"`
+	CommentId string             `json:"commentId,omitempty"`
+}
+
+type viewer struct {
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+}
+
+type lock struct {
+	UserId     string    `json:"userId"`
+	Username   string    `json:"username"`
+	SessionId  string    `json:"sessionId"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// NewHub hands out edit locks for lockTTL, they are renewed by locking
+// again. Status changes are taken from the event feed.
+func NewHub(
+	broadcast broadcastRepo.Repository,
+	locks locksRepo.Repository,
+	comments commentsRepo.Repository,
+	feed events.Feed,
+	lockTTL time.Duration,
+	logger zerolog.Logger,
+) collab.Hub {
+	return &hub{
+		instance:  newId(),
+		broadcast: broadcast,
+		locks:     locks,
+		comments:  comments,
+		feed:      feed,
+		lockTTL:   lockTTL,
+		logger:    logger,
+		rooms:     make(map[string]*room),
+	}
+}
+
+func (h *hub) Join(ctx context.Context, orderId string, viewer model.Viewer) (*collab.Session, error) {
+	send := make(chan *collab.Message, sessionBuffer)
+	s := &session{
+		Session: &collab.Session{
+			SessionId: newId(),
+			OrderId:   orderId,
+			Viewer:    viewer,
+			Messages:  send,
+		},
+		send: send,
+	}
+
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return nil, collab.ErrStopped
+	}
+	r := h.room(orderId)
+	r.sessions[s.SessionId] = s
+	h.sendPresence(r)
+	local := r.localViewers()
+	h.mu.Unlock()
+
+	h.publish(ctx, &envelope{OrderId: orderId, Type: collab.MessagePresence, Viewers: toViewers(local)})
+
+	current, err := h.locks.Get(ctx, orderId)
+	if err != nil && !errors.Is(err, locksRepo.ErrNotFoundLock) {
+		h.Leave(ctx, s.Session)
+		return nil, fmt.Errorf("get lock: %w", err)
+	}
+
+	h.mu.Lock()
+	h.deliver(s, &collab.Message{Type: collab.MessageLock, Lock: current})
+	h.mu.Unlock()
+
+	return s.Session, nil
+}
+
+func (h *hub) Leave(ctx context.Context, leaving *collab.Session) {
+	h.mu.Lock()
+	r, left := h.rooms[leaving.OrderId], false
+	var local []model.Viewer
+	if r != nil {
+		if s, ok := r.sessions[leaving.SessionId]; ok {
+			delete(r.sessions, leaving.SessionId)
+			h.close(s)
+			h.sendPresence(r)
+			local = r.localViewers()
+			h.cleanup(leaving.OrderId, r)
+			left = true
+		}
+	}
+	h.mu.Unlock()
+
+	if left {
+		h.publish(ctx, &envelope{OrderId: leaving.OrderId, Type: collab.MessagePresence, Viewers: toViewers(local)})
+	}
+
+	if err := h.Unlock(ctx, leaving); err != nil {
+		h.logger.Error().Err(err).Str("order_id", leaving.OrderId).Msg("failed to release the lock of a session")
+	}
+}
+
+func (h *hub) Lock(ctx context.Context, s *collab.Session) (*model.OrderLock, error) {
+	acquired, err := h.locks.Acquire(ctx, s.OrderId, s.Viewer.UserId, s.SessionId, h.lockTTL)
+	if err != nil {
+		if errors.Is(err, locksRepo.ErrLocked) {
+			return acquired, collab.ErrLocked
+		}
+		return nil, err
+	}
+
+	h.lockChanged(ctx, s.OrderId, acquired)
+	return acquired, nil
+}
+
+func (h *hub) Unlock(ctx context.Context, s *collab.Session) error {
+	if err := h.locks.Release(ctx, s.OrderId, s.SessionId); err != nil {
+		if errors.Is(err, locksRepo.ErrNotFoundLock) {
+			return nil
+		}
+		return err
+	}
+
+	h.lockChanged(ctx, s.OrderId, nil)
+	return nil
+}
+
+func (h *hub) CommentChanged(ctx context.Context, orderId string, commentId string) {
+	h.publish(ctx, &envelope{OrderId: orderId, Type: collab.MessageComment, CommentId: commentId})
+	h.sendComment(ctx, orderId, commentId)
+}
+
+func (h *hub) Run(ctx context.Context) {
+	defer h.stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		h.listen(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		h.followEvents(ctx)
+	}()
+	defer wg.Wait()
+
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.refreshPresence(ctx)
+		}
+	}
+}
+
+// listen receives the messages of all instances
+func (h *hub) listen(ctx context.Context) {
+	for {
+		err := h.broadcast.Listen(ctx, func(message []byte) {
+			h.receive(ctx, message)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		h.logger.Error().Err(err).Msg("failed to listen to other instances")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (h *hub) receive(ctx context.Context, message []byte) {
+	if message == nil {
+		// Messages may have been missed, the other instances repeat their
+		// viewers soon and expect ours
+		h.refreshPresence(ctx)
+		return
+	}
+
+	var e envelope
+	if err := json.Unmarshal(message, &e); err != nil {
+		h.logger.Error().Err(err).Msg("failed to decode a message of another instance")
+		return
+	}
+	if e.Instance == h.instance {
+		return
+	}
+
+	switch e.Type {
+	case collab.MessagePresence:
+		h.mu.Lock()
+		r := h.room(e.OrderId)
+		if len(e.Viewers) == 0 {
+			delete(r.remote, e.Instance)
+		} else {
+			r.remote[e.Instance] = &remotePresence{viewers: fromViewers(e.Viewers), seenAt: time.Now()}
+		}
+		h.sendPresence(r)
+		h.cleanup(e.OrderId, r)
+		h.mu.Unlock()
+	case collab.MessageLock:
+		var current *model.OrderLock
+		if e.Lock != nil {
+			current = &model.OrderLock{
+				OrderId:    e.OrderId,
+				UserId:     e.Lock.UserId,
+				Username:   e.Lock.Username,
+				SessionId:  e.Lock.SessionId,
+				AcquiredAt: e.Lock.AcquiredAt,
+				ExpiresAt:  e.Lock.ExpiresAt,
+			}
+		}
+		h.sendAll(e.OrderId, &collab.Message{Type: collab.MessageLock, Lock: current})
+	case collab.MessageComment:
+		h.sendComment(ctx, e.OrderId, e.CommentId)
+	}
+}
+
+// followEvents sends the status changes of the orders to their viewers
+func (h *hub) followEvents(ctx context.Context) {
+	after := int64(-1)
+	for {
+		subscription, err := h.feed.Subscribe(ctx, after)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, events.ErrFeedNotReady) {
+				h.logger.Error().Err(err).Msg("failed to follow the order events")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		if after < 0 || subscription.Reset {
+			after = subscription.Sequence
+		}
+		for _, event := range subscription.Backlog {
+			h.sendStatus(event)
+			after = event.Sequence
+		}
+		h.followSubscription(ctx, subscription, &after)
+		subscription.Cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// followSubscription sends the status changes until the subscription ends
+func (h *hub) followSubscription(ctx context.Context, subscription *events.Subscription, after *int64) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			h.sendStatus(event)
+			*after = event.Sequence
+		}
+	}
+}
+
+func (h *hub) sendStatus(event *model.DomainEvent) {
+	if event.Type != model.EventOrderStatusChanged {
+		return
+	}
+	h.sendAll(event.OrderId, &collab.Message{Type: collab.MessageStatus, Event: event})
+}
+
+func (h *hub) sendComment(ctx context.Context, orderId string, commentId string) {
+	h.mu.Lock()
+	r := h.rooms[orderId]
+	viewed := r != nil && len(r.sessions) > 0
+	h.mu.Unlock()
+	if !viewed {
+		return
+	}
+
+	comment, err := h.comments.GetById(ctx, commentId)
+	if err != nil {
+		if !errors.Is(err, commentsRepo.ErrNotFoundComment) {
+			h.logger.Error().Err(err).Str("comment_id", commentId).Msg("failed to load a changed comment")
+		}
+		return
+	}
+
+	h.sendAll(orderId, &collab.Message{Type: collab.MessageComment, Comment: comment})
+}
+
+// lockChanged tells the viewers of the order on every instance about its
+// lock
+func (h *hub) lockChanged(ctx context.Context, orderId string, current *model.OrderLock) {
+	h.sendAll(orderId, &collab.Message{Type: collab.MessageLock, Lock: current})
+
+	e := &envelope{OrderId: orderId, Type: collab.MessageLock}
+	if current != nil {
+		e.Lock = &lock{
+			UserId:     current.UserId,
+			Username:   current.Username,
+			SessionId:  current.SessionId,
+			AcquiredAt: current.AcquiredAt,
+			ExpiresAt:  current.ExpiresAt,
+		}
+	}
+	h.publish(ctx, e)
+}
+
+// refreshPresence repeats the viewers of this instance and drops the ones
+// of instances not heard of for long
+func (h *hub) refreshPresence(ctx context.Context) {
+	now := time.Now()
+	var updates []*envelope
+
+	h.mu.Lock()
+	for orderId, r := range h.rooms {
+		expired := false
+		for instance, presence := range r.remote {
+			if now.Sub(presence.seenAt) > presenceExpiry {
+				delete(r.remote, instance)
+				expired = true
+			}
+		}
+		if expired {
+			h.sendPresence(r)
+		}
+		if len(r.sessions) > 0 {
+			updates = append(updates, &envelope{
+				OrderId: orderId,
+				Type:    collab.MessagePresence,
+				Viewers: toViewers(r.localViewers()),
+			})
+		}
+		h.cleanup(orderId, r)
+	}
+	h.mu.Unlock()
+
+	for _, update := range updates {
+		h.publish(ctx, update)
+	}
+}
+
+func (h *hub) publish(ctx context.Context, e *envelope) {
+	e.Instance = h.instance
+	message, err := json.Marshal(e)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to encode a message to other instances")
+		return
+	}
+
+	if err := h.broadcast.Publish(ctx, message); err != nil {
+		h.logger.Error().Err(err).Str("order_id", e.OrderId).Str("type", string(e.Type)).Msg("failed to tell other instances")
+	}
+}
+
+func (h *hub) sendAll(orderId string, message *collab.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r := h.rooms[orderId]; r != nil {
+		for _, s := range r.sessions {
+			h.deliver(s, message)
+		}
+	}
+}
+
+// sendPresence sends the viewers of all instances to the local sessions.
+// h.mu must be held.
+func (h *hub) sendPresence(r *room) {
+	viewers := r.viewers()
+	for _, s := range r.sessions {
+		h.deliver(s, &collab.Message{Type: collab.MessagePresence, Viewers: viewers})
+	}
+}
+
+// deliver queues the message for the session. A session that cannot take
+// it is ended, its client reconnects and starts over. h.mu must be held.
+func (h *hub) deliver(s *session, message *collab.Message) {
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.send <- message:
+	default:
+		h.close(s)
+	}
+}
+
+// close ends the messages of the session. h.mu must be held.
+func (h *hub) close(s *session) {
+	if !s.closed {
+		s.closed = true
+		close(s.send)
+	}
+}
+
+// room returns the room of the order, creating it. h.mu must be held.
+func (h *hub) room(orderId string) *room {
+	r := h.rooms[orderId]
+	if r == nil {
+		r = &room{
+			sessions: make(map[string]*session),
+			remote:   make(map[string]*remotePresence),
+		}
+		h.rooms[orderId] = r
+	}
+	return r
+}
+
+// cleanup forgets a room nobody views. h.mu must be held.
+func (h *hub) cleanup(orderId string, r *room) {
+	if len(r.sessions) == 0 && len(r.remote) == 0 {
+		delete(h.rooms, orderId)
+	}
+}
+
+func (h *hub) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+	for _, r := range h.rooms {
+		for _, s := range r.sessions {
+			h.close(s)
+		}
+	}
+	h.rooms = make(map[string]*room)
+}
+
+// localViewers lists the users of the sessions of this instance
+func (r *room) localViewers() []model.Viewer {
+	var result []model.Viewer
+	for _, s := range r.sessions {
+		result = append(result, s.Viewer)
+	}
+	return uniqueViewers(result)
+}
+
+// viewers lists the users viewing the order on any instance
+func (r *room) viewers() []model.Viewer {
+	result := r.localViewers()
+	for _, presence := range r.remote {
+		result = append(result, presence.viewers...)
+	}
+	return uniqueViewers(result)
+}
+
+// uniqueViewers sorts the viewers by name and drops repeated users, who
+// view the order in several windows
+func uniqueViewers(viewers []model.Viewer) []model.Viewer {
+	slices.SortFunc(viewers, func(a, b model.Viewer) int {
+		return cmp.Or(cmp.Compare(a.Username, b.Username), cmp.Compare(a.UserId, b.UserId))
+	})
+	return slices.CompactFunc(viewers, func(a, b model.Viewer) bool {
+		return a.UserId == b.UserId
+	})
+}
+
+func toViewers(viewers []model.Viewer) []viewer {
+	result := make([]viewer, 0, len(viewers))
+	for _, v := range viewers {
+		result = append(result, viewer{UserId: v.UserId, Username: v.Username})
+	}
+	return result
+}
+
+func fromViewers(viewers []viewer) []model.Viewer {
+	result := make([]model.Viewer, 0, len(viewers))
+	for _, v := range viewers {
+		result = append(result, model.Viewer{UserId: v.UserId, Username: v.Username})
+	}
+	return result
+}
+
+// newId returns a random UUID
+func newId() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0F | 0x40
+	b[8] = b[8]&0x3F | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+)
+
+const (
+	// BatchSize is the number of events claimed at once
+	BatchSize = 50
+	// MaxBacklog is the number of missed events a subscription is resumed
+	// with, a subscriber further behind starts over
+	MaxBacklog = 1000
+)
+
+var ErrFeedNotReady = errors.New("event feed not ready")
+
+// Handler reacts to a domain event. Events may arrive more than once and,
+// after a failure, out of order, so handlers must be idempotent. An error
+// makes the dispatcher retry the event for this handler later.
+type Handler func(ctx context.Context, event *model.DomainEvent) error
+
+// Dispatcher hands the stored domain events to the subscribers registered
+// in this process. With several server instances each event is dispatched
+// by the one that claims it, so all instances must register the same
+// subscribers and these must not depend on the state of their process.
+type Dispatcher interface {
+	// Subscribe registers a handler before Run. The name is stored with
+	// the events it handled; renaming a subscriber hands it the events
+	// not yet dispatched to everyone again.
+	Subscribe(name string, handler Handler)
+	// Notify wakes the dispatcher after events were stored, instead of
+	// waiting for the next interval
+	Notify()
+	// Process dispatches a batch of due events and returns how many it
+	// handled. The error is only returned when the events could not be
+	// read or updated.
+	Process(ctx context.Context) (int, error)
+	// Run processes the events until ctx is done
+	Run(ctx context.Context)
+}
+
+// Feed follows the stored events for live streams. Every instance reads
+// the event store itself, so its subscribers see the changes made through
+// any instance, in sequence order.
+type Feed interface {
+	// Subscribe starts a subscription with the events stored after the
+	// given sequence, or with new events only when it is negative.
+	// ErrFeedNotReady is returned until Run has read the event store.
+	Subscribe(ctx context.Context, after int64) (*Subscription, error)
+	// Run follows the event store until ctx is done and then ends every
+	// subscription
+	Run(ctx context.Context)
+}
+
+// Subscription receives the events of a Feed
+type Subscription struct {
+	// Backlog holds the missed events, oldest first
+	Backlog []*model.DomainEvent
+	// Reset is set when more than MaxBacklog events were missed. Backlog
+	// is empty then and the subscriber should reload what it shows.
+	Reset bool
+	// Sequence is the last event published when the subscription started,
+	// Events delivers the ones after it
+	Sequence int64
+	// Events delivers the events following Backlog. It is closed when the
+	// feed stops, on Cancel and when the subscriber falls so far behind
+	// that it has to resume from the event store.
+	Events <-chan *model.DomainEvent
+	// Cancel ends the subscription
+	Cancel func()
+}
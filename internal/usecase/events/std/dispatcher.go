@@ -0,0 +1,147 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	eventsRepo "backend_crm/internal/repository/events"
+	"backend_crm/internal/usecase/events"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// lease hides a claimed batch from other instances while it is handled
+	lease = 5 * time.Minute
+	// maxBackoff caps the delay between two dispatches of a failed event
+	maxBackoff = time.Hour
+)
+
+var _ events.Dispatcher = &dispatcher{}
+
+type subscriber struct {
+	name    string
+	handler events.Handler
+}
+
+type dispatcher struct {
+	events eventsRepo.Repository
+
+	interval     time.Duration
+	retryBackoff time.Duration
+	subscribers  []subscriber
+	wake         chan struct{}
+	logger       zerolog.Logger
+}
+
+// NewDispatcher checks for due events every interval and retries failed
+// ones after retryBackoff, doubled with every attempt
+func NewDispatcher(
+	events eventsRepo.Repository,
+	interval time.Duration,
+	retryBackoff time.Duration,
+	logger zerolog.Logger,
+) events.Dispatcher {
+	return &dispatcher{
+		events:       events,
+		interval:     interval,
+		retryBackoff: retryBackoff,
+		wake:         make(chan struct{}, 1),
+		logger:       logger,
+	}
+}
+
+func (d *dispatcher) Subscribe(name string, handler events.Handler) {
+	d.subscribers = append(d.subscribers, subscriber{name: name, handler: handler})
+}
+
+func (d *dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.Process(ctx)
+			if err != nil && ctx.Err() == nil {
+				d.logger.Error().Err(err).Msg("failed to dispatch events")
+			}
+			if err != nil || n < events.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *dispatcher) Process(ctx context.Context) (int, error) {
+	batch, err := d.events.Claim(ctx, events.BatchSize, lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim: %w", err)
+	}
+
+	for i, event := range batch {
+		if ctx.Err() != nil {
+			// The lease brings the rest back later
+			return i, ctx.Err()
+		}
+		if err := d.dispatch(ctx, event); err != nil {
+			return i, err
+		}
+	}
+
+	return len(batch), nil
+}
+
+// dispatch hands the event to the subscribers that have not handled it yet
+func (d *dispatcher) dispatch(ctx context.Context, event *model.DomainEvent) error {
+	log := d.logger.With().Str("event_id", event.EventId).Str("type", string(event.Type)).Logger()
+
+	var failed []error
+	for _, s := range d.subscribers {
+		if slices.Contains(event.HandledBy, s.name) {
+			continue
+		}
+
+		if err := s.handler(ctx, event); err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		if err := d.events.MarkHandled(ctx, event.EventId, s.name); err != nil {
+			return fmt.Errorf("mark handled: %w", err)
+		}
+	}
+
+	if len(failed) == 0 {
+		return d.events.MarkDispatched(ctx, event.EventId)
+	}
+
+	cause := errors.Join(failed...)
+	delay := d.retryDelay(event.Attempts)
+	log.Warn().Err(cause).Int("attempts", event.Attempts).Dur("retry_in", delay).Msg("event dispatch failed")
+	return d.events.Retry(ctx, event.EventId, time.Now().Add(delay), cause.Error())
+}
+
+// retryDelay doubles the backoff with every attempt after the first
+func (d *dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.retryBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
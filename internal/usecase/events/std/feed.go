@@ -0,0 +1,225 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	eventsRepo "backend_crm/internal/repository/events"
+	"backend_crm/internal/usecase/events"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// subscriberBuffer is the number of events a subscriber may lag behind
+// before it is dropped
+const subscriberBuffer = 256
+
+var _ events.Feed = &feed{}
+
+type feed struct {
+	events eventsRepo.Repository
+
+	interval   time.Duration
+	gapTimeout time.Duration
+	logger     zerolog.Logger
+
+	mu          sync.Mutex
+	ready       bool
+	stopped     bool
+	published   int64
+	subscribers map[chan *model.DomainEvent]struct{}
+
+	// Only used by Run
+	pending  map[int64]*model.DomainEvent
+	gapSince time.Time
+}
+
+// NewFeed reads the event store every interval. Events are published in
+// sequence order, so an event following a gap in the sequences is held
+// back until the gap is filled or gapTimeout passed: the gap may be a
+// transaction that has yet to commit or one that rolled back.
+func NewFeed(
+	events eventsRepo.Repository,
+	interval time.Duration,
+	gapTimeout time.Duration,
+	logger zerolog.Logger,
+) events.Feed {
+	return &feed{
+		events:      events,
+		interval:    interval,
+		gapTimeout:  gapTimeout,
+		logger:      logger,
+		subscribers: make(map[chan *model.DomainEvent]struct{}),
+		pending:     make(map[int64]*model.DomainEvent),
+	}
+}
+
+func (f *feed) Subscribe(ctx context.Context, after int64) (*events.Subscription, error) {
+	ch := make(chan *model.DomainEvent, subscriberBuffer)
+
+	f.mu.Lock()
+	if !f.ready || f.stopped {
+		f.mu.Unlock()
+		return nil, events.ErrFeedNotReady
+	}
+	// Events after the published ones go to the channel, the backlog is
+	// read up to them
+	published := f.published
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	subscription := &events.Subscription{
+		Sequence: published,
+		Events:   ch,
+		Cancel:   func() { f.unsubscribe(ch) },
+	}
+	if after < 0 || after >= published {
+		return subscription, nil
+	}
+
+	backlog, err := f.events.After(ctx, after, events.MaxBacklog+1)
+	if err != nil {
+		subscription.Cancel()
+		return nil, fmt.Errorf("read backlog: %w", err)
+	}
+	for _, event := range backlog {
+		if event.Sequence > published {
+			break
+		}
+		subscription.Backlog = append(subscription.Backlog, event)
+	}
+	if len(subscription.Backlog) > events.MaxBacklog {
+		subscription.Backlog = nil
+		subscription.Reset = true
+	}
+
+	return subscription, nil
+}
+
+func (f *feed) Run(ctx context.Context) {
+	defer f.stop()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.poll(ctx); err != nil && ctx.Err() == nil {
+			f.logger.Error().Err(err).Msg("failed to read events for the feed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *feed) poll(ctx context.Context) error {
+	if !f.ready {
+		// Subscribers start with the events stored from now on
+		last, err := f.events.LastSequence(ctx)
+		if err != nil {
+			return fmt.Errorf("read last sequence: %w", err)
+		}
+
+		f.mu.Lock()
+		f.published = last
+		f.ready = true
+		f.mu.Unlock()
+		return nil
+	}
+
+	for {
+		// Read from the published events on, events held back by a gap
+		// come again and the gap may have been filled
+		batch, err := f.events.After(ctx, f.published, events.MaxBacklog)
+		if err != nil {
+			return fmt.Errorf("read events: %w", err)
+		}
+		for _, event := range batch {
+			f.pending[event.Sequence] = event
+		}
+
+		if !f.publish(time.Now()) || len(batch) < events.MaxBacklog {
+			return nil
+		}
+	}
+}
+
+// publish hands the pending events following the published ones to the
+// subscribers and reports whether there were any
+func (f *feed) publish(now time.Time) bool {
+	var ready []*model.DomainEvent
+	next := f.published + 1
+	for len(f.pending) > 0 {
+		if event, ok := f.pending[next]; ok {
+			ready = append(ready, event)
+			delete(f.pending, next)
+			next++
+			f.gapSince = time.Time{}
+			continue
+		}
+
+		if f.gapSince.IsZero() {
+			f.gapSince = now
+		}
+		if now.Sub(f.gapSince) < f.gapTimeout {
+			break
+		}
+		// No transaction runs that long, the missing sequences were lost
+		next = -1
+		for sequence := range f.pending {
+			if next < 0 || sequence < next {
+				next = sequence
+			}
+		}
+		f.gapSince = time.Time{}
+	}
+	if len(ready) == 0 {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.published = next - 1
+	for ch := range f.subscribers {
+		for _, event := range ready {
+			select {
+			case ch <- event:
+				continue
+			default:
+			}
+			// The subscriber resumes from the event store
+			delete(f.subscribers, ch)
+			close(ch)
+			break
+		}
+	}
+
+	return true
+}
+
+func (f *feed) unsubscribe(ch chan *model.DomainEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[ch]; ok {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (f *feed) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,19 @@
+package imports
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"io"
+)
+
+// DefaultBatchSize is the number of records stored per transaction
+const DefaultBatchSize = 100
+
+type Usecase interface {
+	// Products upserts the products of a CSV file. Problems with single rows
+	// end up in the report, the error is only returned when the import
+	// could not run to the end; batches stored until then are kept.
+	Products(ctx context.Context, r io.Reader, dryRun bool) (*model.ImportReport, error)
+	// Orders imports historical orders from a CSV file, see Products
+	Orders(ctx context.Context, r io.Reader, dryRun bool) (*model.ImportReport, error)
+}
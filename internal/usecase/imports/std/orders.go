@@ -0,0 +1,269 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var orderColumns = []string{
+	"externalId", "createdAt", "status", "name", "phone", "email", "description",
+	"sku", "product", "quantity", "price",
+}
+
+// catalog resolves the product references of an order file
+type catalog struct {
+	bySKU  map[string]*model.Product
+	byName map[string][]*model.Product
+}
+
+// pendingOrder collects the rows of one order. Consecutive rows with the
+// same externalId are the lines of a single order.
+type pendingOrder struct {
+	order  *model.NewOrder
+	line   int
+	failed bool
+}
+
+func (u *usecase) Orders(ctx context.Context, r io.Reader, dryRun bool) (*model.ImportReport, error) {
+	report := &model.ImportReport{DryRun: dryRun}
+
+	t, err := newTable(r, orderColumns)
+	if err == nil && !t.has("sku") && !t.has("product") {
+		err = invalid("", "a sku or product column is required")
+	}
+	if err == nil && !t.has("phone") && !t.has("email") {
+		err = invalid("", "a phone or email column is required")
+	}
+	if err != nil {
+		addError(report, 1, err)
+		return report, nil
+	}
+
+	products, err := u.loadCatalog(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	var batch []*model.NewOrder
+	var lines []int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		results, err := u.orders.Import(ctx, batch, dryRun)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			switch {
+			case result.Err != nil:
+				report.Failed++
+				addError(report, lines[i], result.Err)
+			case result.Skipped:
+				report.Skipped++
+			default:
+				report.Created++
+			}
+		}
+
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	var pending *pendingOrder
+	finish := func() error {
+		if pending == nil {
+			return nil
+		}
+		if pending.failed {
+			report.Failed++
+			pending = nil
+			return nil
+		}
+
+		batch = append(batch, pending.order)
+		lines = append(lines, pending.line)
+		pending = nil
+		if len(batch) >= u.batchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	seen := make(map[string]int)
+	for {
+		record, line, err := t.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if line == 0 && err != nil {
+			return report, err
+		}
+		report.Rows++
+		if err != nil {
+			// The row cannot be told apart from the order before it, so
+			// it fails on its own
+			if err := finish(); err != nil {
+				return report, err
+			}
+			report.Failed++
+			addError(report, line, err)
+			continue
+		}
+
+		externalId := t.get(record, "externalId")
+		if pending != nil && externalId != "" && externalId == pending.order.ExternalId {
+			item, err := products.parseItem(t, record)
+			if err != nil {
+				pending.failed = true
+				addError(report, line, err)
+				continue
+			}
+			pending.order.Items = append(pending.order.Items, item)
+			continue
+		}
+
+		if err := finish(); err != nil {
+			return report, err
+		}
+
+		pending = &pendingOrder{line: line}
+		if first, ok := seen[externalId]; ok {
+			pending.order = &model.NewOrder{ExternalId: externalId}
+			pending.failed = true
+			addError(report, line, invalid("externalId", "order %s already appeared on line %d", externalId, first))
+			continue
+		}
+		if externalId != "" {
+			seen[externalId] = line
+		}
+
+		pending.order, err = u.parseOrder(t, record)
+		if err == nil {
+			var item model.NewOrderItem
+			if item, err = products.parseItem(t, record); err == nil {
+				pending.order.Items = append(pending.order.Items, item)
+			}
+		}
+		if err != nil {
+			pending.order = &model.NewOrder{ExternalId: externalId}
+			pending.failed = true
+			addError(report, line, err)
+		}
+	}
+
+	if err := finish(); err != nil {
+		return report, err
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (u *usecase) loadCatalog(ctx context.Context) (*catalog, error) {
+	all, err := u.products.GetAll(ctx, model.ProductFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("load products: %w", err)
+	}
+
+	c := &catalog{
+		bySKU:  make(map[string]*model.Product, len(all)),
+		byName: make(map[string][]*model.Product, len(all)),
+	}
+	for _, product := range all {
+		if product.SKU != "" {
+			c.bySKU[product.SKU] = product
+		}
+		name := strings.ToLower(product.Name)
+		c.byName[name] = append(c.byName[name], product)
+	}
+
+	return c, nil
+}
+
+// parseOrder reads the order fields of the first row of an order
+func (u *usecase) parseOrder(t *table, record []string) (*model.NewOrder, error) {
+	order := &model.NewOrder{
+		ExternalId:  t.get(record, "externalId"),
+		Name:        t.get(record, "name"),
+		Phone:       t.get(record, "phone"),
+		Email:       t.get(record, "email"),
+		Description: t.get(record, "description"),
+		Status:      model.Complete,
+		TaxRate:     u.taxRate,
+	}
+
+	if len(order.ExternalId) > 64 {
+		return nil, invalid("externalId", "must be at most 64 characters")
+	}
+	if order.Phone == "" && order.Email == "" {
+		return nil, invalid("", "phone or email is required")
+	}
+	if len(order.Phone) > 20 {
+		return nil, invalid("phone", "must be at most 20 characters")
+	}
+
+	var err error
+	if status := t.get(record, "status"); status != "" {
+		if order.Status, err = parseStatus("status", status); err != nil {
+			return nil, err
+		}
+		// Imports do not move stock, so an open order would close without
+		// the reservation it never made
+		if order.Status != model.Complete && order.Status != model.Refected {
+			return nil, invalid("status", "only complete and rejected orders can be imported")
+		}
+	}
+	if createdAt := t.get(record, "createdAt"); createdAt != "" {
+		if order.CreatedAt, err = parseTime("createdAt", createdAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// parseItem reads the order line of a row
+func (c *catalog) parseItem(t *table, record []string) (model.NewOrderItem, error) {
+	var product *model.Product
+	if sku := t.get(record, "sku"); sku != "" {
+		if product = c.bySKU[sku]; product == nil {
+			return model.NewOrderItem{}, invalid("sku", "no product with sku %q", sku)
+		}
+	} else if name := t.get(record, "product"); name != "" {
+		switch found := c.byName[strings.ToLower(name)]; len(found) {
+		case 0:
+			return model.NewOrderItem{}, invalid("product", "no product named %q", name)
+		case 1:
+			product = found[0]
+		default:
+			return model.NewOrderItem{}, invalid("product", "several products are named %q, use a sku", name)
+		}
+	} else {
+		return model.NewOrderItem{}, invalid("", "sku or product is required")
+	}
+
+	item := model.NewOrderItem{ProductId: product.ProductId, Quantity: 1}
+
+	var err error
+	if quantity := t.get(record, "quantity"); quantity != "" {
+		if item.Quantity, err = parseQuantity("quantity", quantity); err != nil {
+			return model.NewOrderItem{}, err
+		}
+	}
+	if price := t.get(record, "price"); price != "" {
+		amount, err := parseMoney("price", price, product.Price.Currency)
+		if err != nil {
+			return model.NewOrderItem{}, err
+		}
+		item.UnitPrice = &amount
+	}
+
+	return item, nil
+}
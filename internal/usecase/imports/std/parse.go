@@ -0,0 +1,125 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are tried in order for dates without an offset, which are
+// read as UTC
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+var statusNames = map[string]model.OrderStatus{
+	"consideration": model.Consideration,
+	"rejected":      model.Refected,
+	"at work":       model.AtWork,
+	"complete":      model.Complete,
+	"completed":     model.Complete,
+}
+
+// normalizeNumber removes digit grouping and turns the decimal separator
+// into a point. With both "," and "." present the last one is the decimal
+// separator, a lone "," is one as well.
+func normalizeNumber(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\'':
+			return -1
+		}
+		return r
+	}, value)
+
+	comma, point := strings.LastIndexByte(value, ','), strings.LastIndexByte(value, '.')
+	switch {
+	case comma >= 0 && point >= 0 && comma > point:
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.Replace(value, ",", ".", 1)
+	case comma >= 0 && point >= 0:
+		value = strings.ReplaceAll(value, ",", "")
+	case comma >= 0:
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return value
+}
+
+// parseMoney reads a non-negative amount in major units, e.g. "1 234,50",
+// and returns it in minor units of the currency
+func parseMoney(column, value, currency string) (int64, error) {
+	exponent := model.Money{Currency: currency}.Exponent()
+
+	whole, fraction, _ := strings.Cut(normalizeNumber(value), ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) > exponent {
+		return 0, invalid(column, "%q has more than %d decimal places", value, exponent)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	amount, err := strconv.ParseUint(whole+fraction, 10, 63)
+	if err != nil {
+		return 0, invalid(column, "%q is not a valid amount", value)
+	}
+	return int64(amount), nil
+}
+
+func parseWeight(column, value string) (float32, error) {
+	weight, err := strconv.ParseFloat(normalizeNumber(value), 32)
+	if err != nil || weight < 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return 0, invalid(column, "%q is not a valid weight", value)
+	}
+	return float32(weight), nil
+}
+
+func parseQuantity(column, value string) (int, error) {
+	quantity, err := strconv.Atoi(value)
+	if err != nil || quantity <= 0 {
+		return 0, invalid(column, "%q is not a positive whole number", value)
+	}
+	return quantity, nil
+}
+
+func parseBool(column, value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "y", "да":
+		return true, nil
+	case "0", "false", "no", "n", "нет":
+		return false, nil
+	}
+	return false, invalid(column, "%q is not yes or no", value)
+}
+
+// parseStatus accepts the status number or its name as used by the export
+func parseStatus(column, value string) (model.OrderStatus, error) {
+	if n, err := strconv.Atoi(value); err == nil && n >= model.Consideration && n <= model.Complete {
+		return model.OrderStatus(n), nil
+	}
+
+	name := strings.Join(strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-'
+	}), " ")
+	if status, ok := statusNames[name]; ok {
+		return status, nil
+	}
+	return 0, invalid(column, "%q is not a known status", value)
+}
+
+func parseTime(column, value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalid(column, "%q is not a date, use YYYY-MM-DD or YYYY-MM-DD hh:mm", value)
+}
@@ -0,0 +1,184 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	"testing"
+	"time"
+)
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"1234", "1234"},
+		{"1234.50", "1234.50"},
+		{"1234,50", "1234.50"},
+		{"1 234,50", "1234.50"},
+		{"1\u00a0234,50", "1234.50"},
+		{"1\u202f234,50", "1234.50"},
+		{"1'234.50", "1234.50"},
+		{"1.234,50", "1234.50"},
+		{"1,234.50", "1234.50"},
+		{"1.234.567,5", "1234567.5"},
+		{"1,234,567.5", "1234567.5"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeNumber(tt.value); got != tt.want {
+			t.Errorf("normalizeNumber(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"1 234,50", "RUB", 123450, false},
+		{"1234.5", "RUB", 123450, false},
+		{"1234", "RUB", 123400, false},
+		{",5", "RUB", 50, false},
+		{"0", "RUB", 0, false},
+		{"1.234", "RUB", 0, true},
+		{"1500", "JPY", 1500, false},
+		{"1500.5", "JPY", 0, true},
+		{"1.234", "KWD", 1234, false},
+		{"-5", "RUB", 0, true},
+		{"abc", "RUB", 0, true},
+		{"", "RUB", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMoney("price", tt.value, tt.currency)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseMoney(%q, %s) = %d, %v, want %d, error %v", tt.value, tt.currency, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseWeight(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float32
+		wantErr bool
+	}{
+		{"1,5", 1.5, false},
+		{"0.25", 0.25, false},
+		{"1 000", 1000, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"Inf", 0, true},
+		{"NaN", 0, true},
+		{"1e40", 0, true},
+		{"heavy", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWeight("weight", tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWeight(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"3", 3, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"1.5", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseQuantity("quantity", tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseQuantity(%q) = %d, %v, want %d, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"1", true, false},
+		{"TRUE", true, false},
+		{"Yes", true, false},
+		{"да", true, false},
+		{"0", false, false},
+		{"no", false, false},
+		{"нет", false, false},
+		{"maybe", false, true},
+		{"", false, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBool("active", tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseBool(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    model.OrderStatus
+		wantErr bool
+	}{
+		{"0", model.Consideration, false},
+		{"3", model.Complete, false},
+		{"4", 0, true},
+		{"-1", 0, true},
+		{"Complete", model.Complete, false},
+		{"completed", model.Complete, false},
+		{"rejected", model.Refected, false},
+		{"AT_WORK", model.AtWork, false},
+		{"at-work", model.AtWork, false},
+		{"at  work", model.AtWork, false},
+		{"done", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStatus("status", tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseStatus(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-10-19", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), false},
+		{"2026-10-19 08:30", time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC), false},
+		{"2026-10-19 08:30:15", time.Date(2026, 10, 19, 8, 30, 15, 0, time.UTC), false},
+		{"19.10.2026", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), false},
+		{"19.10.2026 08:30", time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC), false},
+		{"2026-10-19T08:30:00+03:00", time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC), false},
+		{"10/19/2026", time.Time{}, true},
+		{"2026-13-01", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime("createdAt", tt.value)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, %v, want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var productColumns = []string{"sku", "name", "weight", "description", "price", "currency", "active"}
+
+func (u *usecase) Products(ctx context.Context, r io.Reader, dryRun bool) (*model.ImportReport, error) {
+	report := &model.ImportReport{DryRun: dryRun}
+
+	t, err := newTable(r, productColumns, "name", "price")
+	if err != nil {
+		addError(report, 1, err)
+		return report, nil
+	}
+
+	var batch []*model.Product
+	var lines []int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		results, err := u.products.Upsert(ctx, batch, dryRun)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			switch {
+			case result.Err != nil:
+				report.Failed++
+				addError(report, lines[i], result.Err)
+			case result.Created:
+				report.Created++
+			default:
+				report.Updated++
+			}
+		}
+
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	// The same product twice in one file would be updated with whatever
+	// row comes last, which is almost certainly a mistake
+	seen := make(map[string]int)
+	for {
+		record, line, err := t.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if line == 0 && err != nil {
+			return report, err
+		}
+		report.Rows++
+		if err != nil {
+			report.Failed++
+			addError(report, line, err)
+			continue
+		}
+
+		product, err := u.parseProduct(t, record)
+		if err == nil {
+			err = checkDuplicate(seen, product, line)
+		}
+		if err != nil {
+			report.Failed++
+			addError(report, line, err)
+			continue
+		}
+
+		batch = append(batch, product)
+		lines = append(lines, line)
+		if len(batch) >= u.batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// checkDuplicate remembers the product and fails if an earlier row already
+// matches it. Rows without a SKU match by name, like the repository does.
+func checkDuplicate(seen map[string]int, product *model.Product, line int) error {
+	name := "name:" + strings.ToLower(product.Name)
+	key := name
+	if product.SKU != "" {
+		key = "sku:" + product.SKU
+	}
+
+	if first, ok := seen[key]; ok {
+		return invalid("", "same product as line %d", first)
+	}
+	seen[key] = line
+	if _, ok := seen[name]; !ok {
+		seen[name] = line
+	}
+	return nil
+}
+
+func (u *usecase) parseProduct(t *table, record []string) (*model.Product, error) {
+	product := &model.Product{
+		SKU:         t.get(record, "sku"),
+		Name:        t.get(record, "name"),
+		Description: t.get(record, "description"),
+		Price:       model.Money{Currency: strings.ToUpper(t.get(record, "currency"))},
+		Active:      true,
+	}
+
+	if product.Name == "" {
+		return nil, invalid("name", "must not be empty")
+	}
+	if len(product.SKU) > 64 {
+		return nil, invalid("sku", "must be at most 64 characters")
+	}
+	if product.Price.Currency == "" {
+		product.Price.Currency = u.defaultCurrency
+	}
+	if len(product.Price.Currency) != 3 {
+		return nil, invalid("currency", "must be an ISO 4217 code")
+	}
+
+	price := t.get(record, "price")
+	if price == "" {
+		return nil, invalid("price", "must not be empty")
+	}
+	var err error
+	if product.Price.Amount, err = parseMoney("price", price, product.Price.Currency); err != nil {
+		return nil, err
+	}
+
+	if weight := t.get(record, "weight"); weight != "" {
+		if product.Weigth, err = parseWeight("weight", weight); err != nil {
+			return nil, err
+		}
+	}
+
+	if active := t.get(record, "active"); active != "" {
+		if product.Active, err = parseBool("active", active); err != nil {
+			return nil, err
+		}
+	}
+
+	return product, nil
+}
@@ -0,0 +1,154 @@
+package std
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var bom = []byte("\uFEFF")
+
+// rowError is a problem with a single cell or row of the file
+type rowError struct {
+	column  string
+	message string
+}
+
+func (e *rowError) Error() string {
+	if e.column == "" {
+		return e.message
+	}
+	return e.column + ": " + e.message
+}
+
+func invalid(column, format string, args ...any) error {
+	return &rowError{column: column, message: fmt.Sprintf(format, args...)}
+}
+
+// table reads a CSV file with a header row. The field separator is detected
+// from the header, so files saved by spreadsheets with ";" work as well.
+type table struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// newTable reads the header and maps its names to the known columns. Names
+// are matched case-insensitively, ignoring spaces, "_" and "-".
+func newTable(r io.Reader, known []string, required ...string) (*table, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(bom)); bytes.Equal(head, bom) {
+		br.Discard(len(bom))
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = detectSeparator(br)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, invalid("", "file is empty")
+	}
+	if err != nil {
+		return nil, invalid("", "%v", err)
+	}
+
+	byName := make(map[string]string, len(known))
+	for _, name := range known {
+		byName[normalizeColumn(name)] = name
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, raw := range header {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		name, ok := byName[normalizeColumn(raw)]
+		if !ok {
+			return nil, invalid(raw, "unknown column, expected one of %s", strings.Join(known, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, invalid(raw, "duplicate column")
+		}
+		columns[name] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, invalid(name, "required column is missing")
+		}
+	}
+
+	return &table{r: cr, columns: columns}, nil
+}
+
+// next returns the next non-blank row and the line it starts on. A malformed
+// row is returned as a rowError, io.EOF ends the file.
+func (t *table) next() ([]string, int, error) {
+	for {
+		record, err := t.r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, parseErr.StartLine, invalid("", "%v", parseErr.Err)
+			}
+			return nil, 0, err
+		}
+
+		line, _ := t.r.FieldPos(0)
+		for _, cell := range record {
+			if strings.TrimSpace(cell) != "" {
+				return record, line, nil
+			}
+		}
+	}
+}
+
+// has reports whether the file has the column
+func (t *table) has(column string) bool {
+	_, ok := t.columns[column]
+	return ok
+}
+
+// get returns the trimmed cell of the column, empty if the file has no
+// such column or the row is short
+func (t *table) get(record []string, column string) string {
+	i, ok := t.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func normalizeColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// detectSeparator picks the most frequent of ",", ";" and tab in the
+// header line
+func detectSeparator(br *bufio.Reader) rune {
+	head, _ := br.Peek(4096)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+
+	separator, count := ',', bytes.Count(head, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if n := bytes.Count(head, []byte(string(candidate))); n > count {
+			separator, count = candidate, n
+		}
+	}
+	return separator
+}
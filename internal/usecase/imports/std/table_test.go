@@ -0,0 +1,110 @@
+package std
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testColumns = []string{"externalId", "name", "phone", "price"}
+
+func TestNewTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    map[string]int
+		wantErr string
+	}{
+		{"comma", "externalId,name\n", map[string]int{"externalId": 0, "name": 1}, ""},
+		{"semicolon", "externalId;name;price\n", map[string]int{"externalId": 0, "name": 1, "price": 2}, ""},
+		{"tab", "name\tphone\n", map[string]int{"name": 0, "phone": 1}, ""},
+		{"semicolon with commas in names", "name;phone;price, RUB\n", nil, "price, RUB: unknown column, expected one of externalId, name, phone, price"},
+		{"byte order mark", "\uFEFFname,phone\n", map[string]int{"name": 0, "phone": 1}, ""},
+		{"loose names", " External_ID ,NAME, Phone \n", map[string]int{"externalId": 0, "name": 1, "phone": 2}, ""},
+		{"blank column skipped", "name,,phone\n", map[string]int{"name": 0, "phone": 2}, ""},
+		{"unknown column", "name,color\n", nil, "color: unknown column, expected one of externalId, name, phone, price"},
+		{"duplicate column", "name,Name\n", nil, "Name: duplicate column"},
+		{"required column missing", "phone\n", nil, "name: required column is missing"},
+		{"empty file", "", nil, "file is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := newTable(strings.NewReader(tt.file), testColumns, "name")
+			if tt.wantErr != "" {
+				var rowErr *rowError
+				if !errors.As(err, &rowErr) || err.Error() != tt.wantErr {
+					t.Fatalf("newTable() = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newTable() = %v", err)
+			}
+			if !reflect.DeepEqual(table.columns, tt.want) {
+				t.Errorf("columns %v, want %v", table.columns, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableRows(t *testing.T) {
+	file := "\uFEFFname;price\r\n" +
+		"Ann;1 234,50\r\n" +
+		"\r\n" +
+		" ; \r\n" +
+		"\"Bob\nSmith\";10\r\n" +
+		"Eve\r\n"
+	table, err := newTable(strings.NewReader(file), testColumns, "name")
+	if err != nil {
+		t.Fatalf("newTable() = %v", err)
+	}
+
+	want := []struct {
+		line  int
+		name  string
+		price string
+	}{
+		{2, "Ann", "1 234,50"},
+		{5, "Bob\nSmith", "10"},
+		{7, "Eve", ""},
+	}
+	for _, w := range want {
+		record, line, err := table.next()
+		if err != nil {
+			t.Fatalf("next() = %v", err)
+		}
+		if line != w.line || table.get(record, "name") != w.name || table.get(record, "price") != w.price {
+			t.Errorf("line %d %q %q, want line %d %q %q", line, table.get(record, "name"), table.get(record, "price"), w.line, w.name, w.price)
+		}
+		if table.get(record, "phone") != "" {
+			t.Errorf("line %d: missing column is not empty", line)
+		}
+	}
+	if _, _, err := table.next(); !errors.Is(err, io.EOF) {
+		t.Errorf("next() at the end = %v, want io.EOF", err)
+	}
+
+	if !table.has("price") || table.has("phone") {
+		t.Errorf("has() does not match the header")
+	}
+}
+
+func TestTableMalformedRow(t *testing.T) {
+	table, err := newTable(strings.NewReader("name,price\nAnn,\"1\"0\nBob,5\n"), testColumns, "name")
+	if err != nil {
+		t.Fatalf("newTable() = %v", err)
+	}
+
+	_, line, err := table.next()
+	var rowErr *rowError
+	if !errors.As(err, &rowErr) || line != 2 {
+		t.Fatalf("next() = line %d, %v, want a row error on line 2", line, err)
+	}
+	record, line, err := table.next()
+	if err != nil || line != 3 || table.get(record, "name") != "Bob" {
+		t.Errorf("next() after the malformed row = line %d, %v, %v", line, record, err)
+	}
+}
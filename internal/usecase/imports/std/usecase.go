@@ -0,0 +1,76 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	ordersRepo "backend_crm/internal/repository/orders"
+	productsRepo "backend_crm/internal/repository/products"
+	"backend_crm/internal/usecase/imports"
+	"errors"
+)
+
+var _ imports.Usecase = &usecase{}
+
+type usecase struct {
+	products productsRepo.Repository
+	orders   ordersRepo.Repository
+
+	defaultCurrency string
+	// taxRate in basis points for imported orders
+	taxRate   int
+	batchSize int
+}
+
+// NewUsecase stores batchSize records per transaction, imports.DefaultBatchSize
+// when it is not positive
+func NewUsecase(
+	products productsRepo.Repository,
+	orders ordersRepo.Repository,
+	defaultCurrency string,
+	taxRate int,
+	batchSize int,
+) imports.Usecase {
+	if batchSize <= 0 {
+		batchSize = imports.DefaultBatchSize
+	}
+
+	return &usecase{
+		products:        products,
+		orders:          orders,
+		defaultCurrency: defaultCurrency,
+		taxRate:         taxRate,
+		batchSize:       batchSize,
+	}
+}
+
+// addError records a failed record in the report. Validation problems keep
+// their column, anything the repository rejected is reported as a whole.
+func addError(report *model.ImportReport, line int, err error) {
+	var rowErr *rowError
+	if errors.As(err, &rowErr) {
+		report.Errors = append(report.Errors, model.ImportRowError{
+			Line:    line,
+			Column:  rowErr.column,
+			Message: rowErr.message,
+		})
+		return
+	}
+
+	report.Errors = append(report.Errors, model.ImportRowError{
+		Line:    line,
+		Message: repositoryMessage(err),
+	})
+}
+
+func repositoryMessage(err error) string {
+	switch {
+	case errors.Is(err, productsRepo.ErrAmbiguousProduct):
+		return "several products have this name, give a sku to pick one"
+	case errors.Is(err, ordersRepo.ErrNotFoundProduct):
+		return "product not found"
+	case errors.Is(err, ordersRepo.ErrCurrencyMismatch):
+		return "all products of an order must have the same currency"
+	case errors.Is(err, ordersRepo.ErrEmptyOrder):
+		return "order has no items"
+	}
+	return "could not be saved: " + err.Error()
+}
@@ -0,0 +1,37 @@
+package notifications
+
+import (
+	"context"
+	"time"
+)
+
+// BatchSize is the number of messages claimed from an outbox at once
+const BatchSize = 20
+
+// Policy controls how queued messages of a channel are delivered
+type Policy struct {
+	// Interval between checks of the outbox
+	Interval    time.Duration
+	MaxAttempts int
+	// RetryBackoff is the delay after the first failed attempt, doubled
+	// with every further one
+	RetryBackoff time.Duration
+	// MaxAge drops messages that could not be sent in time
+	MaxAge time.Duration
+	// SendTimeout bounds the delivery of a single message
+	SendTimeout time.Duration
+	// Lease hides a claimed batch from other workers. Messages are only
+	// sent while SendTimeout still fits into it, it must be longer.
+	Lease time.Duration
+}
+
+// Usecase delivers the outbox of one channel
+type Usecase interface {
+	// Process sends the due messages, one batch at a time, and returns
+	// how many it handled. Failed deliveries are scheduled for a retry;
+	// the error is only returned when the outbox could not be read or
+	// updated.
+	Process(ctx context.Context) (int, error)
+	// Run processes the outbox periodically until ctx is done
+	Run(ctx context.Context)
+}
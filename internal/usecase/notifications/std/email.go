@@ -0,0 +1,126 @@
+package std
+
+import (
+	"backend_crm/internal/mail"
+	"backend_crm/internal/model"
+	"backend_crm/internal/notification"
+	emailsRepo "backend_crm/internal/repository/emails"
+	ordersRepo "backend_crm/internal/repository/orders"
+	"backend_crm/internal/usecase/notifications"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var _ notifications.Usecase = &emailUsecase{}
+
+type emailUsecase struct {
+	emails emailsRepo.Repository
+	orders ordersRepo.Repository
+	sender mail.Sender
+
+	company string
+	policy  notifications.Policy
+	logger  zerolog.Logger
+}
+
+// NewEmailUsecase delivers the email outbox through sender
+func NewEmailUsecase(
+	emails emailsRepo.Repository,
+	orders ordersRepo.Repository,
+	sender mail.Sender,
+	company string,
+	policy notifications.Policy,
+	logger zerolog.Logger,
+) notifications.Usecase {
+	return &emailUsecase{
+		emails:  emails,
+		orders:  orders,
+		sender:  sender,
+		company: company,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+func (u *emailUsecase) Run(ctx context.Context) {
+	run(ctx, u.policy.Interval, u.Process, u.logger)
+}
+
+func (u *emailUsecase) Process(ctx context.Context) (int, error) {
+	claimed := time.Now()
+	batch, err := u.emails.Claim(ctx, notifications.BatchSize, u.policy.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim: %w", err)
+	}
+
+	for i, email := range batch {
+		if ctx.Err() != nil {
+			// The lease brings the rest back later
+			return i, ctx.Err()
+		}
+		if !inLease(u.policy, claimed) {
+			// The rest is claimed again once the lease ran out
+			return i, nil
+		}
+		if err := u.send(ctx, email); err != nil {
+			return i, err
+		}
+	}
+
+	return len(batch), nil
+}
+
+// send delivers one email and records the outcome
+func (u *emailUsecase) send(ctx context.Context, email *model.OutboxEmail) error {
+	log := u.logger.With().Str("email_id", email.EmailId).Str("order_id", email.OrderId).Str("event", string(email.Event)).Logger()
+
+	if time.Since(email.CreatedAt) > u.policy.MaxAge {
+		log.Warn().Msg("email expired")
+		return u.emails.Finish(ctx, email.EmailId, model.DeliverySkipped, "expired")
+	}
+
+	order, err := u.orders.GetById(ctx, email.OrderId)
+	if err != nil {
+		if errors.Is(err, ordersRepo.ErrNotFoundOrder) {
+			return u.emails.Finish(ctx, email.EmailId, model.DeliverySkipped, "order not found")
+		}
+		return u.retry(ctx, email, fmt.Errorf("load order: %w", err), log)
+	}
+	if order.EmailOptOut {
+		return u.emails.Finish(ctx, email.EmailId, model.DeliverySkipped, "opted out")
+	}
+
+	msg, err := notification.Email(email.Event, order, u.company)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to render email")
+		return u.emails.Finish(ctx, email.EmailId, model.DeliveryFailed, err.Error())
+	}
+	msg.To = []string{email.Recipient}
+
+	if err := u.sender.Send(ctx, msg); err != nil {
+		if errors.Is(err, mail.ErrPermanent) {
+			log.Warn().Err(err).Msg("email rejected")
+			return u.emails.Finish(ctx, email.EmailId, model.DeliveryFailed, err.Error())
+		}
+		return u.retry(ctx, email, err, log)
+	}
+
+	log.Debug().Msg("email sent")
+	return u.emails.MarkSent(ctx, email.EmailId)
+}
+
+// retry schedules the next attempt, or gives up after the last one
+func (u *emailUsecase) retry(ctx context.Context, email *model.OutboxEmail, cause error, log zerolog.Logger) error {
+	if email.Attempts >= u.policy.MaxAttempts {
+		log.Warn().Err(cause).Int("attempts", email.Attempts).Msg("email failed")
+		return u.emails.Finish(ctx, email.EmailId, model.DeliveryFailed, cause.Error())
+	}
+
+	delay := retryDelay(u.policy, email.Attempts)
+	log.Info().Err(cause).Int("attempts", email.Attempts).Dur("retry_in", delay).Msg("email delivery failed")
+	return u.emails.Retry(ctx, email.EmailId, time.Now().Add(delay), cause.Error())
+}
@@ -0,0 +1,48 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	emailsRepo "backend_crm/internal/repository/emails"
+	smsRepo "backend_crm/internal/repository/sms"
+	"backend_crm/internal/usecase/events"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// NotificationHandler queues the email and the SMS telling the customer
+// about an order event. They are queued whether or not a channel is
+// enabled.
+func NotificationHandler(emails emailsRepo.Repository, messages smsRepo.Repository) events.Handler {
+	return func(ctx context.Context, event *model.DomainEvent) error {
+		orderEvent, ok, err := customerEvent(event)
+		if err != nil || !ok {
+			return err
+		}
+
+		if err := emails.Enqueue(ctx, event.EventId, event.OrderId, orderEvent); err != nil {
+			return fmt.Errorf("enqueue email: %w", err)
+		}
+		if err := messages.Enqueue(ctx, event.EventId, event.OrderId, orderEvent); err != nil {
+			return fmt.Errorf("enqueue sms: %w", err)
+		}
+		return nil
+	}
+}
+
+// customerEvent returns what the customer is told about a domain event, if
+// anything
+func customerEvent(event *model.DomainEvent) (model.OrderEvent, bool, error) {
+	switch event.Type {
+	case model.EventOrderCreated:
+		return model.OrderReceived, true, nil
+	case model.EventOrderStatusChanged:
+		var data model.OrderEventData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return "", false, fmt.Errorf("decode event data: %w", err)
+		}
+		orderEvent, ok := model.StatusEvent(data.Status)
+		return orderEvent, ok, nil
+	}
+	return "", false, nil
+}
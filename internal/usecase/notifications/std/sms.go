@@ -0,0 +1,157 @@
+package std
+
+import (
+	"backend_crm/internal/contact"
+	"backend_crm/internal/model"
+	"backend_crm/internal/notification"
+	ordersRepo "backend_crm/internal/repository/orders"
+	smsRepo "backend_crm/internal/repository/sms"
+	"backend_crm/internal/sms"
+	"backend_crm/internal/usecase/notifications"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var _ notifications.Usecase = &smsUsecase{}
+
+type smsUsecase struct {
+	messages smsRepo.Repository
+	orders   ordersRepo.Repository
+	sender   sms.Sender
+
+	company string
+	// countryCode is prepended to phones given without one
+	countryCode string
+	// rateLimit messages at most go to one number per rateWindow
+	rateLimit  int
+	rateWindow time.Duration
+	policy     notifications.Policy
+	logger     zerolog.Logger
+}
+
+// NewSMSUsecase delivers the SMS outbox through sender, sending at most
+// rateLimit messages to a number within rateWindow
+func NewSMSUsecase(
+	messages smsRepo.Repository,
+	orders ordersRepo.Repository,
+	sender sms.Sender,
+	company string,
+	countryCode string,
+	rateLimit int,
+	rateWindow time.Duration,
+	policy notifications.Policy,
+	logger zerolog.Logger,
+) notifications.Usecase {
+	return &smsUsecase{
+		messages:    messages,
+		orders:      orders,
+		sender:      sender,
+		company:     company,
+		countryCode: countryCode,
+		rateLimit:   rateLimit,
+		rateWindow:  rateWindow,
+		policy:      policy,
+		logger:      logger,
+	}
+}
+
+func (u *smsUsecase) Run(ctx context.Context) {
+	run(ctx, u.policy.Interval, u.Process, u.logger)
+}
+
+func (u *smsUsecase) Process(ctx context.Context) (int, error) {
+	claimed := time.Now()
+	batch, err := u.messages.Claim(ctx, notifications.BatchSize, u.policy.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim: %w", err)
+	}
+
+	for i, msg := range batch {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		if !inLease(u.policy, claimed) {
+			// The rest is claimed again once the lease ran out
+			return i, nil
+		}
+		if err := u.send(ctx, msg); err != nil {
+			return i, err
+		}
+	}
+
+	return len(batch), nil
+}
+
+// send delivers one message and records the outcome
+func (u *smsUsecase) send(ctx context.Context, msg *model.OutboxSMS) error {
+	log := u.logger.With().Str("sms_id", msg.SMSId).Str("order_id", msg.OrderId).Str("event", string(msg.Event)).Logger()
+
+	if time.Since(msg.CreatedAt) > u.policy.MaxAge {
+		log.Warn().Msg("sms expired")
+		return u.messages.Finish(ctx, msg.SMSId, model.DeliverySkipped, "expired")
+	}
+
+	to, ok := contact.InternationalPhone(msg.Recipient, u.countryCode)
+	if !ok {
+		return u.messages.Finish(ctx, msg.SMSId, model.DeliverySkipped, "invalid phone number")
+	}
+
+	sent, oldest, err := u.messages.SentSince(ctx, to, time.Now().Add(-u.rateWindow))
+	if err != nil {
+		return fmt.Errorf("count sent: %w", err)
+	}
+	if sent >= u.rateLimit {
+		// Wait until the oldest message leaves the window; expired
+		// messages are skipped then
+		at := oldest.Add(u.rateWindow)
+		log.Debug().Time("until", at).Msg("sms rate limited")
+		return u.messages.Postpone(ctx, msg.SMSId, at, "rate limited")
+	}
+
+	order, err := u.orders.GetById(ctx, msg.OrderId)
+	if err != nil {
+		if errors.Is(err, ordersRepo.ErrNotFoundOrder) {
+			return u.messages.Finish(ctx, msg.SMSId, model.DeliverySkipped, "order not found")
+		}
+		return u.retry(ctx, msg, fmt.Errorf("load order: %w", err), log)
+	}
+	if order.SMSOptOut {
+		return u.messages.Finish(ctx, msg.SMSId, model.DeliverySkipped, "opted out")
+	}
+
+	text, err := notification.SMS(msg.Event, order, u.company)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to render sms")
+		return u.messages.Finish(ctx, msg.SMSId, model.DeliveryFailed, err.Error())
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, u.policy.SendTimeout)
+	providerId, err := u.sender.Send(sendCtx, &sms.Message{To: to, Text: text})
+	cancel()
+	if err != nil {
+		if errors.Is(err, sms.ErrPermanent) {
+			log.Warn().Err(err).Msg("sms rejected")
+			return u.messages.Finish(ctx, msg.SMSId, model.DeliveryFailed, err.Error())
+		}
+		return u.retry(ctx, msg, err, log)
+	}
+
+	log.Debug().Str("provider_message_id", providerId).Msg("sms sent")
+	return u.messages.MarkSent(ctx, msg.SMSId, to, providerId)
+}
+
+// retry schedules the next attempt, or gives up after the last one
+func (u *smsUsecase) retry(ctx context.Context, msg *model.OutboxSMS, cause error, log zerolog.Logger) error {
+	if msg.Attempts >= u.policy.MaxAttempts {
+		log.Warn().Err(cause).Int("attempts", msg.Attempts).Msg("sms failed")
+		return u.messages.Finish(ctx, msg.SMSId, model.DeliveryFailed, cause.Error())
+	}
+
+	delay := retryDelay(u.policy, msg.Attempts)
+	log.Info().Err(cause).Int("attempts", msg.Attempts).Dur("retry_in", delay).Msg("sms delivery failed")
+	return u.messages.Retry(ctx, msg.SMSId, time.Now().Add(delay), cause.Error())
+}
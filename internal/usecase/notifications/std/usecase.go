@@ -0,0 +1,52 @@
+package std
+
+import (
+	"backend_crm/internal/usecase/notifications"
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxBackoff caps the delay between two attempts
+const maxBackoff = 6 * time.Hour
+
+// run calls process every interval until ctx is done, right away again
+// while it handles full batches
+func run(ctx context.Context, interval time.Duration, process func(context.Context) (int, error), logger zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := process(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error().Err(err).Msg("failed to process outbox")
+			}
+			if err != nil || n < notifications.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// inLease reports whether a message of a batch claimed at claimed can still
+// be sent before its lease runs out and another worker may claim it again
+func inLease(policy notifications.Policy, claimed time.Time) bool {
+	return time.Since(claimed)+policy.SendTimeout < policy.Lease
+}
+
+// retryDelay doubles the backoff with every attempt after the first
+func retryDelay(policy notifications.Policy, attempts int) time.Duration {
+	delay := policy.RetryBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
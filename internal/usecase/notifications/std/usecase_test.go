@@ -0,0 +1,52 @@
+package std
+
+import (
+	"backend_crm/internal/usecase/notifications"
+	"testing"
+	"time"
+)
+
+func TestInLease(t *testing.T) {
+	policy := notifications.Policy{SendTimeout: 10 * time.Second, Lease: time.Minute}
+
+	tests := []struct {
+		name    string
+		claimed time.Duration // ago
+		want    bool
+	}{
+		{"just claimed", 0, true},
+		{"time left for one more", 45 * time.Second, true},
+		{"would outlive the lease", 50 * time.Second, false},
+		{"lease ran out", 2 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inLease(policy, time.Now().Add(-tt.claimed)); got != tt.want {
+				t.Errorf("inLease() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := notifications.Policy{RetryBackoff: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{5, 16 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, maxBackoff},
+		{50, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(policy, tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	ordersRepo "backend_crm/internal/repository/orders"
+	webhooksRepo "backend_crm/internal/repository/webhooks"
+	"backend_crm/internal/usecase/notifications"
+	"backend_crm/internal/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var _ notifications.Usecase = &webhookUsecase{}
+
+type webhookUsecase struct {
+	webhooks webhooksRepo.Repository
+	orders   ordersRepo.Repository
+	sender   webhook.Sender
+
+	policy notifications.Policy
+	logger zerolog.Logger
+}
+
+// NewWebhookUsecase delivers the queued webhook events through sender
+func NewWebhookUsecase(
+	webhooks webhooksRepo.Repository,
+	orders ordersRepo.Repository,
+	sender webhook.Sender,
+	policy notifications.Policy,
+	logger zerolog.Logger,
+) notifications.Usecase {
+	return &webhookUsecase{
+		webhooks: webhooks,
+		orders:   orders,
+		sender:   sender,
+		policy:   policy,
+		logger:   logger,
+	}
+}
+
+func (u *webhookUsecase) Run(ctx context.Context) {
+	run(ctx, u.policy.Interval, u.Process, u.logger)
+}
+
+func (u *webhookUsecase) Process(ctx context.Context) (int, error) {
+	claimed := time.Now()
+	batch, err := u.webhooks.Claim(ctx, notifications.BatchSize, u.policy.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim: %w", err)
+	}
+
+	for i, delivery := range batch {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		if !inLease(u.policy, claimed) {
+			// The rest is claimed again once the lease ran out
+			return i, nil
+		}
+		if err := u.send(ctx, delivery); err != nil {
+			if errors.Is(err, webhooksRepo.ErrNotClaimed) {
+				u.logger.Warn().Str("delivery_id", delivery.DeliveryId).Msg("webhook delivery was claimed again while it was sent")
+				continue
+			}
+			return i, err
+		}
+	}
+
+	return len(batch), nil
+}
+
+// send posts one delivery and records the outcome
+func (u *webhookUsecase) send(ctx context.Context, delivery *model.WebhookDelivery) error {
+	log := u.logger.With().
+		Str("delivery_id", delivery.DeliveryId).
+		Str("webhook_id", delivery.WebhookId).
+		Str("event", string(delivery.Event)).
+		Logger()
+
+	if time.Since(delivery.CreatedAt) > u.policy.MaxAge {
+		log.Warn().Msg("webhook delivery expired")
+		return u.webhooks.Finish(ctx, delivery.DeliveryId, delivery.Attempts, model.DeliverySkipped, "expired", nil)
+	}
+
+	hook, err := u.webhooks.GetById(ctx, delivery.WebhookId)
+	if err != nil {
+		if errors.Is(err, webhooksRepo.ErrNotFoundWebhook) {
+			// Deleted together with its deliveries
+			return nil
+		}
+		return u.retry(ctx, delivery, fmt.Errorf("load webhook: %w", err), nil, log)
+	}
+	if !hook.Active {
+		return u.webhooks.Finish(ctx, delivery.DeliveryId, delivery.Attempts, model.DeliverySkipped, "webhook disabled", nil)
+	}
+	if !hook.Subscribed(delivery.Event) {
+		return u.webhooks.Finish(ctx, delivery.DeliveryId, delivery.Attempts, model.DeliverySkipped, "unsubscribed", nil)
+	}
+
+	order, err := u.orders.GetById(ctx, delivery.OrderId)
+	if err != nil {
+		if errors.Is(err, ordersRepo.ErrNotFoundOrder) {
+			return u.webhooks.Finish(ctx, delivery.DeliveryId, delivery.Attempts, model.DeliverySkipped, "order not found", nil)
+		}
+		return u.retry(ctx, delivery, fmt.Errorf("load order: %w", err), nil, log)
+	}
+
+	body, err := webhook.Body(delivery, order)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to build webhook payload")
+		return u.webhooks.Finish(ctx, delivery.DeliveryId, delivery.Attempts, model.DeliveryFailed, err.Error(), nil)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, u.policy.SendTimeout)
+	defer cancel()
+
+	response, err := u.sender.Send(sendCtx, &webhook.Request{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		Event:      delivery.Event,
+		EventId:    delivery.EventId,
+		DeliveryId: delivery.DeliveryId,
+		Body:       body,
+	})
+	if err != nil {
+		return u.retry(ctx, delivery, err, nil, log)
+	}
+	if response.Status < 200 || response.Status > 299 {
+		return u.retry(ctx, delivery, fmt.Errorf("receiver answered %d", response.Status), response, log)
+	}
+
+	log.Debug().Int("status", response.Status).Dur("took", response.Duration).Msg("webhook delivered")
+	return u.webhooks.MarkSent(ctx, delivery.DeliveryId, delivery.Attempts, response)
+}
+
+// retry schedules the next attempt, or gives up after the last one
+func (u *webhookUsecase) retry(ctx context.Context, delivery *model.WebhookDelivery, cause error, response *model.WebhookResponse, log zerolog.Logger) error {
+	if delivery.Attempts >= u.policy.MaxAttempts {
+		log.Warn().Err(cause).Int("attempts", delivery.Attempts).Msg("webhook delivery failed")
+		return u.webhooks.Finish(ctx, delivery.DeliveryId, delivery.Attempts, model.DeliveryFailed, cause.Error(), response)
+	}
+
+	delay := retryDelay(u.policy, delivery.Attempts)
+	log.Info().Err(cause).Int("attempts", delivery.Attempts).Dur("retry_in", delay).Msg("webhook delivery failed")
+	return u.webhooks.Retry(ctx, delivery.DeliveryId, delivery.Attempts, time.Now().Add(delay), cause.Error(), response)
+}
@@ -0,0 +1,20 @@
+package orders
+
+import (
+	"backend_crm/internal/model"
+	"context"
+)
+
+// Usecase makes the order changes other parts of the system react to. Each
+// change is stored together with its domain events, see the events
+// dispatcher; errors are those of the orders repository.
+type Usecase interface {
+	// Create places an order and emits order.created
+	Create(ctx context.Context, newOrder *model.NewOrder) error
+	// UpdateStatus changes the status as userId and emits
+	// order.status_changed unless the order already has the status
+	UpdateStatus(ctx context.Context, orderId string, status model.OrderStatus, userId string) error
+	// BulkUpdate applies the update like the orders repository and emits
+	// order.status_changed for every status it changed
+	BulkUpdate(ctx context.Context, update *model.BulkOrderUpdate) ([]model.BulkOrderResult, error)
+}
@@ -0,0 +1,79 @@
+package std
+
+import (
+	"backend_crm/internal/model"
+	ordersRepo "backend_crm/internal/repository/orders"
+	"backend_crm/internal/usecase/events"
+	"backend_crm/internal/usecase/orders"
+	"context"
+	"encoding/json"
+)
+
+var _ orders.Usecase = &usecase{}
+
+type usecase struct {
+	orders     ordersRepo.Repository
+	dispatcher events.Dispatcher
+}
+
+// NewUsecase notifies dispatcher of the events it stored
+func NewUsecase(orders ordersRepo.Repository, dispatcher events.Dispatcher) orders.Usecase {
+	return &usecase{
+		orders:     orders,
+		dispatcher: dispatcher,
+	}
+}
+
+func (u *usecase) Create(ctx context.Context, newOrder *model.NewOrder) error {
+	if err := u.orders.Save(ctx, newOrder, orderEvents); err != nil {
+		return err
+	}
+
+	u.dispatcher.Notify()
+	return nil
+}
+
+func (u *usecase) UpdateStatus(ctx context.Context, orderId string, status model.OrderStatus, userId string) error {
+	if err := u.orders.UpdateOrderStatus(ctx, orderId, status, userId, orderEvents); err != nil {
+		return err
+	}
+
+	u.dispatcher.Notify()
+	return nil
+}
+
+func (u *usecase) BulkUpdate(ctx context.Context, update *model.BulkOrderUpdate) ([]model.BulkOrderResult, error) {
+	results, err := u.orders.BulkUpdate(ctx, update, orderEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	u.dispatcher.Notify()
+	return results, nil
+}
+
+// orderEvents describes a transition as domain events
+func orderEvents(transition *model.OrderTransition) ([]*model.DomainEvent, error) {
+	event := &model.DomainEvent{
+		Type:       model.EventOrderStatusChanged,
+		OrderId:    transition.OrderId,
+		AssigneeId: transition.AssigneeId,
+	}
+	data := model.OrderEventData{
+		Status: transition.To,
+		UserId: transition.UserId,
+	}
+
+	if transition.Created {
+		event.Type = model.EventOrderCreated
+	} else {
+		from := transition.From
+		data.PreviousStatus = &from
+	}
+
+	var err error
+	if event.Data, err = json.Marshal(data); err != nil {
+		return nil, err
+	}
+	return []*model.DomainEvent{event}, nil
+}
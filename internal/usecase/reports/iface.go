@@ -0,0 +1,32 @@
+package reports
+
+import (
+	"backend_crm/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFoundReport = errors.New("not found report")
+	ErrUnknownFormat  = errors.New("unknown report format")
+)
+
+type Usecase interface {
+	// Schedules lists the configured reports
+	Schedules() []model.ReportSchedule
+	// Build collects the numbers of the named report for the period
+	// before the day of at
+	Build(ctx context.Context, name string, at time.Time) (*model.Report, error)
+	// Render returns the report as a document in format and its content type
+	Render(report *model.Report, format model.ReportFormat) ([]byte, string, error)
+	// Deliver builds the named report and sends it to its recipients and
+	// directory
+	Deliver(ctx context.Context, name string, at time.Time) error
+	// DeliverScheduled delivers the run of the named report due at, unless
+	// another instance already took it. It is what the scheduler runs. A
+	// failed run is left for RetryRuns.
+	DeliverScheduled(ctx context.Context, name string, at time.Time) error
+	// RetryRuns delivers the failed runs that are due for another attempt
+	RetryRuns(ctx context.Context) error
+}
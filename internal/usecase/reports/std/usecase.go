@@ -0,0 +1,295 @@
+package std
+
+import (
+	"backend_crm/internal/mail"
+	"backend_crm/internal/model"
+	"backend_crm/internal/report"
+	analyticsRepo "backend_crm/internal/repository/analytics"
+	reportsRepo "backend_crm/internal/repository/reports"
+	"backend_crm/internal/usecase/reports"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ reports.Usecase = &usecase{}
+
+const (
+	// runLease hides a run being delivered from the retries of other
+	// instances, a crashed instance's run is tried again after it
+	runLease = 15 * time.Minute
+	// runRetryBackoff is the delay after the first failed attempt, doubled
+	// with every further one up to maxRunBackoff
+	runRetryBackoff = 5 * time.Minute
+	maxRunBackoff   = 6 * time.Hour
+	maxRunAttempts  = 6
+	// retryBatch limits the runs RetryRuns takes at once
+	retryBatch = 10
+)
+
+type usecase struct {
+	analytics analyticsRepo.Repository
+	runs      reportsRepo.Repository
+	// sender is nil when no SMTP server is configured
+	sender    mail.Sender
+	schedules []model.ReportSchedule
+
+	loc          *time.Location
+	overdueAfter time.Duration
+}
+
+// NewUsecase builds reports with periods and dates in loc. sender may be
+// nil as long as no schedule has recipients.
+func NewUsecase(
+	analytics analyticsRepo.Repository,
+	runs reportsRepo.Repository,
+	sender mail.Sender,
+	schedules []model.ReportSchedule,
+	loc *time.Location,
+	overdueAfter time.Duration,
+) reports.Usecase {
+	return &usecase{
+		analytics:    analytics,
+		runs:         runs,
+		sender:       sender,
+		schedules:    schedules,
+		loc:          loc,
+		overdueAfter: overdueAfter,
+	}
+}
+
+func (u *usecase) Schedules() []model.ReportSchedule {
+	return u.schedules
+}
+
+func (u *usecase) schedule(name string) (*model.ReportSchedule, error) {
+	for i := range u.schedules {
+		if u.schedules[i].Name == name {
+			return &u.schedules[i], nil
+		}
+	}
+	return nil, reports.ErrNotFoundReport
+}
+
+func (u *usecase) Build(ctx context.Context, name string, at time.Time) (*model.Report, error) {
+	schedule, err := u.schedule(name)
+	if err != nil {
+		return nil, err
+	}
+
+	at = at.In(u.loc)
+	to := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, u.loc)
+	var from time.Time
+	switch schedule.Period {
+	case model.GroupByMonth:
+		from = to.AddDate(0, -1, 0)
+	case model.GroupByWeek:
+		from = to.AddDate(0, 0, -7)
+	default:
+		from = to.AddDate(0, 0, -1)
+	}
+	rng := model.AnalyticsRange{From: from, To: to, GroupBy: schedule.Period}
+
+	r := &model.Report{
+		Name:         name,
+		From:         from,
+		To:           to,
+		GeneratedAt:  at,
+		OverdueAfter: u.overdueAfter,
+	}
+
+	if r.Statuses, err = u.analytics.OrdersByStatus(ctx, rng); err != nil {
+		return nil, fmt.Errorf("orders by status: %w", err)
+	}
+	for _, s := range r.Statuses {
+		r.NewOrders += s.Orders
+	}
+
+	if r.Completed, r.CompletedWeight, err = u.analytics.Completed(ctx, rng); err != nil {
+		return nil, fmt.Errorf("completed: %w", err)
+	}
+
+	r.Overdue, r.OverdueTotal, err = u.analytics.Overdue(ctx, at.Add(-u.overdueAfter), model.MaxReportOverdue)
+	if err != nil {
+		return nil, fmt.Errorf("overdue: %w", err)
+	}
+	for _, o := range r.Overdue {
+		o.CreatedAt = o.CreatedAt.In(u.loc)
+	}
+
+	return r, nil
+}
+
+func (u *usecase) Render(r *model.Report, format model.ReportFormat) ([]byte, string, error) {
+	switch format {
+	case model.ReportHTML:
+		data, err := report.HTML(r)
+		return data, "text/html; charset=utf-8", err
+	case model.ReportPDF:
+		data, err := report.PDF(r)
+		return data, "application/pdf", err
+	}
+	return nil, "", reports.ErrUnknownFormat
+}
+
+func (u *usecase) Deliver(ctx context.Context, name string, at time.Time) error {
+	schedule, err := u.schedule(name)
+	if err != nil {
+		return err
+	}
+
+	r, err := u.Build(ctx, name, at)
+	if err != nil {
+		return err
+	}
+
+	msg := &mail.Message{
+		To:      schedule.To,
+		Subject: "Report " + name + " " + period(r),
+	}
+	for _, format := range schedule.Formats {
+		data, contentType, err := u.Render(r, format)
+		if err != nil {
+			return fmt.Errorf("render %s: %w", format, err)
+		}
+		msg.Attachments = append(msg.Attachments, mail.Attachment{
+			Name:        fileName(r, format),
+			ContentType: contentType,
+			Data:        data,
+		})
+		if format == model.ReportHTML {
+			msg.HTML = string(data)
+		}
+	}
+	msg.Text = "The " + name + " report for " + period(r) + " is attached."
+
+	// Both targets are attempted, a failing mail server should not also
+	// cost the archived copy
+	var errs []error
+	if schedule.Directory != "" {
+		if err := save(schedule.Directory, msg.Attachments); err != nil {
+			errs = append(errs, fmt.Errorf("save: %w", err))
+		}
+	}
+	if len(schedule.To) > 0 {
+		if u.sender == nil {
+			errs = append(errs, errors.New("send: smtp is not configured"))
+		} else if err := u.sender.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("send: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (u *usecase) DeliverScheduled(ctx context.Context, name string, at time.Time) error {
+	// Every instance runs the schedules, the first to claim a run sends it
+	run, err := u.runs.Claim(ctx, name, at, runLease)
+	if err != nil {
+		if errors.Is(err, reportsRepo.ErrAlreadyClaimed) {
+			return nil
+		}
+		return fmt.Errorf("claim run: %w", err)
+	}
+
+	return u.deliverRun(ctx, run)
+}
+
+func (u *usecase) RetryRuns(ctx context.Context) error {
+	runs, err := u.runs.ClaimDue(ctx, retryBatch, runLease)
+	if err != nil {
+		return fmt.Errorf("claim runs: %w", err)
+	}
+
+	var errs []error
+	for _, run := range runs {
+		if err := u.deliverRun(ctx, run); err != nil {
+			errs = append(errs, fmt.Errorf("%s due %s: %w", run.Name, run.DueAt.Format(time.RFC3339), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deliverRun delivers a claimed run and records the outcome. A failed run
+// is tried again later until maxRunAttempts, a report no longer configured
+// is given up at once.
+func (u *usecase) deliverRun(ctx context.Context, run *model.ReportRun) error {
+	err := u.Deliver(ctx, run.Name, run.DueAt)
+	if err == nil {
+		if err := u.runs.MarkSent(ctx, run); err != nil {
+			return fmt.Errorf("mark sent: %w", err)
+		}
+		return nil
+	}
+
+	if run.Attempts >= maxRunAttempts || errors.Is(err, reports.ErrNotFoundReport) {
+		if finishErr := u.runs.Finish(ctx, run, err.Error()); finishErr != nil {
+			return errors.Join(err, fmt.Errorf("finish: %w", finishErr))
+		}
+		return fmt.Errorf("giving up after %d attempts: %w", run.Attempts, err)
+	}
+
+	delay := retryDelay(run.Attempts)
+	if retryErr := u.runs.Retry(ctx, run, time.Now().Add(delay), err.Error()); retryErr != nil {
+		return errors.Join(err, fmt.Errorf("retry: %w", retryErr))
+	}
+	return fmt.Errorf("retrying in %s: %w", delay, err)
+}
+
+// retryDelay doubles the backoff with every attempt after the first
+func retryDelay(attempts int) time.Duration {
+	delay := runRetryBackoff
+	for i := 1; i < attempts && delay < maxRunBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRunBackoff)
+}
+
+func period(r *model.Report) string {
+	last := r.To.AddDate(0, 0, -1)
+	if r.From.Equal(last) {
+		return r.From.Format(time.DateOnly)
+	}
+	return r.From.Format(time.DateOnly) + " to " + last.Format(time.DateOnly)
+}
+
+// fileName names report files after the report and the first day of the
+// period, so every run of a schedule gets its own file
+func fileName(r *model.Report, format model.ReportFormat) string {
+	return r.Name + "-" + r.From.Format(time.DateOnly) + "." + string(format)
+}
+
+// save writes the files through temporary files, so a directory watched
+// by another program never shows a partial report
+func save(dir string, files []mail.Attachment) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		tmp, err := os.CreateTemp(dir, "."+f.Name+".*")
+		if err != nil {
+			return err
+		}
+		_, err = tmp.Write(f.Data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), 0o644)
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), filepath.Join(dir, f.Name))
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package std
+
+import (
+	"backend_crm/internal/mail"
+	"backend_crm/internal/model"
+	analyticsRepo "backend_crm/internal/repository/analytics"
+	reportsRepo "backend_crm/internal/repository/reports"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// emptyAnalytics reports a period without orders
+type emptyAnalytics struct {
+	analyticsRepo.Repository
+}
+
+func (emptyAnalytics) OrdersByStatus(context.Context, model.AnalyticsRange) ([]model.StatusCount, error) {
+	return nil, nil
+}
+
+func (emptyAnalytics) Completed(context.Context, model.AnalyticsRange) (int, float64, error) {
+	return 0, 0, nil
+}
+
+func (emptyAnalytics) Overdue(context.Context, time.Time, int) ([]*model.OverdueOrder, int, error) {
+	return nil, 0, nil
+}
+
+type failingSender struct {
+	err error
+}
+
+func (s failingSender) Send(context.Context, *mail.Message) error {
+	return s.err
+}
+
+// fakeRuns records the outcome of the runs
+type fakeRuns struct {
+	claimed bool
+	due     []*model.ReportRun
+
+	status    model.DeliveryStatus
+	retryAt   time.Time
+	lastError string
+}
+
+func (r *fakeRuns) Claim(_ context.Context, name string, at time.Time, _ time.Duration) (*model.ReportRun, error) {
+	if r.claimed {
+		return nil, reportsRepo.ErrAlreadyClaimed
+	}
+	r.claimed = true
+	return &model.ReportRun{Name: name, DueAt: at, Attempts: 1}, nil
+}
+
+func (r *fakeRuns) ClaimDue(context.Context, int, time.Duration) ([]*model.ReportRun, error) {
+	return r.due, nil
+}
+
+func (r *fakeRuns) MarkSent(context.Context, *model.ReportRun) error {
+	r.status = model.DeliverySent
+	return nil
+}
+
+func (r *fakeRuns) Retry(_ context.Context, _ *model.ReportRun, at time.Time, lastError string) error {
+	r.status, r.retryAt, r.lastError = model.DeliveryPending, at, lastError
+	return nil
+}
+
+func (r *fakeRuns) Finish(_ context.Context, _ *model.ReportRun, lastError string) error {
+	r.status, r.lastError = model.DeliveryFailed, lastError
+	return nil
+}
+
+func TestDeliverRun(t *testing.T) {
+	smtpDown := errors.New("smtp down")
+	schedules := []model.ReportSchedule{{
+		Name:    "weekly",
+		Period:  model.GroupByWeek,
+		Formats: []model.ReportFormat{model.ReportHTML},
+		To:      []string{"management@example.com"},
+	}}
+	due := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		run        *model.ReportRun
+		sendErr    error
+		wantStatus model.DeliveryStatus
+		// wantDelay is the delay of the retry, 0 for none
+		wantDelay time.Duration
+	}{
+		{"sent", &model.ReportRun{Name: "weekly", DueAt: due, Attempts: 1}, nil, model.DeliverySent, 0},
+		{"first failure", &model.ReportRun{Name: "weekly", DueAt: due, Attempts: 1}, smtpDown, model.DeliveryPending, runRetryBackoff},
+		{"third failure", &model.ReportRun{Name: "weekly", DueAt: due, Attempts: 3}, smtpDown, model.DeliveryPending, 4 * runRetryBackoff},
+		{"last attempt", &model.ReportRun{Name: "weekly", DueAt: due, Attempts: maxRunAttempts}, smtpDown, model.DeliveryFailed, 0},
+		{"report removed", &model.ReportRun{Name: "daily", DueAt: due, Attempts: 1}, nil, model.DeliveryFailed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := &fakeRuns{}
+			u := NewUsecase(emptyAnalytics{}, runs, failingSender{tt.sendErr}, schedules, time.UTC, time.Hour).(*usecase)
+
+			start := time.Now()
+			err := u.deliverRun(context.Background(), tt.run)
+			if (err == nil) != (tt.wantStatus == model.DeliverySent) {
+				t.Errorf("deliverRun() = %v", err)
+			}
+			if runs.status != tt.wantStatus {
+				t.Errorf("status %q, want %q", runs.status, tt.wantStatus)
+			}
+			if tt.wantStatus != model.DeliverySent && runs.lastError == "" {
+				t.Error("last error not recorded")
+			}
+			if tt.wantDelay != 0 {
+				if delay := runs.retryAt.Sub(start); delay < tt.wantDelay || delay > tt.wantDelay+time.Minute {
+					t.Errorf("retry in %v, want %v", delay, tt.wantDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverScheduledOnce(t *testing.T) {
+	runs := &fakeRuns{}
+	schedules := []model.ReportSchedule{{Name: "weekly", Period: model.GroupByWeek, Formats: []model.ReportFormat{model.ReportHTML}, To: []string{"a@example.com"}}}
+	u := NewUsecase(emptyAnalytics{}, runs, failingSender{}, schedules, time.UTC, time.Hour)
+
+	due := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	if err := u.DeliverScheduled(context.Background(), "weekly", due); err != nil {
+		t.Fatalf("first instance: %v", err)
+	}
+	runs.status = ""
+	if err := u.DeliverScheduled(context.Background(), "weekly", due); err != nil {
+		t.Fatalf("second instance: %v", err)
+	}
+	if runs.status != "" {
+		t.Errorf("second instance delivered the run again")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{4, 40 * time.Minute},
+		{7, 320 * time.Minute},
+		{8, maxRunBackoff},
+		{100, maxRunBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ var (
 	ErrIncorrectPassword   = errors.New("incorrect password")
 	ErrExpiredAccessToken  = errors.New("expired access token")
 	ErrExpiredRefreshToken = errors.New("expired refresh token")
+	ErrEmptySecrets        = errors.New("empty secrets")
 )
 
 type Usecase interface {
@@ -18,4 +19,10 @@ type Usecase interface {
 	RefreshTokens(ctx context.Context, refreshToken string) (*model.Token, error)
 	Login(ctx context.Context, login *model.Login) (*model.Token, error)
 	Register(ctx context.Context, register *model.Register) error
+	// SetSecrets replaces the JWT secrets. The first secret of each set signs
+	// new tokens, all of them are accepted when verifying. The secrets must
+	// have passed CheckSecrets.
+	SetSecrets(accessSecrets [][]byte, refreshSecrets [][]byte)
+	// CheckSecrets reports whether the secrets can sign tokens
+	CheckSecrets(accessSecrets [][]byte, refreshSecrets [][]byte) error
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -21,8 +22,9 @@ type usecase struct {
 
 	passHasher hash.Hash
 
-	accessSecret  []byte
-	refreshSecret []byte
+	mu             sync.RWMutex
+	accessSecrets  [][]byte
+	refreshSecrets [][]byte
 
 	accessExpired  time.Duration
 	refreshExpired time.Duration
@@ -30,20 +32,55 @@ type usecase struct {
 
 func NewUsecase(
 	users usersRepo.Repository,
-	accessSecret []byte,
-	refreshSecret []byte,
+	accessSecrets [][]byte,
+	refreshSecrets [][]byte,
 	accessTTL time.Duration,
 	refreshTTL time.Duration,
 ) users.Usecase {
 	return &usecase{
 		users:          users,
-		accessSecret:   accessSecret,
-		refreshSecret:  refreshSecret,
+		accessSecrets:  accessSecrets,
+		refreshSecrets: refreshSecrets,
 		accessExpired:  accessTTL,
 		refreshExpired: refreshTTL,
 	}
 }
 
+func (u *usecase) SetSecrets(accessSecrets [][]byte, refreshSecrets [][]byte) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.accessSecrets = accessSecrets
+	u.refreshSecrets = refreshSecrets
+}
+
+func (u *usecase) CheckSecrets(accessSecrets [][]byte, refreshSecrets [][]byte) error {
+	if len(accessSecrets) == 0 || len(accessSecrets[0]) == 0 ||
+		len(refreshSecrets) == 0 || len(refreshSecrets[0]) == 0 {
+		return users.ErrEmptySecrets
+	}
+	return nil
+}
+
+func (u *usecase) signingSecret(secrets *[][]byte) []byte {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return (*secrets)[0]
+}
+
+func (u *usecase) verificationKeys(secrets *[][]byte) jwt.VerificationKeySet {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	keys := make([]jwt.VerificationKey, 0, len(*secrets))
+	for _, s := range *secrets {
+		keys = append(keys, s)
+	}
+
+	return jwt.VerificationKeySet{Keys: keys}
+}
+
 func (u *usecase) Register(ctx context.Context, register *model.Register) error {
 	b, err := bcrypt.GenerateFromPassword([]byte(register.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -112,7 +149,7 @@ func (u *usecase) generateAccess(userId string, userRole model.Role) (string, er
 	}
 	access := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaim)
 
-	accessToken, err := access.SignedString(u.accessSecret)
+	accessToken, err := access.SignedString(u.signingSecret(&u.accessSecrets))
 	if err != nil {
 		return "", fmt.Errorf("signed string: %w", err)
 	}
@@ -132,7 +169,7 @@ func (u *usecase) generateRefresh(userId string, userRole model.Role) (string, e
 	}
 	refresh := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaim)
 
-	refreshToken, err := refresh.SignedString(u.refreshSecret)
+	refreshToken, err := refresh.SignedString(u.signingSecret(&u.refreshSecrets))
 	if err != nil {
 		return "", fmt.Errorf("signed string: %w", err)
 	}
@@ -149,7 +186,7 @@ func (u *usecase) CheckAccess(ctx context.Context, accessToken string) (string,
 			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 			}
-			return u.accessSecret, nil
+			return u.verificationKeys(&u.accessSecrets), nil
 		})
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -174,7 +211,7 @@ func (u *usecase) RefreshTokens(ctx context.Context, refreshToken string) (*mode
 			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 			}
-			return u.refreshSecret, nil
+			return u.verificationKeys(&u.refreshSecrets), nil
 		})
 
 	if err != nil {
@@ -0,0 +1,110 @@
+// Package webhook builds and signs the requests sent to webhooks and posts
+// them.
+//
+// Every request carries the headers
+//
+//	X-Webhook-Event: order.status_changed
+//	X-Webhook-Id: <event id, the same for every delivery of the event>
+//	X-Webhook-Delivery: <delivery id>
+//	X-Webhook-Signature: t=<unix time>,v1=<hex HMAC-SHA256>
+//
+// The signature is computed with the webhook secret over the time, a dot
+// and the raw body. Receivers should compare it in constant time and
+// reject old timestamps to prevent replays.
+package webhook
+
+import (
+	"backend_crm/internal/model"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Payload is the JSON body of a webhook request
+type Payload struct {
+	Id         string          `json:"id"`
+	Event      string          `json:"event"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+	// Order is the order as it is when the request is sent
+	Order Order `json:"order"`
+}
+
+type Order struct {
+	OrderId     string         `json:"orderId"`
+	CustomerId  string         `json:"customerId"`
+	UserId      string         `json:"userId,omitempty"`
+	Phone       string         `json:"phone"`
+	Email       string         `json:"email"`
+	Description string         `json:"description"`
+	Status      int            `json:"status"`
+	Items       []Item         `json:"items"`
+	Currency    string         `json:"currency"`
+	Subtotal    int64          `json:"subtotal"`
+	Discount    int64          `json:"discount"`
+	Tax         int64          `json:"tax"`
+	Total       int64          `json:"total"`
+	Tags        []string       `json:"tags"`
+	Fields      map[string]any `json:"fields"`
+	CreatedAt   time.Time      `json:"createdAt"`
+}
+
+// Item amounts are in minor units of the order currency
+type Item struct {
+	ProductId  string  `json:"productId"`
+	Name       string  `json:"name"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  int64   `json:"unitPrice"`
+	UnitWeight float32 `json:"unitWeight"`
+	Total      int64   `json:"total"`
+}
+
+// Body encodes the payload of a delivery with the current order
+func Body(delivery *model.WebhookDelivery, order *model.Order) ([]byte, error) {
+	payload := Payload{
+		Id:         delivery.EventId,
+		Event:      string(delivery.Event),
+		OccurredAt: delivery.CreatedAt,
+		Data:       delivery.Data,
+		Order: Order{
+			OrderId:     order.OrderId,
+			CustomerId:  order.CustomerId,
+			UserId:      order.UserId,
+			Phone:       order.Phone,
+			Email:       order.Email,
+			Description: order.Description,
+			Status:      int(order.Status),
+			Items:       make([]Item, 0, len(order.Items)),
+			Currency:    order.Currency,
+			Subtotal:    order.Subtotal().Amount,
+			Discount:    order.Discount().Amount,
+			Tax:         order.Tax().Amount,
+			Total:       order.Total().Amount,
+			Tags:        order.Tags,
+			Fields:      order.Fields,
+			CreatedAt:   order.CreatedAt,
+		},
+	}
+	if payload.Order.Tags == nil {
+		payload.Order.Tags = []string{}
+	}
+	if payload.Order.Fields == nil {
+		payload.Order.Fields = map[string]any{}
+	}
+	for _, item := range order.Items {
+		payload.Order.Items = append(payload.Order.Items, Item{
+			ProductId:  item.Product.ProductId,
+			Name:       item.Product.Name,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.UnitPrice,
+			UnitWeight: item.UnitWeight,
+			Total:      item.Total(),
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"backend_crm/internal/model"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxResponseBody is the part of a response body kept in the delivery log
+const maxResponseBody = 1024
+
+// Request is one attempt to deliver an event to a webhook
+type Request struct {
+	URL        string
+	Secret     string
+	Event      model.WebhookEvent
+	EventId    string
+	DeliveryId string
+	Body       []byte
+}
+
+// Sender posts webhook requests
+type Sender interface {
+	// Send returns the response whatever its status. The error is only set
+	// when there is no response, e.g. the receiver could not be reached.
+	Send(ctx context.Context, req *Request) (*model.WebhookResponse, error)
+}
+
+type sender struct {
+	client *http.Client
+}
+
+// NewSender returns a sender giving up on a request after timeout. Redirects
+// are not followed, they count as failures.
+func NewSender(timeout time.Duration) Sender {
+	return &sender{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Sign returns the X-Webhook-Signature value of body sent at the time
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *sender) Send(ctx context.Context, req *Request) (*model.WebhookResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "backend_crm-webhooks")
+	httpReq.Header.Set("X-Webhook-Event", string(req.Event))
+	httpReq.Header.Set("X-Webhook-Id", req.EventId)
+	httpReq.Header.Set("X-Webhook-Delivery", req.DeliveryId)
+	httpReq.Header.Set("X-Webhook-Signature", Sign(req.Secret, time.Now(), req.Body))
+
+	start := time.Now()
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	duration := time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	// Let the connection be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return &model.WebhookResponse{
+		Status: resp.StatusCode,
+		// Postgres text takes neither invalid UTF-8 nor NUL bytes
+		Body:     string(bytes.ReplaceAll(bytes.ToValidUTF8(body, nil), []byte{0}, nil)),
+		Duration: duration,
+	}, nil
+}
@@ -0,0 +1,282 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455) on hijacked fasthttp connections.
+//
+// Only what browsers need is supported: text and binary messages,
+// fragmentation, ping, pong and close. Extensions such as compression are
+// not negotiated.
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Opcodes of the frames
+const (
+	opContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close codes
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseUnsupported   = 1003
+	CloseInvalidData   = 1007
+	ClosePolicy        = 1008
+	CloseTooBig        = 1009
+	CloseInternalError = 1011
+)
+
+// maxControlPayload is the limit of ping, pong and close payloads
+const maxControlPayload = 125
+
+var (
+	// ErrClosed is returned by ReadMessage after the peer closed the
+	// connection with a close frame
+	ErrClosed   = errors.New("websocket: connection closed")
+	ErrTooBig   = errors.New("websocket: message too big")
+	errProtocol = errors.New("websocket: protocol error")
+)
+
+// Conn is an upgraded connection. ReadMessage must be called from one
+// goroutine, the write methods are safe for concurrent use.
+type Conn struct {
+	conn         net.Conn
+	r            *bufio.Reader
+	readLimit    int
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newConn(conn net.Conn, options Options) *Conn {
+	return &Conn{
+		conn:         conn,
+		r:            bufio.NewReader(conn),
+		readLimit:    options.ReadLimit,
+		readTimeout:  options.ReadTimeout,
+		writeTimeout: options.WriteTimeout,
+	}
+}
+
+// SetReadTimeout replaces the longest wait for a frame, e.g. once a client
+// authenticated. It must not be called while ReadMessage runs.
+func (c *Conn) SetReadTimeout(timeout time.Duration) {
+	c.readTimeout = timeout
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// while waiting. A close frame from the peer is answered and reported as
+// ErrClosed; on any error the connection is closed.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		opcode  int
+		message []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			c.fail(err)
+			return 0, nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.write(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.closeReply(payload)
+			return 0, nil, ErrClosed
+		case opContinuation:
+			if opcode == 0 {
+				c.fail(errProtocol)
+				return 0, nil, errProtocol
+			}
+		case OpText, OpBinary:
+			if opcode != 0 {
+				c.fail(errProtocol)
+				return 0, nil, errProtocol
+			}
+			opcode = op
+		default:
+			c.fail(errProtocol)
+			return 0, nil, errProtocol
+		}
+
+		if len(message)+len(payload) > c.readLimit {
+			c.Close(CloseTooBig, "")
+			return 0, nil, ErrTooBig
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+
+		if opcode == OpText && !utf8.Valid(message) {
+			c.Close(CloseInvalidData, "")
+			return 0, nil, fmt.Errorf("websocket: invalid utf-8 in text message")
+		}
+		return opcode, message, nil
+	}
+}
+
+// WriteMessage sends a text or binary message in a single frame
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.write(opcode, data)
+}
+
+// Ping sends a ping. The pong is read by ReadMessage and keeps a quiet
+// connection within the read timeout.
+func (c *Conn) Ping() error {
+	return c.write(opPing, nil)
+}
+
+// Close sends a close frame with the code and reason and closes the
+// connection without waiting for the answer
+func (c *Conn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+
+	err := c.write(opClose, payload)
+	c.closeConn()
+	return err
+}
+
+// closeReply answers a close frame with its code and closes the connection
+func (c *Conn) closeReply(payload []byte) {
+	if len(payload) >= 2 {
+		payload = payload[:2]
+	}
+	c.write(opClose, payload)
+	c.closeConn()
+}
+
+// fail closes the connection after a read error, telling the peer about
+// protocol errors
+func (c *Conn) fail(err error) {
+	if errors.Is(err, errProtocol) {
+		c.Close(CloseProtocolError, "")
+		return
+	}
+	c.closeConn()
+}
+
+func (c *Conn) closeConn() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		c.conn.Close()
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+
+	var header [2]byte
+	if _, err = io.ReadFull(c.r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	// No extension is negotiated, so the reserved bits stay unset. Clients
+	// must mask their frames.
+	if header[0]&0x70 != 0 || !masked {
+		return false, 0, nil, errProtocol
+	}
+	if opcode >= opClose && (!fin || length > maxControlPayload) {
+		return false, 0, nil, errProtocol
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > uint64(c.readLimit) {
+		c.Close(CloseTooBig, "")
+		return false, 0, nil, ErrTooBig
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *Conn) write(opcode int, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|byte(opcode))
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return net.ErrClosed
+	}
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,406 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeConn reads the frames of a client from in and collects what the
+// server writes in out
+type fakeConn struct {
+	in     *bytes.Reader
+	out    bytes.Buffer
+	closed bool
+}
+
+func (c *fakeConn) Read(p []byte) (int, error)       { return c.in.Read(p) }
+func (c *fakeConn) Write(p []byte) (int, error)      { return c.out.Write(p) }
+func (c *fakeConn) Close() error                     { c.closed = true; return nil }
+func (c *fakeConn) LocalAddr() net.Addr              { return nil }
+func (c *fakeConn) RemoteAddr() net.Addr             { return nil }
+func (c *fakeConn) SetDeadline(time.Time) error      { return nil }
+func (c *fakeConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+
+// frame builds a frame as a client sends it, masked unless unmasked is set
+type frame struct {
+	op       byte
+	payload  []byte
+	more     bool // fin unset
+	unmasked bool
+	rsv      byte
+}
+
+func (f frame) bytes() []byte {
+	first := f.op | f.rsv<<4
+	if !f.more {
+		first |= 0x80
+	}
+	b := []byte{first}
+
+	mask := byte(0x80)
+	if f.unmasked {
+		mask = 0
+	}
+	switch n := len(f.payload); {
+	case n < 126:
+		b = append(b, mask|byte(n))
+	case n <= 0xFFFF:
+		b = append(b, mask|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, mask|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+
+	if f.unmasked {
+		return append(b, f.payload...)
+	}
+	key := [4]byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, key[:]...)
+	for i, v := range f.payload {
+		b = append(b, v^key[i%4])
+	}
+	return b
+}
+
+func frames(fs ...frame) []byte {
+	var b []byte
+	for _, f := range fs {
+		b = append(b, f.bytes()...)
+	}
+	return b
+}
+
+// serverFrame is a frame written by the server, which never masks
+type serverFrame struct {
+	op      int
+	payload []byte
+}
+
+func readServerFrames(t *testing.T, data []byte) []serverFrame {
+	t.Helper()
+
+	var result []serverFrame
+	for len(data) > 0 {
+		if len(data) < 2 || data[0]&0x80 == 0 || data[1]&0x80 != 0 {
+			t.Fatalf("malformed server frame % x", data)
+		}
+		op := int(data[0] & 0x0F)
+		n, header := int(data[1]&0x7F), 2
+		switch n {
+		case 126:
+			n, header = int(binary.BigEndian.Uint16(data[2:])), 4
+		case 127:
+			n, header = int(binary.BigEndian.Uint64(data[2:])), 10
+		}
+		result = append(result, serverFrame{op: op, payload: data[header : header+n]})
+		data = data[header+n:]
+	}
+	return result
+}
+
+func closeCode(frames []serverFrame) int {
+	for _, f := range frames {
+		if f.op == opClose && len(f.payload) >= 2 {
+			return int(binary.BigEndian.Uint16(f.payload))
+		}
+	}
+	return 0
+}
+
+func TestReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 300)
+	huge := bytes.Repeat([]byte("b"), 70000)
+	closePayload := binary.BigEndian.AppendUint16(nil, CloseGoingAway)
+
+	tests := []struct {
+		name      string
+		readLimit int
+		input     []byte
+		wantOp    int
+		wantData  []byte
+		wantErr   error
+		// wantClose is the code of the close frame the server sends, 0
+		// for none
+		wantClose int
+		wantPong  []byte
+	}{
+		{
+			name:     "text",
+			input:    frames(frame{op: OpText, payload: []byte("hello")}),
+			wantOp:   OpText,
+			wantData: []byte("hello"),
+		},
+		{
+			name:     "binary",
+			input:    frames(frame{op: OpBinary, payload: []byte{0, 0xFF, 0x80}}),
+			wantOp:   OpBinary,
+			wantData: []byte{0, 0xFF, 0x80},
+		},
+		{
+			name:     "empty",
+			input:    frames(frame{op: OpText}),
+			wantOp:   OpText,
+			wantData: []byte{},
+		},
+		{
+			name:     "16 bit length",
+			input:    frames(frame{op: OpBinary, payload: long}),
+			wantOp:   OpBinary,
+			wantData: long,
+		},
+		{
+			name:      "64 bit length",
+			readLimit: 100000,
+			input:     frames(frame{op: OpBinary, payload: huge}),
+			wantOp:    OpBinary,
+			wantData:  huge,
+		},
+		{
+			name: "fragments",
+			input: frames(
+				frame{op: OpText, payload: []byte("hel"), more: true},
+				frame{op: opContinuation, payload: []byte("l"), more: true},
+				frame{op: opContinuation, payload: []byte("o")},
+			),
+			wantOp:   OpText,
+			wantData: []byte("hello"),
+		},
+		{
+			name: "ping between fragments",
+			input: frames(
+				frame{op: OpText, payload: []byte("hel"), more: true},
+				frame{op: opPing, payload: []byte("are you there")},
+				frame{op: opContinuation, payload: []byte("lo")},
+			),
+			wantOp:   OpText,
+			wantData: []byte("hello"),
+			wantPong: []byte("are you there"),
+		},
+		{
+			name: "pong is skipped",
+			input: frames(
+				frame{op: opPong, payload: []byte("x")},
+				frame{op: OpText, payload: []byte("hello")},
+			),
+			wantOp:   OpText,
+			wantData: []byte("hello"),
+		},
+		{
+			name:     "utf-8 split across fragments",
+			input:    frames(frame{op: OpText, payload: []byte("\xd0"), more: true}, frame{op: opContinuation, payload: []byte("\x96")}),
+			wantOp:   OpText,
+			wantData: []byte("Ж"),
+		},
+		{
+			name:      "close",
+			input:     frames(frame{op: opClose, payload: append(closePayload, "bye"...)}),
+			wantErr:   ErrClosed,
+			wantClose: CloseGoingAway,
+		},
+		{
+			name:      "unmasked",
+			input:     frames(frame{op: OpText, payload: []byte("hello"), unmasked: true}),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "reserved bit",
+			input:     frames(frame{op: OpText, payload: []byte("hello"), rsv: 4}),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "unknown opcode",
+			input:     frames(frame{op: 0x3, payload: []byte("hello")}),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "continuation without start",
+			input:     frames(frame{op: opContinuation, payload: []byte("hello")}),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "new message inside fragments",
+			input: frames(
+				frame{op: OpText, payload: []byte("hel"), more: true},
+				frame{op: OpText, payload: []byte("lo")},
+			),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "fragmented ping",
+			input:     frames(frame{op: opPing, payload: []byte("x"), more: true}),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "control payload too long",
+			input:     frames(frame{op: opPing, payload: bytes.Repeat([]byte("x"), maxControlPayload+1)}),
+			wantErr:   errProtocol,
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "frame over the limit",
+			readLimit: 100,
+			input:     frames(frame{op: OpBinary, payload: long}),
+			wantErr:   ErrTooBig,
+			wantClose: CloseTooBig,
+		},
+		{
+			// Refused from the header alone, nothing is allocated for it
+			name:      "declared length over the limit",
+			readLimit: 100,
+			input:     []byte{0x82, 0x80 | 127, 0, 0, 1, 0, 0, 0, 0, 0, 0x12, 0x34, 0x56, 0x78},
+			wantErr:   ErrTooBig,
+			wantClose: CloseTooBig,
+		},
+		{
+			name:      "fragments over the limit",
+			readLimit: 5,
+			input: frames(
+				frame{op: OpText, payload: []byte("hel"), more: true},
+				frame{op: opContinuation, payload: []byte("lo!")},
+			),
+			wantErr:   ErrTooBig,
+			wantClose: CloseTooBig,
+		},
+		{
+			name:      "invalid utf-8",
+			input:     frames(frame{op: OpText, payload: []byte("\xff\xfe")}),
+			wantClose: CloseInvalidData,
+		},
+		{
+			name:    "truncated payload",
+			input:   frames(frame{op: OpText, payload: []byte("hello")})[:8],
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "no frame",
+			input:   nil,
+			wantErr: io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.readLimit
+			if limit == 0 {
+				limit = 1024
+			}
+			fake := &fakeConn{in: bytes.NewReader(tt.input)}
+			conn := newConn(fake, Options{ReadLimit: limit})
+
+			op, data, err := conn.ReadMessage()
+			written := readServerFrames(t, fake.out.Bytes())
+
+			wantFailure := tt.wantErr != nil || tt.wantClose != 0
+			switch {
+			case wantFailure && err == nil:
+				t.Fatalf("ReadMessage succeeded, want an error")
+			case !wantFailure && err != nil:
+				t.Fatalf("ReadMessage: %v", err)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("ReadMessage error = %v, want %v", err, tt.wantErr)
+			}
+			if wantFailure && !fake.closed {
+				t.Errorf("connection left open after %v", err)
+			}
+
+			if !wantFailure {
+				if op != tt.wantOp {
+					t.Errorf("opcode = %d, want %d", op, tt.wantOp)
+				}
+				if !bytes.Equal(data, tt.wantData) {
+					t.Errorf("data = %q, want %q", shorten(data), shorten(tt.wantData))
+				}
+			}
+
+			if got := closeCode(written); got != tt.wantClose {
+				t.Errorf("close code = %d, want %d", got, tt.wantClose)
+			}
+
+			var pong []byte
+			for _, f := range written {
+				if f.op == opPong {
+					pong = f.payload
+				}
+			}
+			if !bytes.Equal(pong, tt.wantPong) {
+				t.Errorf("pong = %q, want %q", pong, tt.wantPong)
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		wantHeader []byte
+	}{
+		{"7 bit length", 125, []byte{0x81, 125}},
+		{"16 bit length", 126, []byte{0x81, 126, 0, 126}},
+		{"largest 16 bit length", 0xFFFF, []byte{0x81, 126, 0xFF, 0xFF}},
+		{"64 bit length", 0x10000, []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeConn{in: bytes.NewReader(nil)}
+			conn := newConn(fake, Options{})
+
+			payload := []byte(strings.Repeat("x", tt.size))
+			if err := conn.WriteMessage(OpText, payload); err != nil {
+				t.Fatalf("WriteMessage: %v", err)
+			}
+
+			out := fake.out.Bytes()
+			if !bytes.HasPrefix(out, tt.wantHeader) {
+				t.Fatalf("header = % x, want % x", out[:len(tt.wantHeader)], tt.wantHeader)
+			}
+			if !bytes.Equal(out[len(tt.wantHeader):], payload) {
+				t.Errorf("payload differs from the message")
+			}
+		})
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	fake := &fakeConn{in: bytes.NewReader(nil)}
+	conn := newConn(fake, Options{})
+
+	conn.Close(CloseNormal, strings.Repeat("r", 200))
+	written := readServerFrames(t, fake.out.Bytes())
+	if len(written) != 1 || len(written[0].payload) != maxControlPayload {
+		t.Fatalf("close frame not cut to %d bytes: %v", maxControlPayload, written)
+	}
+
+	if err := conn.WriteMessage(OpText, []byte("late")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("WriteMessage after Close = %v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	// The example of RFC 6455, section 1.3
+	if got := accept([]byte("dGhlIHNhbXBsZSBub25jZQ==")); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("accept = %q", got)
+	}
+}
+
+func shorten(b []byte) []byte {
+	if len(b) > 20 {
+		return b[:20]
+	}
+	return b
+}
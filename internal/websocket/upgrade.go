@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// acceptGUID is appended to the key of the client to compute the accept
+// header
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Options of an upgraded connection
+type Options struct {
+	// ReadLimit is the largest message accepted, larger ones close the
+	// connection
+	ReadLimit int
+	// ReadTimeout is the longest wait for a frame. Ping more often to
+	// keep connections without messages open.
+	ReadTimeout time.Duration
+	// WriteTimeout limits every write
+	WriteTimeout time.Duration
+}
+
+// Upgrade answers a WebSocket handshake and hands the connection to handler
+// once the response was sent. The handler runs in its own goroutine, the
+// connection is closed when it returns. The request context must not be
+// used by the handler; copy what it needs before calling Upgrade.
+//
+// An invalid handshake is answered with an error and false is returned.
+func Upgrade(ctx *fasthttp.RequestCtx, options Options, handler func(conn *Conn)) bool {
+	if !ctx.IsGet() {
+		ctx.Error("Only GET method allowed", fasthttp.StatusMethodNotAllowed)
+		return false
+	}
+
+	if !headerContains(ctx.Request.Header.Peek(fasthttp.HeaderConnection), "upgrade") ||
+		!headerContains(ctx.Request.Header.Peek(fasthttp.HeaderUpgrade), "websocket") {
+		ctx.Response.Header.Set(fasthttp.HeaderUpgrade, "websocket")
+		ctx.Error("WebSocket upgrade required", fasthttp.StatusUpgradeRequired)
+		return false
+	}
+
+	if string(ctx.Request.Header.Peek(fasthttp.HeaderSecWebSocketVersion)) != "13" {
+		ctx.Response.Header.Set(fasthttp.HeaderSecWebSocketVersion, "13")
+		ctx.Error("Unsupported WebSocket version", fasthttp.StatusUpgradeRequired)
+		return false
+	}
+
+	key := ctx.Request.Header.Peek(fasthttp.HeaderSecWebSocketKey)
+	if decoded, err := base64.StdEncoding.DecodeString(string(key)); err != nil || len(decoded) != 16 {
+		ctx.Error("Invalid Sec-WebSocket-Key", fasthttp.StatusBadRequest)
+		return false
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusSwitchingProtocols)
+	ctx.Response.Header.Set(fasthttp.HeaderUpgrade, "websocket")
+	ctx.Response.Header.Set(fasthttp.HeaderConnection, "Upgrade")
+	ctx.Response.Header.Set(fasthttp.HeaderSecWebSocketAccept, accept(key))
+
+	ctx.Hijack(func(c net.Conn) {
+		// Clear the deadlines of the HTTP request
+		c.SetDeadline(time.Time{})
+		handler(newConn(c, options))
+	})
+	return true
+}
+
+func accept(key []byte) string {
+	hash := sha1.New()
+	hash.Write(key)
+	hash.Write([]byte(acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// headerContains reports whether the comma separated header has the token,
+// ignoring case
+func headerContains(header []byte, token string) bool {
+	for _, value := range bytes.Split(header, []byte(",")) {
+		if bytes.EqualFold(bytes.TrimSpace(value), []byte(token)) {
+			return true
+		}
+	}
+	return false
+}
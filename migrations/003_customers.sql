@@ -0,0 +1,59 @@
+-- Create customers table
+CREATE TABLE IF NOT EXISTS customers (
+    customer_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    phones TEXT[] NOT NULL DEFAULT '{}', -- normalized: digits only
+    emails TEXT[] NOT NULL DEFAULT '{}', -- normalized: trimmed, lower case
+    notes TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id UUID REFERENCES customers(customer_id);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_customers_phones ON customers USING GIN (phones);
+CREATE INDEX IF NOT EXISTS idx_customers_emails ON customers USING GIN (emails);
+CREATE INDEX IF NOT EXISTS idx_customers_tags ON customers USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
+
+-- Link existing orders to customers, matching on normalized phone or email
+DO $$
+DECLARE
+    o RECORD;
+    norm_phone TEXT;
+    norm_email TEXT;
+    found_id UUID;
+BEGIN
+    FOR o IN SELECT order_id, phone, email FROM orders WHERE customer_id IS NULL ORDER BY created_at LOOP
+        norm_phone := regexp_replace(o.phone, '\D', '', 'g');
+        IF length(norm_phone) = 11 AND left(norm_phone, 1) = '8' THEN
+            norm_phone := '7' || substr(norm_phone, 2);
+        END IF;
+        norm_email := lower(trim(o.email));
+
+        SELECT customer_id INTO found_id
+        FROM customers
+        WHERE (norm_phone <> '' AND phones @> ARRAY[norm_phone])
+           OR (norm_email <> '' AND emails @> ARRAY[norm_email])
+        ORDER BY created_at
+        LIMIT 1;
+
+        IF found_id IS NULL THEN
+            INSERT INTO customers (phones, emails)
+            VALUES (
+                array_remove(ARRAY[norm_phone], ''),
+                array_remove(ARRAY[norm_email], '')
+            )
+            RETURNING customer_id INTO found_id;
+        ELSE
+            UPDATE customers
+            SET phones = CASE WHEN norm_phone = '' OR phones @> ARRAY[norm_phone] THEN phones ELSE array_append(phones, norm_phone) END,
+                emails = CASE WHEN norm_email = '' OR emails @> ARRAY[norm_email] THEN emails ELSE array_append(emails, norm_email) END
+            WHERE customer_id = found_id;
+        END IF;
+
+        UPDATE orders SET customer_id = found_id WHERE order_id = o.order_id;
+    END LOOP;
+END $$;
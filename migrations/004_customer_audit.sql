@@ -0,0 +1,13 @@
+-- Create customer audit table, keeps merges and splits traceable after the
+-- merged customers are deleted
+CREATE TABLE IF NOT EXISTS customer_audit (
+    audit_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    user_id UUID REFERENCES users(user_id),
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_customer_audit_customer_id ON customer_audit(customer_id);
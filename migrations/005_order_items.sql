@@ -0,0 +1,23 @@
+-- Create order items table. Weight and price are copied from the product when
+-- the order is placed, so later catalog changes do not alter old orders.
+CREATE TABLE IF NOT EXISTS order_items (
+    order_item_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(product_id),
+    position SMALLINT NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_weight DECIMAL(10,2) NOT NULL,
+    unit_price BIGINT NOT NULL DEFAULT 0, -- minor currency units
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
+
+-- Turn existing single-product orders into one-item orders
+INSERT INTO order_items (order_id, product_id, position, quantity, unit_weight)
+SELECT o.order_id, o.product_id, 0, 1, p.weight
+FROM orders o
+JOIN products p ON o.product_id = p.product_id
+WHERE NOT EXISTS (SELECT 1 FROM order_items i WHERE i.order_id = o.order_id);
@@ -0,0 +1,10 @@
+-- Prices are stored as integer minor units (kopecks, cents) to avoid
+-- floating point rounding
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price BIGINT NOT NULL DEFAULT 0 CHECK (price >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- Orders keep the currency, discount and tax rate they were priced with
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0 CHECK (discount_amount >= 0);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_percent SMALLINT NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_rate INTEGER NOT NULL DEFAULT 0 CHECK (tax_rate >= 0); -- basis points, 2000 = 20%
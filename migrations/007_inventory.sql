@@ -0,0 +1,25 @@
+-- Stock on hand and the part of it reserved by orders at work
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0);
+
+DO $$
+BEGIN
+    ALTER TABLE products ADD CONSTRAINT products_reserved_check CHECK (reserved <= stock);
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+-- Create stock movements table
+CREATE TABLE IF NOT EXISTS stock_movements (
+    movement_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(product_id),
+    order_id UUID REFERENCES orders(order_id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(user_id),
+    kind VARCHAR(16) NOT NULL, -- adjust, reserve, release, deduct, return
+    quantity INTEGER NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_order_id ON stock_movements(order_id);
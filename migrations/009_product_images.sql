@@ -0,0 +1,22 @@
+-- Create product images table. The content itself lives in the blob
+-- store, rows only keep the keys and what is needed to serve it.
+CREATE TABLE IF NOT EXISTS product_images (
+    image_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    blob_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    thumbnail_content_type VARCHAR(64) NOT NULL,
+    thumbnail_size BIGINT NOT NULL,
+    thumbnail_sha256 CHAR(64) NOT NULL,
+    uploaded_by UUID REFERENCES users(user_id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images(product_id, position);
@@ -0,0 +1,32 @@
+-- Create order comments table. Comments are internal and never exposed
+-- to customers.
+CREATE TABLE IF NOT EXISTS order_comments (
+    comment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES order_comments(comment_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id),
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Previous versions of edited comments
+CREATE TABLE IF NOT EXISTS order_comment_edits (
+    edit_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    comment_id UUID NOT NULL REFERENCES order_comments(comment_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id),
+    body TEXT NOT NULL,
+    edited_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS order_comment_mentions (
+    comment_id UUID NOT NULL REFERENCES order_comments(comment_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id),
+    PRIMARY KEY (comment_id, user_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_order_comments_order_id ON order_comments(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_order_comments_parent_id ON order_comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_order_comment_edits_comment_id ON order_comment_edits(comment_id, edited_at);
+CREATE INDEX IF NOT EXISTS idx_order_comment_mentions_user_id ON order_comment_mentions(user_id);
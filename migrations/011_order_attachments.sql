@@ -0,0 +1,16 @@
+-- Create order attachments table. The files themselves live in the blob
+-- store.
+CREATE TABLE IF NOT EXISTS order_attachments (
+    attachment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    blob_key TEXT NOT NULL,
+    uploaded_by UUID NOT NULL REFERENCES users(user_id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_order_attachments_order_id ON order_attachments(order_id, created_at);
@@ -0,0 +1,53 @@
+-- Trigram indexes make partial phone matches ("1234567" in "79001234567") fast
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The 'simple' configuration does not stem, so names, e-mails and words in
+-- any language are indexed as written. Queries match on word prefixes.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(email, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED;
+-- Normalized like customer phones: digits only, the 11-digit trunk prefix 8
+-- replaced with the country code 7
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS phone_digits TEXT
+    GENERATED ALWAYS AS (regexp_replace(
+        regexp_replace(phone, '\D', '', 'g'), '^8(\d{10})$', '7\1'
+    )) STORED;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED;
+
+-- Array columns cannot feed generated columns, customers are indexed by a trigger
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_vector TSVECTOR NOT NULL DEFAULT ''::tsvector;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_digits TEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE FUNCTION customers_search_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', NEW.name), 'A') ||
+        setweight(to_tsvector('simple', array_to_string(NEW.emails, ' ')), 'A') ||
+        setweight(to_tsvector('simple', array_to_string(NEW.tags, ' ')), 'B') ||
+        setweight(to_tsvector('simple', NEW.notes), 'C');
+    NEW.phone_digits := array_to_string(NEW.phones, ' ');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS customers_search_update ON customers;
+CREATE TRIGGER customers_search_update
+    BEFORE INSERT OR UPDATE OF name, emails, tags, notes, phones ON customers
+    FOR EACH ROW EXECUTE FUNCTION customers_search_update();
+
+-- Index existing customers
+UPDATE customers SET name = name;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_orders_search_vector ON orders USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_orders_phone_digits ON orders USING GIN (phone_digits gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_customers_search_vector ON customers USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_customers_phone_digits ON customers USING GIN (phone_digits gin_trgm_ops);
@@ -0,0 +1,10 @@
+-- Stock keeping units identify products across systems. Products created
+-- in the CRM may have none, imported ones are matched by it.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku) WHERE sku IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_products_lower_name ON products(lower(name));
+
+-- Orders imported from other systems keep their original number, so running
+-- the same import twice does not duplicate them.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS external_id VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_external_id ON orders(external_id) WHERE external_id IS NOT NULL;
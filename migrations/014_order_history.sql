@@ -0,0 +1,21 @@
+-- Free-form labels on orders
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_orders_tags ON orders USING GIN (tags);
+
+-- Create order history table. Every change of status, assignee or tags is
+-- recorded with the user who made it; changes made in one bulk operation
+-- share the bulk_id.
+CREATE TABLE IF NOT EXISTS order_history (
+    history_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(user_id),
+    field VARCHAR(16) NOT NULL,
+    old_value TEXT NOT NULL DEFAULT '',
+    new_value TEXT NOT NULL DEFAULT '',
+    bulk_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_order_history_order_id ON order_history(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_order_history_field_created_at ON order_history(field, created_at);
@@ -0,0 +1,16 @@
+-- Create custom fields table. Directors define extra order properties,
+-- the values are stored on the order.
+CREATE TABLE IF NOT EXISTS custom_fields (
+    key VARCHAR(40) PRIMARY KEY,
+    label VARCHAR(255) NOT NULL,
+    type VARCHAR(8) NOT NULL CHECK (type IN ('text', 'number', 'date', 'enum')),
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Values by field key, validated against custom_fields by the application
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_orders_custom_fields ON orders USING GIN (custom_fields jsonb_path_ops);
@@ -0,0 +1,2 @@
+-- Analytics aggregate orders by creation date
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
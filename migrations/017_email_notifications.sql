@@ -0,0 +1,22 @@
+-- Customers may opt out of emails about an order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS email_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create email outbox table. Notifications are queued in the transaction
+-- that changes the order and sent by a background worker, which retries
+-- failed deliveries with growing delays.
+CREATE TABLE IF NOT EXISTS email_outbox (
+    email_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    event VARCHAR(16) NOT NULL CHECK (event IN ('received', 'at_work', 'completed', 'rejected')),
+    recipient VARCHAR(255) NOT NULL,
+    status VARCHAR(8) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_email_outbox_order_id ON email_outbox(order_id, created_at);
@@ -0,0 +1,24 @@
+-- Customers may opt out of text messages about an order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS sms_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create SMS outbox table, sent like the email outbox. The recipient is
+-- the normalized phone of the order until the message is sent, then the
+-- number in international format it went to.
+CREATE TABLE IF NOT EXISTS sms_outbox (
+    sms_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    event VARCHAR(16) NOT NULL CHECK (event IN ('received', 'at_work', 'completed', 'rejected')),
+    recipient VARCHAR(32) NOT NULL,
+    status VARCHAR(8) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    provider_message_id VARCHAR(255) NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_sms_outbox_due ON sms_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_sms_outbox_order_id ON sms_outbox(order_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sms_outbox_recipient_sent_at ON sms_outbox(recipient, sent_at) WHERE status = 'sent';
@@ -0,0 +1,39 @@
+-- Create webhooks table. Other systems subscribe to order events; every
+-- request is signed with the secret of the subscription.
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    description VARCHAR(200) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create webhook deliveries table. Deliveries are queued in the transaction
+-- that changes the order, like the email outbox, and keep the outcome of
+-- their last attempt as the delivery log. A redelivery is a new row with
+-- the event id of the original.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    data JSONB NOT NULL DEFAULT '{}',
+    redelivery_of UUID REFERENCES webhook_deliveries(delivery_id) ON DELETE SET NULL,
+    status VARCHAR(8) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    response_status INTEGER,
+    response_body TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
@@ -0,0 +1,28 @@
+-- Create domain events table. Events are stored in the transaction that
+-- changes the order and handed to every subscriber of the process at least
+-- once; handled_by lists the subscribers done with an event.
+CREATE TABLE IF NOT EXISTS domain_events (
+    sequence BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    type VARCHAR(64) NOT NULL,
+    order_id UUID NOT NULL,
+    assignee_id UUID,
+    data JSONB NOT NULL DEFAULT '{}',
+    handled_by TEXT[] NOT NULL DEFAULT '{}',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    dispatched_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_domain_events_due ON domain_events(next_attempt_at) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_domain_events_order_id ON domain_events(order_id, sequence);
+
+-- Subscribers may see an event more than once; the outboxes they fill keep
+-- the event id so a repeated event queues nothing new
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS event_id UUID;
+ALTER TABLE sms_outbox ADD COLUMN IF NOT EXISTS event_id UUID;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_outbox_event_id ON email_outbox(event_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sms_outbox_event_id ON sms_outbox(event_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_id) WHERE redelivery_of IS NULL;
@@ -0,0 +1,10 @@
+-- Create order locks table. A user editing an order holds its lock until
+-- the editing session ends or the lock expires without being renewed;
+-- changes by other users are refused meanwhile.
+CREATE TABLE IF NOT EXISTS order_locks (
+    order_id UUID PRIMARY KEY REFERENCES orders(order_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    session_id UUID NOT NULL,
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
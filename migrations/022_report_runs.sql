@@ -0,0 +1,17 @@
+-- Create report runs table. Every instance runs the report schedules, the
+-- first one to record a run delivers it. A failed run stays pending and is
+-- tried again with growing delays by whichever instance claims it next.
+CREATE TABLE IF NOT EXISTS report_runs (
+    name VARCHAR(64) NOT NULL,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(8) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 1,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    claimed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (name, due_at)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_report_runs_due ON report_runs(next_attempt_at) WHERE status = 'pending';